@@ -0,0 +1,192 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mbaksheev/clickhouse-table-graph/graph"
+	"github.com/mbaksheev/clickhouse-table-graph/mermaid"
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+// Colors of the added, removed and changed tables and links of the diff graph.
+const (
+	addedColor   = "#2ea043"
+	removedColor = "#ff5757"
+	changedColor = "#f4a622"
+)
+
+// diffJSON is the JSON representation of the [graph.GraphDiff].
+type diffJSON struct {
+	Tables []tableChangeJSON `json:"tables"`
+	Links  []linkChangeJSON  `json:"links"`
+}
+
+// tableChangeJSON is the JSON representation of the [graph.TableChange].
+type tableChangeJSON struct {
+	Table              string `json:"table"`
+	Change             string `json:"change"`
+	OldEngine          string `json:"old_engine,omitempty"`
+	NewEngine          string `json:"new_engine,omitempty"`
+	EngineChanged      bool   `json:"engine_changed"`
+	QueryChanged       bool   `json:"query_changed"`
+	CreateQueryChanged bool   `json:"create_query_changed"`
+}
+
+// linkChangeJSON is the JSON representation of the [graph.LinkChange].
+type linkChangeJSON struct {
+	linkJSON
+	Change  string `json:"change"`
+	OldKind string `json:"old_kind,omitempty"`
+}
+
+// diffTables returns the difference between the graphs of the old and the new tables
+// in the text, JSON, mermaid or DOT format.
+func diffTables(options inputOptions) (string, error) {
+	diff, err := graph.DiffProviders(options.oldTableInfoProvider, options.tableInfoProvider)
+	if err != nil {
+		return "", err
+	}
+	switch options.outputFormat {
+	case Text:
+		return diffText(diff), nil
+	case JSON:
+		return diffJSONText(diff)
+	default:
+		return diffFlowchart(options, diff)
+	}
+}
+
+// diffText returns the text summary of the changes with one line per changed table or link.
+// Added items are marked with "+", removed items with "-" and changed items with "~".
+func diffText(diff *graph.GraphDiff) string {
+	var result strings.Builder
+	tableCounts := make(map[graph.ChangeKind]int)
+	for _, change := range diff.Tables {
+		tableCounts[change.Kind]++
+	}
+	linkCounts := make(map[graph.ChangeKind]int)
+	for _, change := range diff.Links {
+		linkCounts[change.Kind]++
+	}
+	fmt.Fprintf(&result, "Tables: %d added, %d removed, %d changed\n", tableCounts[graph.Added], tableCounts[graph.Removed], tableCounts[graph.Changed])
+	fmt.Fprintf(&result, "Links: %d added, %d removed, %d changed\n", linkCounts[graph.Added], linkCounts[graph.Removed], linkCounts[graph.Changed])
+	if len(diff.Tables) > 0 {
+		result.WriteString("\n")
+	}
+	for _, change := range diff.Tables {
+		switch change.Kind {
+		case graph.Added:
+			fmt.Fprintf(&result, "+ %s (%s)\n", change.Key, change.New.Engine)
+		case graph.Removed:
+			fmt.Fprintf(&result, "- %s (%s)\n", change.Key, change.Old.Engine)
+		default:
+			fmt.Fprintf(&result, "~ %s (%s): %s\n", change.Key, change.New.Engine, describeTableChange(change))
+		}
+	}
+	if len(diff.Links) > 0 {
+		result.WriteString("\n")
+	}
+	for _, change := range diff.Links {
+		link := change.Link
+		switch change.Kind {
+		case graph.Added:
+			fmt.Fprintf(&result, "+ %s -> %s (%s)\n", link.FromTableKey, link.ToTableKey, link.Kind)
+		case graph.Removed:
+			fmt.Fprintf(&result, "- %s -> %s (%s)\n", link.FromTableKey, link.ToTableKey, link.Kind)
+		default:
+			fmt.Fprintf(&result, "~ %s -> %s: %s -> %s\n", link.FromTableKey, link.ToTableKey, change.OldKind, link.Kind)
+		}
+	}
+	return result.String()
+}
+
+// describeTableChange returns the comma-separated list of the changed parts of the table definition.
+func describeTableChange(change graph.TableChange) string {
+	changes := make([]string, 0, 3)
+	if change.EngineChanged {
+		if change.Old.Engine != change.New.Engine {
+			changes = append(changes, fmt.Sprintf("engine changed from %s", change.Old.Engine))
+		} else {
+			changes = append(changes, "engine parameters changed")
+		}
+	}
+	if change.QueryChanged {
+		changes = append(changes, "query changed")
+	}
+	if change.CreateQueryChanged && !change.EngineChanged && !change.QueryChanged {
+		changes = append(changes, "create query changed")
+	}
+	return strings.Join(changes, ", ")
+}
+
+// diffJSONText returns the JSON summary of the changes.
+func diffJSONText(diff *graph.GraphDiff) (string, error) {
+	summary := diffJSON{
+		Tables: make([]tableChangeJSON, 0, len(diff.Tables)),
+		Links:  make([]linkChangeJSON, 0, len(diff.Links)),
+	}
+	for _, change := range diff.Tables {
+		summary.Tables = append(summary.Tables, tableChangeJSON{
+			Table:              change.Key.String(),
+			Change:             change.Kind.String(),
+			OldEngine:          change.Old.Engine,
+			NewEngine:          change.New.Engine,
+			EngineChanged:      change.EngineChanged,
+			QueryChanged:       change.QueryChanged,
+			CreateQueryChanged: change.CreateQueryChanged,
+		})
+	}
+	for _, change := range diff.Links {
+		linkChange := linkChangeJSON{
+			linkJSON: linkJSON{
+				From: change.Link.FromTableKey.String(),
+				To:   change.Link.ToTableKey.String(),
+				Kind: change.Link.Kind.String(),
+			},
+			Change: change.Kind.String(),
+		}
+		if change.Kind == graph.Changed {
+			linkChange.OldKind = change.OldKind.String()
+		}
+		summary.Links = append(summary.Links, linkChange)
+	}
+	result, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("diffJSONText: failed to marshal diff: %w", err)
+	}
+	return string(result) + "\n", nil
+}
+
+// diffFlowchart returns the mermaid flowchart or the DOT graph of the merged graph of both versions.
+// Added tables and links are highlighted with the green color, removed ones with the red color and changed ones with the orange color.
+func diffFlowchart(options inputOptions, diff *graph.GraphDiff) (string, error) {
+	tables := make(map[graph.ChangeKind][]table.Key)
+	for _, change := range diff.Tables {
+		tables[change.Kind] = append(tables[change.Kind], change.Key)
+	}
+	links := make(map[graph.ChangeKind][]graph.Link)
+	for _, change := range diff.Links {
+		links[change.Kind] = append(links[change.Kind], change.Link)
+	}
+	title := fmt.Sprintf("ClickHouse tables diff between %s and %s", options.oldTableInfoProvider, options.tableInfoProvider)
+	return renderGraph(options, *diff.Graph, title, mermaid.FlowchartOptions{
+		Orientation:   mermaid.TB,
+		IncludeEngine: true,
+		Theme:         options.mermaidTheme,
+		Grouping:      options.grouping,
+		EngineStyles:  options.engineStyles,
+		Legend:        options.legend,
+		LinkHighlights: []graph.LinkHighlight{
+			{Links: links[graph.Added], Color: addedColor},
+			{Links: links[graph.Removed], Color: removedColor},
+			{Links: links[graph.Changed], Color: changedColor},
+		},
+		TableHighlights: []graph.TableHighlight{
+			{Tables: tables[graph.Added], Color: addedColor},
+			{Tables: tables[graph.Removed], Color: removedColor},
+			{Tables: tables[graph.Changed], Color: changedColor},
+		},
+	})
+}
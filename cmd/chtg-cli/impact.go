@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/mbaksheev/clickhouse-table-graph/graph"
+	"github.com/mbaksheev/clickhouse-table-graph/mermaid"
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+// Colors of the tables and links of the impact graph.
+const (
+	hardBreakColor          = "#ff5757"
+	stopsReceivingDataColor = "#f4a622"
+)
+
+// impactReportJSON is the JSON representation of the [graph.ImpactReport].
+type impactReportJSON struct {
+	Table   string       `json:"table"`
+	Impacts []impactJSON `json:"impacts"`
+}
+
+// impactJSON is the JSON representation of the [graph.Impact].
+type impactJSON struct {
+	Table    string   `json:"table"`
+	Engine   string   `json:"engine"`
+	Severity string   `json:"severity"`
+	Effect   string   `json:"effect"`
+	Reason   string   `json:"reason"`
+	Depth    int      `json:"depth"`
+	Link     linkJSON `json:"link"`
+}
+
+// linkJSON is the JSON representation of the [graph.Link].
+type linkJSON struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
+}
+
+// createImpactReport returns the report of all tables affected by dropping the fromTable table
+// in the text, JSON, mermaid or DOT format. The impacts are ranked by severity: hard breaks first.
+func createImpactReport(options inputOptions) (string, error) {
+	tables, err := options.tableInfoProvider.TableInfos()
+	if err != nil {
+		return "", err
+	}
+	tableGraph := graph.New()
+	for _, t := range tables {
+		tableGraph.AddTable(t)
+	}
+	report, err := tableGraph.Impact(options.fromTable)
+	if err != nil {
+		return "", err
+	}
+	switch options.outputFormat {
+	case Text:
+		return impactText(report), nil
+	case JSON:
+		return impactJSONText(report)
+	default:
+		return impactFlowchart(options, report)
+	}
+}
+
+// impactText returns the text report with one line per affected table and the link by which the impact comes.
+func impactText(report *graph.ImpactReport) string {
+	var result strings.Builder
+	hardBreaks := 0
+	for _, impact := range report.Impacts {
+		if impact.Severity == graph.HardBreak {
+			hardBreaks++
+		}
+	}
+	fmt.Fprintf(&result, "Impact of dropping %s: %d hard breaks, %d tables stop receiving data\n",
+		report.Table, hardBreaks, len(report.Impacts)-hardBreaks)
+	for _, impact := range report.Impacts {
+		tableInfo, _ := report.Graph.TableInfo(impact.Table)
+		fmt.Fprintf(&result, "\n%-18s %-11s %s (%s): %s\n", impact.Severity, impact.Effect, impact.Table, tableInfo.Engine, impact.Reason)
+		fmt.Fprintf(&result, "  via %s -> %s (%s)\n", impact.Link.FromTableKey, impact.Link.ToTableKey, impact.Link.Kind)
+	}
+	return result.String()
+}
+
+// impactJSONText returns the JSON report of the impacts.
+func impactJSONText(report *graph.ImpactReport) (string, error) {
+	reportJSON := impactReportJSON{Table: report.Table.String(), Impacts: make([]impactJSON, 0, len(report.Impacts))}
+	for _, impact := range report.Impacts {
+		tableInfo, _ := report.Graph.TableInfo(impact.Table)
+		reportJSON.Impacts = append(reportJSON.Impacts, impactJSON{
+			Table:    impact.Table.String(),
+			Engine:   tableInfo.Engine,
+			Severity: impact.Severity.String(),
+			Effect:   impact.Effect.String(),
+			Reason:   impact.Reason,
+			Depth:    impact.Depth,
+			Link: linkJSON{
+				From: impact.Link.FromTableKey.String(),
+				To:   impact.Link.ToTableKey.String(),
+				Kind: impact.Link.Kind.String(),
+			},
+		})
+	}
+	result, err := json.MarshalIndent(reportJSON, "", "  ")
+	if err != nil {
+		return "", fmt.Errorf("impactJSONText: failed to marshal impact report: %w", err)
+	}
+	return string(result) + "\n", nil
+}
+
+// impactFlowchart returns the mermaid flowchart or the DOT graph of the impact graph.
+// Hard breaks and the links by which they come are highlighted with the red color, tables which stop receiving data with the orange one.
+func impactFlowchart(options inputOptions, report *graph.ImpactReport) (string, error) {
+	var hardBreakLinks, stopsReceivingDataLinks []graph.Link
+	var hardBreakTables, stopsReceivingDataTables []table.Key
+	for _, impact := range report.Impacts {
+		if impact.Severity == graph.HardBreak {
+			hardBreakLinks = append(hardBreakLinks, impact.Link)
+			hardBreakTables = append(hardBreakTables, impact.Table)
+		} else {
+			stopsReceivingDataLinks = append(stopsReceivingDataLinks, impact.Link)
+			stopsReceivingDataTables = append(stopsReceivingDataTables, impact.Table)
+		}
+	}
+	title := fmt.Sprintf("Impact of dropping ClickHouse table %s", report.Table)
+	return renderGraph(options, *report.Graph, title, mermaid.FlowchartOptions{
+		Orientation:                mermaid.TB,
+		IncludeEngine:              true,
+		Theme:                      options.mermaidTheme,
+		Grouping:                   options.grouping,
+		EngineStyles:               options.engineStyles,
+		Legend:                     options.legend,
+		InitialTableHighlightColor: options.tableHighlightColor,
+		LinkHighlights: []graph.LinkHighlight{
+			{Links: hardBreakLinks, Color: hardBreakColor},
+			{Links: stopsReceivingDataLinks, Color: stopsReceivingDataColor},
+		},
+		TableHighlights: []graph.TableHighlight{
+			{Tables: hardBreakTables, Color: hardBreakColor},
+			{Tables: stopsReceivingDataTables, Color: stopsReceivingDataColor},
+		},
+	})
+}
@@ -3,17 +3,42 @@ package main
 import (
 	"flag"
 	"fmt"
+	"os"
+	"strconv"
 	"strings"
 
 	"github.com/mbaksheev/clickhouse-table-graph/clickhouse"
+	"github.com/mbaksheev/clickhouse-table-graph/cytoscape"
+	"github.com/mbaksheev/clickhouse-table-graph/ddl"
+	"github.com/mbaksheev/clickhouse-table-graph/graph"
+	"github.com/mbaksheev/clickhouse-table-graph/mermaid"
+	"github.com/mbaksheev/clickhouse-table-graph/snapshot"
+	"github.com/mbaksheev/clickhouse-table-graph/table"
 	"golang.org/x/crypto/ssh/terminal"
 )
 
+type command int
+
+const (
+	GraphCommand command = iota
+	SnapshotCommand
+	ExplainCommand
+	PathCommand
+	ImpactCommand
+	OrderCommand
+	DiffCommand
+)
+
 type outputFormat int
 
 const (
 	MermaidHtml outputFormat = iota
 	MermaidMarkdown
+	Text
+	JSON
+	Dot
+	CytoscapeHtml
+	CytoscapeJson
 )
 
 type outputMode int
@@ -27,77 +52,452 @@ var (
 	chHost              = flag.String("clickhouse-host", "localhost", "ClickHouse host to get tables from. Optional.")
 	chPort              = flag.String("clickhouse-port", "9000", "ClickHouse port. Optional.")
 	chUsername          = flag.String("clickhouse-user", "", "ClickHouse username. Optional. If not provided, the default value is empty string.")
-	chTable             = flag.String("clickhouse-table", "", "ClickHouse full table name in format <database>.<table> to get dependencies for. Required.")
-	outFormat           = flag.String("out-format", "mermaid-html", "Output format. Possible options: 'mermaid-html' - to generate full html document for displaying chart which can be opened in browser or 'mermaid-md' - to generate only mermaid markdown diagram.")
+	outFormat           = flag.String("out-format", "mermaid-html", "Output format. Possible options: 'mermaid-html' - to generate full html document for displaying chart which can be opened in browser 'mermaid-md' - to generate only mermaid markdown diagram 'dot' - to generate Graphviz DOT graph, 'cytoscape-html' - to generate full html document with Cytoscape.js graph which stays usable with thousands of tables or 'cytoscape-json' - to generate only Cytoscape.js elements JSON. The 'impact' and 'diff' commands support 'text' and 'json' formats as well and use 'text' by default.")
 	outFile             = flag.String("out-file", "", "Output file name. Optional. If not specified, the output will be printed to the console.")
+	offline             = flag.Bool("offline", false, "Embed the Mermaid JS library into the 'mermaid-html' output, so it is rendered without network access. Optional. Default value is false.")
+	cytoscapeLayout     = flag.String("cytoscape-layout", "dagre", "Layout of the 'cytoscape-html' output. Possible options: 'dagre' - layered layout with links from top to bottom, 'force' - force-directed layout. Optional. Default value is 'dagre'.")
+	groupBy             = flag.String("group-by", "none", "Grouping of the tables into mermaid subgraphs. Possible options: 'none' - tables are not grouped, 'database' - tables are grouped by database, 'cluster' - tables are grouped by database and Distributed tables are grouped by cluster as well. Links between databases are dashed. Optional. Default value is 'none'.")
+	styleConfigFile     = flag.String("style-config", "", "JSON file with the styles of the mermaid nodes which override the default shape, fill and border colors of the tables with the matching engines, e.g. {\"engine_styles\": [{\"name\": \"Kafka\", \"engines\": [\"Kafka\"], \"engine_regex\": \"\", \"shape\": \"h-cyl\", \"fill\": \"#fbe9e7\", \"stroke\": \"#d84315\"}]}. Optional.")
+	legend              = flag.Bool("legend", false, "Add the legend of the engine styles used in the mermaid diagram. Optional. Default value is false.")
 	mermaidTheme        = flag.String("mermaid-theme", "", "Mermaid theme. Optional. Default value is 'default'. See https://mermaid-js.github.io/mermaid/#/theming")
 	tableHighlightColor = flag.String("table-highlight-color", "", "Highlight color for the selected clickhouse table. E.g. '#ff5757' or 'red' Optional. If not specified, the table will not be highlighted. See https://mermaid.js.org/syntax/flowchart.html?id=flowcharts-basic-syntax#styling-a-node")
+	cycleHighlightColor = flag.String("cycle-highlight-color", "#ff5757", "Highlight color for the links and tables which form cycles, e.g. loops of materialized views. Optional. Default value is '#ff5757'. Empty value disables the highlighting.")
 	chSecure            = flag.Bool("secure", false, "Use secure connection to ClickHouse. Optional. Default value is false.")
 	chSkipTLSVerify     = flag.Bool("skip-tls-verify", false, "Skip TLS verification. Optional. Default value is false.")
+	ddlPath             = flag.String("ddl-path", "", "Comma-separated list of .sql files, directories or glob patterns with DDL statements to get tables from instead of ClickHouse server. Optional.")
+	ddlDatabase         = flag.String("ddl-database", "default", "Database for table names without database in DDL files. Optional. Default value is 'default'.")
+	snapshotFile        = flag.String("snapshot-file", "", "Snapshot file created with the 'snapshot' command to get tables from instead of ClickHouse server. Optional.")
+	direction           = flag.String("direction", "both", "Direction of the graph traversal from the clickhouse-table. Possible options: 'both' - dependent tables and tables they depend on, 'upstream' - only tables the clickhouse-table depends on, 'downstream' - only dependent tables, 'component' - all connected tables including siblings. Optional. Default value is 'both'.")
+	depth               = flag.String("depth", "", "Maximum number of links followed from the clickhouse-table in each direction: '<depth>' for both directions or '<upstream>:<downstream>', e.g. '2' or '1:3'. Zero means unlimited. Optional. If not specified, the depth is unlimited.")
+	allTables           = flag.Bool("all-tables", false, "Create the graph of all tables including tables without links instead of the graph of the clickhouse-table. Optional. Default value is false.")
+	pathLimit           = flag.Int("path-limit", 10, "Maximum number of paths rendered by the 'path' and 'explain' commands. Must be positive. Optional. Default value is 10.")
+	shortestPath        = flag.Bool("shortest", false, "Render only the shortest path with the 'path' command. Optional. Default value is false.")
+	pathHighlightColor  = flag.String("path-highlight-color", "#ff5757", "Highlight color for the links of the shortest path rendered by the 'path' command. Optional. Default value is '#ff5757'. Empty value disables the highlighting.")
+	createOrder         = flag.Bool("create", false, "Print tables in the order they can be created with the 'order' command. Optional. It is the default order.")
+	dropOrder           = flag.Bool("drop", false, "Print tables in the order they can be dropped with the 'order' command. Optional. Default value is false.")
+	orderStatements     = flag.Bool("statements", false, "Print CREATE or DROP statements instead of table names with the 'order' command. Optional. Default value is false.")
+	snapshotFormat      = flag.String("snapshot-format", "json", "Snapshot format for the 'snapshot' command. Possible options: 'json' - single JSON document or 'ndjson' - newline delimited JSON. Optional. Default value is 'json'.")
 )
 
-type inputOptions struct {
-	clickhouseServer    clickhouse.Server
-	clickhouseTable     string
-	clickhouseDatabase  string
-	secure              string
-	skipTLSVerify       string
-	outputFormat        outputFormat
-	outputMode          outputMode
-	outputFile          string
-	mermaidTheme        string
-	tableHighlightColor string
-}
-
-func parseFlags() (inputOptions, error) {
-	flag.Parse()
-	password, err := askForPassword()
-	if err != nil {
-		return inputOptions{}, fmt.Errorf("parseFlags: Error while asking for password: %w", err)
+// chTables is a list of tables or patterns of tables specified with the repeatable clickhouse-table flag.
+var chTables tableFlags
+
+func init() {
+	flag.Var(&chTables, "clickhouse-table", "ClickHouse full table name in format <database>.<table> to get dependencies for, glob pattern like <database>.* or regular expression like 're:^raw_.*_kafka$' matched against <database>.<table>. The flag can be repeated or contain a comma-separated list of tables and glob patterns. Required.")
+}
+
+// tableFlags represents the repeatable flag with the list of tables or patterns of tables.
+type tableFlags []string
+
+// String returns the comma-separated list of the specified tables.
+func (f *tableFlags) String() string {
+	return strings.Join(*f, ",")
+}
+
+// Set adds the specified tables to the list. Comma-separated tables are split, unless the value is a regular expression.
+func (f *tableFlags) Set(value string) error {
+	if strings.HasPrefix(value, "re:") {
+		*f = append(*f, value)
+		return nil
 	}
-	chServer := clickhouse.Server{
-		Address:       fmt.Sprintf("%s:%s", *chHost, *chPort),
-		Username:      *chUsername,
-		Password:      *password,
-		Secure:        *chSecure,
-		SkipTLSVerify: *chSkipTLSVerify,
+	for _, t := range strings.Split(value, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			*f = append(*f, t)
+		}
 	}
+	return nil
+}
 
+type inputOptions struct {
+	command              command
+	tableInfoProvider    table.InfoProvider
+	tablePatterns        []table.Pattern
+	secure               string
+	skipTLSVerify        string
+	outputFormat         outputFormat
+	outputMode           outputMode
+	outputFile           string
+	mermaidTheme         string
+	offline              bool
+	grouping             mermaid.Grouping
+	cytoscapeLayout      cytoscape.Layout
+	engineStyles         []mermaid.EngineStyle
+	legend               bool
+	tableHighlightColor  string
+	cycleHighlightColor  string
+	snapshotFormat       snapshot.Format
+	fromTable            table.Key
+	toTable              table.Key
+	pathLimit            int
+	shortestPath         bool
+	pathHighlightColor   string
+	dropOrder            bool
+	oldTableInfoProvider table.InfoProvider
+	orderStatements      bool
+	allTables            bool
+	direction            graph.Direction
+	maxUpstreamDepth     int
+	maxDownstreamDepth   int
+}
+
+// parseFlags parses the specified command line arguments.
+// The first argument may be a command name, e.g. "snapshot". If it is not specified, the graph is created.
+// Positional arguments of the command, e.g. tables of the "explain" command, may be specified before or after the flags.
+func parseFlags(arguments []string) (inputOptions, error) {
 	var inputOpts inputOptions
-	inputOpts.clickhouseServer = chServer
-	if *chTable != "" {
-		tableNameParts := strings.Split(*chTable, ".")
-		if len(tableNameParts) != 2 {
-			return inputOptions{}, fmt.Errorf("parseFlags: Incorrect table name format: '%s'. Clickhouse table should be in format <database>.<table>", *chTable)
-		}
-		inputOpts.clickhouseDatabase = tableNameParts[0]
-		inputOpts.clickhouseTable = tableNameParts[1]
-	} else {
-		return inputOptions{}, fmt.Errorf("parseFlags: Incorrect table name. Clickhouse table is required")
+	commandName := "graph"
+	if len(arguments) > 0 && !strings.HasPrefix(arguments[0], "-") {
+		commandName = arguments[0]
+		switch commandName {
+		case "graph":
+			inputOpts.command = GraphCommand
+		case "snapshot":
+			inputOpts.command = SnapshotCommand
+		case "explain":
+			inputOpts.command = ExplainCommand
+		case "path":
+			inputOpts.command = PathCommand
+		case "impact":
+			inputOpts.command = ImpactCommand
+		case "order":
+			inputOpts.command = OrderCommand
+		case "diff":
+			inputOpts.command = DiffCommand
+		default:
+			return inputOptions{}, fmt.Errorf("parseFlags: unknown command: %s", arguments[0])
+		}
+		arguments = arguments[1:]
 	}
-
-	switch *outFormat {
-	case "mermaid-html":
-		inputOpts.outputFormat = MermaidHtml
-	case "mermaid-md":
-		inputOpts.outputFormat = MermaidMarkdown
-	default:
-		return inputOptions{}, fmt.Errorf("parseFlags: unknown output format: %s", *outFormat)
+	positional := make([]string, 0)
+	for len(arguments) > 0 && !strings.HasPrefix(arguments[0], "-") {
+		positional = append(positional, arguments[0])
+		arguments = arguments[1:]
+	}
+	if err := flag.CommandLine.Parse(arguments); err != nil {
+		return inputOptions{}, fmt.Errorf("parseFlags: %w", err)
 	}
+	positional = append(positional, flag.CommandLine.Args()...)
+	if inputOpts.command == ExplainCommand || inputOpts.command == PathCommand {
+		if len(positional) != 2 {
+			return inputOptions{}, fmt.Errorf("parseFlags: %s command requires two tables in format <database>.<table>: %s <from> <to>", commandName, commandName)
+		}
+		var err error
+		if inputOpts.fromTable, err = parseTableKey(positional[0]); err != nil {
+			return inputOptions{}, err
+		}
+		if inputOpts.toTable, err = parseTableKey(positional[1]); err != nil {
+			return inputOptions{}, err
+		}
+	} else if inputOpts.command == ImpactCommand {
+		if len(positional) != 1 {
+			return inputOptions{}, fmt.Errorf("parseFlags: impact command requires one table in format <database>.<table>: impact <table>")
+		}
+		var err error
+		if inputOpts.fromTable, err = parseTableKey(positional[0]); err != nil {
+			return inputOptions{}, err
+		}
+	} else if inputOpts.command == DiffCommand {
+		return parseDiffFlags(inputOpts, positional)
+	} else if len(positional) > 0 {
+		return inputOptions{}, fmt.Errorf("parseFlags: unexpected arguments: %s", strings.Join(positional, " "))
+	}
+	tableInfoProvider, err := createTableInfoProvider()
+	if err != nil {
+		return inputOptions{}, err
+	}
+	inputOpts.tableInfoProvider = tableInfoProvider
+
 	if *outFile != "" {
 		inputOpts.outputMode = File
 		inputOpts.outputFile = *outFile
 	} else {
 		inputOpts.outputMode = Stdout
 	}
-	inputOpts.mermaidTheme = *mermaidTheme
+
+	if inputOpts.command == SnapshotCommand {
+		switch *snapshotFormat {
+		case "json":
+			inputOpts.snapshotFormat = snapshot.JSON
+		case "ndjson":
+			inputOpts.snapshotFormat = snapshot.NDJSON
+		default:
+			return inputOptions{}, fmt.Errorf("parseFlags: unknown snapshot format: %s", *snapshotFormat)
+		}
+		return inputOpts, nil
+	}
+	if *pathLimit <= 0 {
+		return inputOptions{}, fmt.Errorf("parseFlags: Incorrect path limit: %d. Path limit should be a positive number", *pathLimit)
+	}
+	inputOpts.pathLimit = *pathLimit
+	if inputOpts.command == ExplainCommand {
+		return inputOpts, nil
+	}
+	if inputOpts.command == OrderCommand {
+		if *createOrder && *dropOrder {
+			return inputOptions{}, fmt.Errorf("parseFlags: only one of create and drop flags can be specified")
+		}
+		inputOpts.dropOrder = *dropOrder
+		inputOpts.orderStatements = *orderStatements
+		inputOpts.tablePatterns, err = parsePatterns(chTables)
+		return inputOpts, err
+	}
+
+	if inputOpts.command == PathCommand {
+		inputOpts.shortestPath = *shortestPath
+		inputOpts.pathHighlightColor = *pathHighlightColor
+	} else if inputOpts.command != ImpactCommand {
+		if *allTables {
+			inputOpts.allTables = true
+		} else if len(chTables) > 0 {
+			if inputOpts.tablePatterns, err = parsePatterns(chTables); err != nil {
+				return inputOptions{}, err
+			}
+		} else {
+			return inputOptions{}, fmt.Errorf("parseFlags: Incorrect table name. Clickhouse table is required")
+		}
+	}
+
+	if err = parseOutputOptions(&inputOpts, inputOpts.command == ImpactCommand); err != nil {
+		return inputOptions{}, err
+	}
+	switch *direction {
+	case "both":
+		inputOpts.direction = graph.Both
+	case "upstream":
+		inputOpts.direction = graph.Upstream
+	case "downstream":
+		inputOpts.direction = graph.Downstream
+	case "component":
+		inputOpts.direction = graph.Component
+	default:
+		return inputOptions{}, fmt.Errorf("parseFlags: unknown direction: %s", *direction)
+	}
+	if *depth != "" {
+		if inputOpts.maxUpstreamDepth, inputOpts.maxDownstreamDepth, err = parseDepth(*depth); err != nil {
+			return inputOptions{}, err
+		}
+	}
 	inputOpts.tableHighlightColor = *tableHighlightColor
+	inputOpts.cycleHighlightColor = *cycleHighlightColor
+	return inputOpts, nil
+}
+
+// parseDiffFlags parses the arguments of the diff command: the sources of the old and the new tables and the output format.
+func parseDiffFlags(inputOpts inputOptions, sources []string) (inputOptions, error) {
+	if len(sources) != 2 {
+		return inputOptions{}, fmt.Errorf("parseDiffFlags: diff command requires two sources of tables: diff <old> <new>")
+	}
+	var err error
+	if inputOpts.oldTableInfoProvider, err = parseSource(sources[0]); err != nil {
+		return inputOptions{}, err
+	}
+	if inputOpts.tableInfoProvider, err = parseSource(sources[1]); err != nil {
+		return inputOptions{}, err
+	}
+	if *outFile != "" {
+		inputOpts.outputMode = File
+		inputOpts.outputFile = *outFile
+	}
+	if err = parseOutputOptions(&inputOpts, true); err != nil {
+		return inputOptions{}, err
+	}
 	return inputOpts, nil
 }
 
+// parseOutputOptions parses the output format and the options of the rendered graph shared by the commands which render graphs:
+// mermaid theme, grouping, cytoscape layout, engine styles and legend.
+// The text and json formats are supported only if textFormats is true, in this case the text format is used by default.
+func parseOutputOptions(inputOpts *inputOptions, textFormats bool) error {
+	format := *outFormat
+	if textFormats && !isFlagSet("out-format") {
+		format = "text"
+	}
+	switch format {
+	case "mermaid-html":
+		inputOpts.outputFormat = MermaidHtml
+	case "mermaid-md":
+		inputOpts.outputFormat = MermaidMarkdown
+	case "dot":
+		inputOpts.outputFormat = Dot
+	case "cytoscape-html":
+		inputOpts.outputFormat = CytoscapeHtml
+	case "cytoscape-json":
+		inputOpts.outputFormat = CytoscapeJson
+	case "text", "json":
+		if !textFormats {
+			return fmt.Errorf("parseOutputOptions: output format %s is supported only by the impact and diff commands", format)
+		}
+		inputOpts.outputFormat = Text
+		if format == "json" {
+			inputOpts.outputFormat = JSON
+		}
+	default:
+		return fmt.Errorf("parseOutputOptions: unknown output format: %s", format)
+	}
+	inputOpts.mermaidTheme = *mermaidTheme
+	inputOpts.offline = *offline
+	var err error
+	if inputOpts.grouping, err = parseGrouping(*groupBy); err != nil {
+		return err
+	}
+	if inputOpts.cytoscapeLayout, err = parseCytoscapeLayout(*cytoscapeLayout); err != nil {
+		return err
+	}
+	if *styleConfigFile != "" {
+		if inputOpts.engineStyles, err = loadEngineStyles(*styleConfigFile); err != nil {
+			return err
+		}
+	}
+	inputOpts.legend = *legend
+	return nil
+}
+
+// parseGrouping parses the grouping of the tables into mermaid subgraphs: none, database or cluster.
+func parseGrouping(value string) (mermaid.Grouping, error) {
+	switch value {
+	case "none":
+		return mermaid.NoGrouping, nil
+	case "database":
+		return mermaid.DatabaseGrouping, nil
+	case "cluster":
+		return mermaid.ClusterGrouping, nil
+	default:
+		return mermaid.NoGrouping, fmt.Errorf("parseGrouping: unknown grouping: %s", value)
+	}
+}
+
+// parseCytoscapeLayout parses the layout of the Cytoscape.js graph: dagre or force.
+func parseCytoscapeLayout(value string) (cytoscape.Layout, error) {
+	switch value {
+	case "dagre":
+		return cytoscape.Dagre, nil
+	case "force":
+		return cytoscape.Force, nil
+	default:
+		return cytoscape.Dagre, fmt.Errorf("parseCytoscapeLayout: unknown cytoscape layout: %s", value)
+	}
+}
+
+// parseSource creates the provider of tables from the source argument of the diff command:
+// ClickHouse server in format clickhouse://[user@]host[:port], snapshot file with .json or .ndjson extension,
+// or comma-separated list of .sql files, directories or glob patterns with DDL statements.
+func parseSource(source string) (table.InfoProvider, error) {
+	switch {
+	case strings.HasPrefix(source, "clickhouse://"):
+		address := strings.TrimPrefix(source, "clickhouse://")
+		username := ""
+		if user, host, found := strings.Cut(address, "@"); found {
+			username, address = user, host
+		}
+		if !strings.Contains(address, ":") {
+			address += ":9000"
+		}
+		fmt.Fprintf(os.Stderr, "Password for %s. ", address)
+		password, err := askForPassword()
+		if err != nil {
+			return nil, fmt.Errorf("parseSource: Error while asking for password: %w", err)
+		}
+		return &clickhouse.Server{
+			Address:       address,
+			Username:      username,
+			Password:      *password,
+			Secure:        *chSecure,
+			SkipTLSVerify: *chSkipTLSVerify,
+		}, nil
+	case strings.HasSuffix(source, ".json") || strings.HasSuffix(source, ".ndjson"):
+		return &snapshot.File{Path: source}, nil
+	default:
+		return &ddl.Files{
+			Paths:           strings.Split(source, ","),
+			DefaultDatabase: *ddlDatabase,
+		}, nil
+	}
+}
+
+// parsePatterns parses the specified tables or patterns of tables.
+func parsePatterns(values []string) ([]table.Pattern, error) {
+	patterns := make([]table.Pattern, 0, len(values))
+	for _, value := range values {
+		pattern, err := table.ParsePattern(value)
+		if err != nil {
+			return nil, fmt.Errorf("parsePatterns: %w", err)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// isFlagSet reports whether the flag with the specified name is set in the command line.
+func isFlagSet(name string) bool {
+	set := false
+	flag.CommandLine.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+// parseTableKey parses the table name in format <database>.<table>.
+func parseTableKey(name string) (table.Key, error) {
+	tableNameParts := strings.Split(name, ".")
+	if len(tableNameParts) != 2 {
+		return table.Key{}, fmt.Errorf("parseTableKey: Incorrect table name format: '%s'. Clickhouse table should be in format <database>.<table>", name)
+	}
+	return table.Key{Database: tableNameParts[0], Name: tableNameParts[1]}, nil
+}
+
+// parseDepth parses the maximum depth in format <depth> for both directions or <upstream>:<downstream>.
+func parseDepth(value string) (int, int, error) {
+	upstreamValue, downstreamValue, separated := strings.Cut(value, ":")
+	if !separated {
+		downstreamValue = upstreamValue
+	}
+	upstream, err := strconv.Atoi(upstreamValue)
+	if err != nil || upstream < 0 {
+		return 0, 0, fmt.Errorf("parseDepth: Incorrect depth: '%s'. Depth should be a non-negative number or in format <upstream>:<downstream>", value)
+	}
+	downstream, err := strconv.Atoi(downstreamValue)
+	if err != nil || downstream < 0 {
+		return 0, 0, fmt.Errorf("parseDepth: Incorrect depth: '%s'. Depth should be a non-negative number or in format <upstream>:<downstream>", value)
+	}
+	return upstream, downstream, nil
+}
+
+// createTableInfoProvider creates the provider of tables depending on the specified flags:
+// DDL files, snapshot file or ClickHouse server. The password is asked only for ClickHouse server.
+func createTableInfoProvider() (table.InfoProvider, error) {
+	switch {
+	case *ddlPath != "" && *snapshotFile != "":
+		return nil, fmt.Errorf("createTableInfoProvider: only one of ddl-path and snapshot-file flags can be specified")
+	case *ddlPath != "":
+		return &ddl.Files{
+			Paths:           strings.Split(*ddlPath, ","),
+			DefaultDatabase: *ddlDatabase,
+		}, nil
+	case *snapshotFile != "":
+		return &snapshot.File{Path: *snapshotFile}, nil
+	default:
+		password, err := askForPassword()
+		if err != nil {
+			return nil, fmt.Errorf("createTableInfoProvider: Error while asking for password: %w", err)
+		}
+		return &clickhouse.Server{
+			Address:       fmt.Sprintf("%s:%s", *chHost, *chPort),
+			Username:      *chUsername,
+			Password:      *password,
+			Secure:        *chSecure,
+			SkipTLSVerify: *chSkipTLSVerify,
+		}, nil
+	}
+}
+
+// askForPassword reads the password from the terminal without echo.
+// The prompt is written to stderr, so it does not mix with the output of the command redirected from stdout.
 func askForPassword() (*string, error) {
-	fmt.Print("Enter Password: ")
+	fmt.Fprint(os.Stderr, "Enter Password: ")
 	bytePassword, err := terminal.ReadPassword(0)
+	fmt.Fprintln(os.Stderr)
 	if err != nil {
 		return nil, fmt.Errorf("askForPassword: Error while reading password: %w", err)
 	}
@@ -1,18 +1,46 @@
 // Package main provides the main entry point for the CLI application.
 //
-// The main function parses the command line arguments,
-// creates a graph of tables, and saves it to the specified output file or outputs it to the console depending on the specified options:
+// The main function parses the command line arguments and runs one of the commands:
+//
+//   - graph - the default command. Creates a graph of tables, and saves it to the specified output file or outputs it to the console;
+//   - snapshot - saves tables information to the snapshot file, so the graph can be created later from this file without ClickHouse server;
+//   - explain <from> <to> - prints all paths between two tables with the evidence of every link: the extractor and the part of the table metadata it came from.
+//   - order - prints all tables or their CREATE statements in the order they can be created, or in the order they can be dropped with the --drop option.
+//   - diff <old> <new> - prints added, removed and changed tables and links between two sources of tables: ClickHouse servers, snapshot files or DDL files.
+//   - impact <table> - prints all tables which break or stop receiving data if the table is dropped, ranked by severity.
+//   - path <from> <to> - creates a graph which contains only the paths between two tables with the links of the shortest path highlighted.
+//
+// The following options are supported:
 //
 //   - --clickhouse-host string - Clickhouse host to get tables from. Optional. Default value is "localhost"
 //   - --clickhouse-port string - Clickhouse port. Optional. Default value 9000
-//   - --clickhouse-table string - Clickhouse full table name in format <database>.<table> to get dependencies for. Required.
+//   - --clickhouse-table string - Clickhouse full table name in format <database>.<table> to get dependencies for, glob pattern like <database>.* or regular expression like re:^raw_.*_kafka$. Can be repeated or contain a comma-separated list. Required.
 //   - --clickhouse-user string - Clickhouse username. Optional. Default value is "" (empty string)
 //   - --out-file string - Output file name. Optional. If not specified, the output will be printed to the console.
-//   - --out-format string - Output format. Default value "mermaid-html". Possible values: "mermaid-html", "mermaid-md".
+//   - --out-format string - Output format. Default value "mermaid-html". Possible values: "mermaid-html", "mermaid-md", "dot", "cytoscape-html", "cytoscape-json". The impact and diff commands support "text" and "json" as well and use "text" by default.
+//   - --offline - Embed the Mermaid JS library into the mermaid-html output, so it is rendered without network access. Optional. Default value is false.
+//   - --cytoscape-layout string - Layout of the cytoscape-html output. Default value "dagre". Possible values: "dagre", "force".
+//   - --group-by string - Grouping of the tables into mermaid subgraphs. Default value "none". Possible values: "none", "database", "cluster".
+//   - --style-config string - JSON file with the styles of the mermaid nodes which override the default shape, fill and border colors of the tables with the matching engines. Optional.
+//   - --legend - Add the legend of the engine styles used in the mermaid diagram. Optional. Default value is false.
 //   - --mermaid-theme - Mermaid theme. Optional. Default value is 'default'. See https://mermaid-js.github.io/mermaid/#/theming
 //   - --table-highlight-color - Highlight color for the selected clickhouse table. E.g. '#ff5757' or 'red' Optional. If not specified, the table will not be highlighted. See https://mermaid.js.org/syntax/flowchart.html?id=flowcharts-basic-syntax#styling-a-node
+//   - --cycle-highlight-color - Highlight color for the links and tables which form cycles, e.g. loops of materialized views. Optional. Default value is '#ff5757'. Empty value disables the highlighting.
+//   - --path-limit int - Maximum number of paths rendered by the path and explain commands. Must be positive. Optional. Default value is 10.
+//   - --shortest - Render only the shortest path with the path command. Optional. Default value is false.
+//   - --path-highlight-color - Highlight color for the links of the shortest path rendered by the path command. Optional. Default value is '#ff5757'. Empty value disables the highlighting.
+//   - --create - Print tables in the order they can be created with the order command. Optional. It is the default order.
+//   - --drop - Print tables in the order they can be dropped with the order command. Optional. Default value is false.
+//   - --statements - Print CREATE or DROP statements instead of table names with the order command. Optional. Default value is false.
+//   - --ddl-path string - Comma-separated list of .sql files, directories or glob patterns with DDL statements to get tables from instead of ClickHouse server. Optional.
+//   - --ddl-database string - Database for table names without database in DDL files. Optional. Default value is "default"
+//   - --snapshot-file string - Snapshot file created with the snapshot command to get tables from instead of ClickHouse server. Optional.
+//   - --snapshot-format string - Snapshot format for the snapshot command. Default value "json". Possible values: "json", "ndjson".
+//   - --direction string - Direction of the graph traversal from the clickhouse-table. Default value "both". Possible values: "both", "upstream", "downstream", "component".
+//   - --depth string - Maximum number of links followed from the clickhouse-table: "<depth>" for both directions or "<upstream>:<downstream>". Optional. If not specified, the depth is unlimited.
+//   - --all-tables - Create the graph of all tables including tables without links instead of the graph of the clickhouse-table. Optional. Default value is false.
 //
-// Note: The command will ask for the ClickHouse password for the specified user.
+// Note: The command will ask for the ClickHouse password for the specified user, unless the tables are read from DDL or snapshot files.
 //
 // For example command:
 //
@@ -25,32 +53,135 @@
 //  4. create a graph of tables connected to the specified table test_db.test_table;
 //  5. export graph to the mermaid html format;
 //  6. save the exported mermaid html to output.html file;
+//
+// The snapshot command:
+//
+//	go run . snapshot --clickhouse-host=localhost --clickhouse-user=test_user --out-file=schema.json
+//
+// saves all tables of the ClickHouse server to the schema.json file. The graph can be created from it later with the --snapshot-file=schema.json option.
+//
+// The explain command:
+//
+//	go run . explain test_db.input_table test_db.target_table --snapshot-file=schema.json
+//
+// prints why the test_db.input_table is connected to the test_db.target_table.
+//
+// The path command:
+//
+//	go run . path test_db.input_table test_db.target_table --snapshot-file=schema.json --out-format=mermaid-md
+//
+// prints the mermaid flowchart with all paths from the test_db.input_table to the test_db.target_table.
+//
+// The impact command:
+//
+//	go run . impact test_db.input_table --snapshot-file=schema.json --out-format=json
+//
+// prints all tables which break or stop receiving data if the test_db.input_table is dropped.
+//
+// The order command:
+//
+//	go run . order --create --statements --snapshot-file=schema.json
+//
+// prints CREATE statements of all tables, so every referenced table is created first.
+//
+// The diff command:
+//
+//	go run . diff old-schema.json clickhouse://test_user@localhost:9000 --out-format=mermaid-html --out-file=diff.html
+//
+// compares the tables of the snapshot file with the tables of the ClickHouse server and saves the graph of changes to diff.html.
 package main
 
 import (
+	"encoding/json"
 	"fmt"
+	"github.com/mbaksheev/clickhouse-table-graph/cytoscape"
+	"github.com/mbaksheev/clickhouse-table-graph/dot"
 	"github.com/mbaksheev/clickhouse-table-graph/graph"
 	"github.com/mbaksheev/clickhouse-table-graph/mermaid"
+	"github.com/mbaksheev/clickhouse-table-graph/snapshot"
 	"github.com/mbaksheev/clickhouse-table-graph/table"
 	"log"
 	"os"
+	"slices"
+	"strings"
 )
 
 func main() {
-	options, err := parseFlags()
-	handleError(err)
-	log.Printf("Creating graph for table %s.%s\n", options.clickhouseDatabase, options.clickhouseTable)
-	result, err := createTableGraph(options)
+	options, err := parseFlags(os.Args[1:])
 	handleError(err)
-	if options.outputMode == Stdout {
-		log.Println("\n" + result)
-	} else {
-		handleError(saveToFile(options.outputFile, result))
+	switch options.command {
+	case SnapshotCommand:
+		log.Printf("Creating snapshot of tables from %s\n", options.tableInfoProvider)
+		result, err := createSnapshot(options)
+		handleError(err)
+		if options.outputMode == Stdout {
+			fmt.Print(result)
+		} else {
+			handleError(saveToFile(options.outputFile, result))
+		}
+	case ExplainCommand:
+		log.Printf("Explaining links from %s to %s\n", options.fromTable, options.toTable)
+		result, err := explainLinks(options)
+		handleError(err)
+		if options.outputMode == Stdout {
+			fmt.Print(result)
+		} else {
+			handleError(saveToFile(options.outputFile, result))
+		}
+	case PathCommand:
+		log.Printf("Creating graph of paths from %s to %s\n", options.fromTable, options.toTable)
+		result, err := createPathGraph(options)
+		handleError(err)
+		if options.outputMode == Stdout {
+			log.Println("\n" + result)
+		} else {
+			handleError(saveToFile(options.outputFile, result))
+		}
+	case DiffCommand:
+		log.Printf("Comparing tables from %s and %s\n", options.oldTableInfoProvider, options.tableInfoProvider)
+		result, err := diffTables(options)
+		handleError(err)
+		if options.outputMode == Stdout {
+			fmt.Print(result)
+		} else {
+			handleError(saveToFile(options.outputFile, result))
+		}
+	case OrderCommand:
+		log.Printf("Ordering tables from %s\n", options.tableInfoProvider)
+		result, err := orderTables(options)
+		handleError(err)
+		if options.outputMode == Stdout {
+			fmt.Print(result)
+		} else {
+			handleError(saveToFile(options.outputFile, result))
+		}
+	case ImpactCommand:
+		log.Printf("Analyzing impact of dropping %s\n", options.fromTable)
+		result, err := createImpactReport(options)
+		handleError(err)
+		if options.outputMode == Stdout {
+			fmt.Print(result)
+		} else {
+			handleError(saveToFile(options.outputFile, result))
+		}
+	default:
+		if options.allTables {
+			log.Println("Creating graph for all tables")
+		} else {
+			log.Printf("Creating graph for tables %s\n", joinPatterns(options.tablePatterns))
+		}
+		result, err := createTableGraph(options)
+		handleError(err)
+		if options.outputMode == Stdout {
+			log.Println("\n" + result)
+		} else {
+			handleError(saveToFile(options.outputFile, result))
+		}
 	}
 }
 
 func createTableGraph(options inputOptions) (string, error) {
-	tables, err := options.clickhouseServer.TableInfos()
+	tables, err := options.tableInfoProvider.TableInfos()
 	if err != nil {
 		return "", err
 	}
@@ -58,27 +189,269 @@ func createTableGraph(options inputOptions) (string, error) {
 	for _, t := range tables {
 		myTableGraph.AddTable(t)
 	}
-	tableLinks, err := myTableGraph.TableLinks(table.Key{Database: options.clickhouseDatabase, Name: options.clickhouseTable})
+	title := fmt.Sprintf("ClickHouse table dependencies graph for %s", joinPatterns(options.tablePatterns))
+	var tableLinks *graph.Links
+	if options.allTables {
+		tableLinks = myTableGraph.FullGraph()
+		title = "ClickHouse table dependencies graph for all tables"
+	} else {
+		initialTables := table.Select(options.tablePatterns, tables)
+		if len(initialTables) == 0 {
+			return "", fmt.Errorf("createTableGraph: no tables match %s", joinPatterns(options.tablePatterns))
+		}
+		tableLinks, err = myTableGraph.TablesLinks(
+			initialTables,
+			graph.WithDirection(options.direction),
+			graph.WithMaxDepth(options.maxUpstreamDepth, options.maxDownstreamDepth),
+		)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	for _, cycle := range tableLinks.Cycles() {
+		log.Printf("Warning: cycle of tables found: %s\n", cycle)
+	}
+
+	return renderGraph(options, *tableLinks, title, mermaid.FlowchartOptions{
+		Orientation:                mermaid.TB,
+		IncludeEngine:              true,
+		Theme:                      options.mermaidTheme,
+		Grouping:                   options.grouping,
+		EngineStyles:               options.engineStyles,
+		Legend:                     options.legend,
+		InitialTableHighlightColor: options.tableHighlightColor,
+		CycleHighlightColor:        options.cycleHighlightColor,
+	})
+}
+
+// createPathGraph returns the graph which contains only the paths from the fromTable table to the toTable table.
+// Links of the shortest path are highlighted. If the shortestPath option is set, only the shortest path is rendered.
+func createPathGraph(options inputOptions) (string, error) {
+	tables, err := options.tableInfoProvider.TableInfos()
 	if err != nil {
 		return "", err
 	}
+	tableGraph := graph.New()
+	for _, t := range tables {
+		tableGraph.AddTable(t)
+	}
+	from, to := options.fromTable, options.toTable
+	shortest, err := tableGraph.ShortestPath(from, to)
+	if err != nil {
+		return "", err
+	}
+	if len(shortest) == 0 {
+		return "", fmt.Errorf("createPathGraph: no paths from %s to %s found", from, to)
+	}
+	pathLinks := slices.Clone(shortest)
+	if !options.shortestPath {
+		paths, err := tableGraph.SimplePaths(from, to, options.pathLimit)
+		if err != nil {
+			return "", err
+		}
+		log.Printf("Found %d paths from %s to %s, the shortest path has %d links\n", len(paths), from, to, len(shortest))
+		pathLinks = append(slices.Concat(paths...), shortest...)
+	}
+	pathGraph := tableGraph.FullGraph().Subgraph(pathLinks, from, to)
 
-	mermaidFlowchart := mermaid.Flowchart(*tableLinks, mermaid.FlowchartOptions{
+	title := fmt.Sprintf("ClickHouse table paths from %s to %s", from, to)
+	return renderGraph(options, *pathGraph, title, mermaid.FlowchartOptions{
 		Orientation:                mermaid.TB,
 		IncludeEngine:              true,
 		Theme:                      options.mermaidTheme,
+		Grouping:                   options.grouping,
+		EngineStyles:               options.engineStyles,
+		Legend:                     options.legend,
 		InitialTableHighlightColor: options.tableHighlightColor,
+		LinkHighlights:             []graph.LinkHighlight{{Links: shortest, Color: options.pathHighlightColor}},
 	})
-	var result string
-	if options.outputFormat == MermaidMarkdown {
-		result = mermaidFlowchart
+}
+
+// orderTables returns the list of tables in the order they can be created, or dropped if the dropOrder option is set.
+// If the orderStatements option is set, CREATE or DROP statements are returned instead of table names.
+// If the table patterns are specified, only the matched tables are returned in the order of the complete graph.
+func orderTables(options inputOptions) (string, error) {
+	tables, err := options.tableInfoProvider.TableInfos()
+	if err != nil {
+		return "", err
+	}
+	tableGraph := graph.New()
+	for _, t := range tables {
+		tableGraph.AddTable(t)
+	}
+	var order []table.Key
+	if options.dropOrder {
+		order, err = tableGraph.DropOrder()
 	} else {
-		result = mermaid.Html(mermaidFlowchart, mermaid.HtmlOptions{
-			Title: fmt.Sprintf("ClickHouse table dependencies graph for %s.%s", options.clickhouseDatabase, options.clickhouseTable),
+		order, err = tableGraph.CreationOrder()
+	}
+	if err != nil {
+		return "", err
+	}
+	if len(options.tablePatterns) > 0 {
+		selected := table.Select(options.tablePatterns, tables)
+		order = slices.DeleteFunc(order, func(key table.Key) bool {
+			return !slices.Contains(selected, key)
 		})
 	}
+	tableInfos := make(map[table.Key]table.Info, len(tables))
+	for _, t := range tables {
+		tableInfos[t.Key] = t
+	}
+	var result strings.Builder
+	for _, key := range order {
+		tableInfo, exists := tableInfos[key]
+		switch {
+		case !options.orderStatements:
+			result.WriteString(key.String() + "\n")
+		case !exists:
+			fmt.Fprintf(&result, "-- %s: table does not exist\n", key)
+		case options.dropOrder:
+			fmt.Fprintf(&result, "DROP %s IF EXISTS %s;\n", objectType(tableInfo.Engine), key)
+		case tableInfo.CreateTableQuery == "":
+			fmt.Fprintf(&result, "-- %s: create query is not available\n", key)
+		default:
+			result.WriteString(strings.TrimSuffix(strings.TrimSpace(tableInfo.CreateTableQuery), ";") + ";\n")
+		}
+	}
+	return result.String(), nil
+}
 
-	return result, nil
+// objectType returns the type of the object with the specified engine used in DROP statement: TABLE, VIEW or DICTIONARY.
+func objectType(engine string) string {
+	switch engine {
+	case "MaterializedView", "View", "LiveView", "WindowView":
+		return "VIEW"
+	case "Dictionary":
+		return "DICTIONARY"
+	default:
+		return "TABLE"
+	}
+}
+
+// renderGraph returns the graph in the specified output format: mermaid html, mermaid markdown, Graphviz DOT,
+// Cytoscape.js html or Cytoscape.js elements JSON. The flowchart options describe the highlighting of the graph
+// and are converted to the DOT options and the Cytoscape.js options for the other formats.
+// The mermaid html embeds the table details shown when the node is clicked, and the Mermaid JS library if the offline option is set.
+func renderGraph(options inputOptions, graphLinks graph.Links, title string, flowchartOptions mermaid.FlowchartOptions) (string, error) {
+	switch options.outputFormat {
+	case Dot:
+		return dot.Graph(graphLinks, dotOptions(flowchartOptions, title)), nil
+	case CytoscapeHtml:
+		return cytoscape.Html(cytoscape.Elements(graphLinks, cytoscapeOptions(flowchartOptions)), cytoscape.HtmlOptions{
+			Title:  title,
+			Layout: options.cytoscapeLayout,
+		})
+	case CytoscapeJson:
+		elements, err := json.MarshalIndent(cytoscape.Elements(graphLinks, cytoscapeOptions(flowchartOptions)), "", "  ")
+		if err != nil {
+			return "", fmt.Errorf("renderGraph: failed to marshal cytoscape elements: %w", err)
+		}
+		return string(elements) + "\n", nil
+	case MermaidMarkdown:
+		return mermaid.Flowchart(graphLinks, flowchartOptions), nil
+	default:
+		flowchartOptions.Clickable = true
+		return mermaid.Html(mermaid.Flowchart(graphLinks, flowchartOptions), mermaid.HtmlOptions{
+			Title:   title,
+			Offline: options.offline,
+			Graph:   &graphLinks,
+		})
+	}
+}
+
+// dotOptions returns the DOT options with the same highlighting as the specified flowchart options.
+func dotOptions(flowchartOptions mermaid.FlowchartOptions, title string) dot.GraphOptions {
+	return dot.GraphOptions{
+		Title:                      title,
+		RankDir:                    dot.TB,
+		IncludeEngine:              flowchartOptions.IncludeEngine,
+		InitialTableHighlightColor: flowchartOptions.InitialTableHighlightColor,
+		CycleHighlightColor:        flowchartOptions.CycleHighlightColor,
+		LinkHighlights:             flowchartOptions.LinkHighlights,
+		TableHighlights:            flowchartOptions.TableHighlights,
+	}
+}
+
+// cytoscapeOptions returns the Cytoscape.js options with the same highlighting as the specified flowchart options.
+func cytoscapeOptions(flowchartOptions mermaid.FlowchartOptions) cytoscape.ElementsOptions {
+	return cytoscape.ElementsOptions{
+		IncludeEngine:              flowchartOptions.IncludeEngine,
+		InitialTableHighlightColor: flowchartOptions.InitialTableHighlightColor,
+		CycleHighlightColor:        flowchartOptions.CycleHighlightColor,
+		LinkHighlights:             flowchartOptions.LinkHighlights,
+		TableHighlights:            flowchartOptions.TableHighlights,
+	}
+}
+
+// joinPatterns returns the comma-separated list of the specified patterns of tables.
+func joinPatterns(patterns []table.Pattern) string {
+	values := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		values = append(values, pattern.String())
+	}
+	return strings.Join(values, ", ")
+}
+
+func createSnapshot(options inputOptions) (string, error) {
+	tables, err := options.tableInfoProvider.TableInfos()
+	if err != nil {
+		return "", err
+	}
+	var result strings.Builder
+	if err := snapshot.Write(&result, tables, options.snapshotFormat); err != nil {
+		return "", err
+	}
+	return result.String(), nil
+}
+
+// explainLinks returns the text description of the paths from the fromTable table to the toTable table, at most pathLimit paths.
+// Each link of the path is printed with its kind and provenance.
+// If there are no paths in the direction of the links, the paths in the opposite direction are described.
+func explainLinks(options inputOptions) (string, error) {
+	tables, err := options.tableInfoProvider.TableInfos()
+	if err != nil {
+		return "", err
+	}
+	tableGraph := graph.New()
+	for _, t := range tables {
+		tableGraph.AddTable(t)
+	}
+	from, to := options.fromTable, options.toTable
+	paths, err := tableGraph.SimplePaths(from, to, options.pathLimit)
+	if err != nil {
+		return "", err
+	}
+	var result strings.Builder
+	if len(paths) == 0 {
+		fmt.Fprintf(&result, "No paths from %s to %s found\n", from, to)
+		from, to = to, from
+		if paths, err = tableGraph.SimplePaths(from, to, options.pathLimit); err != nil {
+			return "", err
+		}
+		if len(paths) == 0 {
+			return result.String(), nil
+		}
+		fmt.Fprintf(&result, "Paths in the opposite direction:\n")
+	}
+	if len(paths) == options.pathLimit {
+		fmt.Fprintf(&result, "The number of paths is limited to %d, use --path-limit to change the limit\n", options.pathLimit)
+	}
+	for i, path := range paths {
+		fmt.Fprintf(&result, "\nPath %d of %d: %s", i+1, len(paths), from)
+		for _, link := range path {
+			fmt.Fprintf(&result, " -> %s", link.ToTableKey)
+		}
+		result.WriteString("\n")
+		for _, link := range path {
+			fmt.Fprintf(&result, "  %s -> %s (%s)\n", link.FromTableKey, link.ToTableKey, link.Kind)
+			for _, provenance := range link.Provenance {
+				fmt.Fprintf(&result, "    %s\n", provenance)
+			}
+		}
+	}
+	return result.String(), nil
 }
 
 func saveToFile(fileName, result string) error {
@@ -2,10 +2,20 @@ package main
 
 import (
 	"context"
+	"encoding/json"
+	"flag"
 	"github.com/mbaksheev/clickhouse-table-graph/clickhouse"
+	"github.com/mbaksheev/clickhouse-table-graph/cytoscape"
+	"github.com/mbaksheev/clickhouse-table-graph/ddl"
+	"github.com/mbaksheev/clickhouse-table-graph/dot"
+	"github.com/mbaksheev/clickhouse-table-graph/graph"
+	"github.com/mbaksheev/clickhouse-table-graph/mermaid"
+	"github.com/mbaksheev/clickhouse-table-graph/snapshot"
+	"github.com/mbaksheev/clickhouse-table-graph/table"
 	"github.com/testcontainers/testcontainers-go"
 	tcClickhouse "github.com/testcontainers/testcontainers-go/modules/clickhouse"
 	"log"
+	"os"
 	"path/filepath"
 	"strings"
 	"testing"
@@ -46,10 +56,9 @@ func TestCreateTableGraph(t *testing.T) {
 		}
 
 		mermaid, err := createTableGraph(inputOptions{
-			clickhouseServer:   chServer,
-			clickhouseDatabase: "test_db",
-			clickhouseTable:    "input_table",
-			outputFormat:       MermaidMarkdown,
+			tableInfoProvider: &chServer,
+			tablePatterns:     mustParsePatterns(t, "test_db.input_table"),
+			outputFormat:      MermaidMarkdown,
 		})
 		if err != nil {
 			t.Errorf("failed to create table graph: %s", err)
@@ -74,3 +83,651 @@ func TestCreateTableGraph(t *testing.T) {
 	})
 
 }
+
+func TestCreateTableGraphFromDdlFiles(t *testing.T) {
+	mermaid, err := createTableGraph(inputOptions{
+		tableInfoProvider: &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+		tablePatterns:     mustParsePatterns(t, "test_db.input_table"),
+		outputFormat:      MermaidMarkdown,
+	})
+	if err != nil {
+		t.Fatalf("failed to create table graph: %s", err)
+	}
+	expectedTables := []string{
+		"test_db.input_table (Null)",
+		"test_db.target_table_mv (MaterializedView)",
+		"test_db.target_table (ReplacingMergeTree)",
+		"test_db.base_1 (MergeTree)",
+		"test_db.base_2 (MergeTree)",
+		"test_db.join_target_mv (MaterializedView)",
+		"test_db.dict_a (Dictionary)",
+	}
+	for _, expectedTable := range expectedTables {
+		if !strings.Contains(mermaid, expectedTable) {
+			t.Errorf("expected table '%s' not found in mermaid result", expectedTable)
+		}
+	}
+}
+
+func TestCreateTableGraphFromSnapshot(t *testing.T) {
+	snapshotJson, err := createSnapshot(inputOptions{
+		tableInfoProvider: &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+		snapshotFormat:    snapshot.NDJSON,
+	})
+	if err != nil {
+		t.Fatalf("failed to create snapshot: %s", err)
+	}
+	snapshotFileName := filepath.Join(t.TempDir(), "snapshot.ndjson")
+	if err := saveToFile(snapshotFileName, snapshotJson); err != nil {
+		t.Fatalf("failed to save snapshot: %s", err)
+	}
+
+	mermaid, err := createTableGraph(inputOptions{
+		tableInfoProvider: &snapshot.File{Path: snapshotFileName},
+		tablePatterns:     mustParsePatterns(t, "test_db.input_table"),
+		outputFormat:      MermaidMarkdown,
+	})
+	if err != nil {
+		t.Fatalf("failed to create table graph: %s", err)
+	}
+	expectedTables := []string{
+		"test_db.input_table (Null)",
+		"test_db.target_table_mv (MaterializedView)",
+		"test_db.target_table (ReplacingMergeTree)",
+	}
+	for _, expectedTable := range expectedTables {
+		if !strings.Contains(mermaid, expectedTable) {
+			t.Errorf("expected table '%s' not found in mermaid result", expectedTable)
+		}
+	}
+}
+
+func TestExplainLinksFromDdlFiles(t *testing.T) {
+	explanation, err := explainLinks(inputOptions{
+		tableInfoProvider: &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+		fromTable:         table.Key{Database: "test_db", Name: "input_table"},
+		toTable:           table.Key{Database: "test_db", Name: "join_target"},
+		pathLimit:         1,
+	})
+	if err != nil {
+		t.Fatalf("failed to explain links: %s", err)
+	}
+	expectedLines := []string{
+		"The number of paths is limited to 1, use --path-limit to change the limit",
+		"Path 1 of 1: test_db.input_table -> test_db.join_target_mv -> test_db.join_target",
+		"test_db.input_table -> test_db.join_target_mv (MVTrigger)",
+		"MaterializedViewSources: test_db.join_target_mv.create_table_query[189:208] = test_db.input_table",
+		"MaterializedViewTarget: test_db.join_target_mv.create_table_query[48:70] = TO test_db.join_target",
+	}
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(explanation, expectedLine) {
+			t.Errorf("expected line '%s' not found in explanation:\n%s", expectedLine, explanation)
+		}
+	}
+}
+
+func TestCreateAllTablesGraphFromDdlFiles(t *testing.T) {
+	mermaid, err := createTableGraph(inputOptions{
+		tableInfoProvider: &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+		allTables:         true,
+		outputFormat:      MermaidMarkdown,
+	})
+	if err != nil {
+		t.Fatalf("failed to create table graph: %s", err)
+	}
+	expectedTables := []string{
+		"test_db.input_table (Null)",
+		"test_db.join_target (MergeTree)",
+		"test_db.dict_b (Dictionary)",
+	}
+	for _, expectedTable := range expectedTables {
+		if !strings.Contains(mermaid, expectedTable) {
+			t.Errorf("expected table '%s' not found in mermaid result", expectedTable)
+		}
+	}
+}
+
+func TestParseDepth(t *testing.T) {
+	tests := []struct {
+		value          string
+		wantUpstream   int
+		wantDownstream int
+		wantErr        bool
+	}{
+		{value: "2", wantUpstream: 2, wantDownstream: 2},
+		{value: "1:3", wantUpstream: 1, wantDownstream: 3},
+		{value: "0:1", wantUpstream: 0, wantDownstream: 1},
+		{value: "-1", wantErr: true},
+		{value: "1:x", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			upstream, downstream, err := parseDepth(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseDepth() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if upstream != tt.wantUpstream || downstream != tt.wantDownstream {
+				t.Errorf("parseDepth() = %d, %d, want %d, %d", upstream, downstream, tt.wantUpstream, tt.wantDownstream)
+			}
+		})
+	}
+}
+
+func TestParseOutputOptions(t *testing.T) {
+	t.Cleanup(func() { *outFormat = "mermaid-html" })
+	tests := []struct {
+		format      string
+		textFormats bool
+		want        outputFormat
+		wantErr     bool
+	}{
+		{format: "mermaid-md", want: MermaidMarkdown},
+		{format: "cytoscape-json", textFormats: true, want: CytoscapeJson},
+		{format: "json", textFormats: true, want: JSON},
+		{format: "text", wantErr: true},
+		{format: "svg", textFormats: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			if err := flag.CommandLine.Set("out-format", tt.format); err != nil {
+				t.Fatal(err)
+			}
+			var inputOpts inputOptions
+			err := parseOutputOptions(&inputOpts, tt.textFormats)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseOutputOptions() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && inputOpts.outputFormat != tt.want {
+				t.Errorf("parseOutputOptions() output format = %d, want %d", inputOpts.outputFormat, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateDownstreamTableGraphFromDdlFiles(t *testing.T) {
+	mermaid, err := createTableGraph(inputOptions{
+		tableInfoProvider:  &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+		tablePatterns:      mustParsePatterns(t, "test_db.join_target_mv"),
+		direction:          graph.Downstream,
+		maxDownstreamDepth: 1,
+		outputFormat:       MermaidMarkdown,
+	})
+	if err != nil {
+		t.Fatalf("failed to create table graph: %s", err)
+	}
+	if !strings.Contains(mermaid, "test_db.join_target (MergeTree)") {
+		t.Errorf("expected table 'test_db.join_target (MergeTree)' not found in mermaid result")
+	}
+	if strings.Contains(mermaid, "test_db.input_table") {
+		t.Errorf("unexpected upstream table 'test_db.input_table' found in mermaid result")
+	}
+}
+
+func mustParsePatterns(t *testing.T, values ...string) []table.Pattern {
+	patterns, err := parsePatterns(values)
+	if err != nil {
+		t.Fatalf("failed to parse table pattern: %s", err)
+	}
+	return patterns
+}
+
+func TestCreateMultipleTablesGraphFromDdlFiles(t *testing.T) {
+	mermaid, err := createTableGraph(inputOptions{
+		tableInfoProvider:   &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+		tablePatterns:       mustParsePatterns(t, "test_db.base_*", "re:^test_db\\.dict_[ab]$"),
+		direction:           graph.Downstream,
+		outputFormat:        MermaidMarkdown,
+		tableHighlightColor: "red",
+	})
+	if err != nil {
+		t.Fatalf("failed to create table graph: %s", err)
+	}
+	expectedLines := []string{
+		"test_db.join_target (MergeTree)",
+		"test_db.target_table_dict (MergeTree)",
+		"style " + nodeId(t, "test_db.base_1") + " stroke:red",
+		"style " + nodeId(t, "test_db.base_2") + " stroke:red",
+		"style " + nodeId(t, "test_db.dict_a") + " stroke:red",
+		"style " + nodeId(t, "test_db.dict_b") + " stroke:red",
+	}
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(mermaid, expectedLine) {
+			t.Errorf("expected '%s' not found in mermaid result:\n%s", expectedLine, mermaid)
+		}
+	}
+	_, err = createTableGraph(inputOptions{
+		tableInfoProvider: &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+		tablePatterns:     mustParsePatterns(t, "other_db.*"),
+	})
+	if err == nil {
+		t.Errorf("expected error for patterns without matched tables")
+	}
+}
+
+func TestCreateDotTableGraphFromDdlFiles(t *testing.T) {
+	dotGraph, err := createTableGraph(inputOptions{
+		tableInfoProvider:   &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+		tablePatterns:       mustParsePatterns(t, "test_db.input_table"),
+		outputFormat:        Dot,
+		tableHighlightColor: "red",
+	})
+	if err != nil {
+		t.Fatalf("failed to create table graph: %s", err)
+	}
+	expectedLines := []string{
+		"digraph {",
+		"subgraph cluster_test_db_",
+		"    label=\"test_db\";",
+		dotNodeId(t, "test_db.input_table") + " [label=\"test_db.input_table (Null)\", shape=box, color=\"red\", penwidth=2, style=\"rounded\"];",
+		dotNodeId(t, "test_db.target_table_mv") + " [label=\"test_db.target_table_mv (MaterializedView)\", shape=hexagon];",
+		dotNodeId(t, "test_db.dict_a") + " [label=\"test_db.dict_a (Dictionary)\", shape=component];",
+		dotNodeId(t, "test_db.target_table_mv") + " -> " + dotNodeId(t, "test_db.target_table") + " [label=\"target\"];",
+	}
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(dotGraph, expectedLine) {
+			t.Errorf("expected '%s' not found in dot result:\n%s", expectedLine, dotGraph)
+		}
+	}
+}
+
+func TestCreateCytoscapeTableGraphFromDdlFiles(t *testing.T) {
+	options := inputOptions{
+		tableInfoProvider:   &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+		tablePatterns:       mustParsePatterns(t, "test_db.input_table"),
+		outputFormat:        CytoscapeJson,
+		tableHighlightColor: "red",
+	}
+	elementsJson, err := createTableGraph(options)
+	if err != nil {
+		t.Fatalf("failed to create table graph: %s", err)
+	}
+	var elements []cytoscape.Element
+	if err := json.Unmarshal([]byte(elementsJson), &elements); err != nil {
+		t.Fatalf("failed to parse cytoscape elements: %s\n%s", err, elementsJson)
+	}
+	found := make(map[string]cytoscape.Element)
+	for _, element := range elements {
+		found[element.Data.Id] = element
+	}
+	inputId := cytoscapeNodeId(t, "test_db.input_table")
+	if input := found[inputId]; input.Data.Name != "test_db.input_table" || input.Data.Color != "red" || input.Data.Shape != "round-rectangle" {
+		t.Errorf("expected highlighted test_db.input_table node, got %+v", input)
+	}
+	targetId := cytoscapeNodeId(t, "test_db.target_table_mv") + "-" + cytoscapeNodeId(t, "test_db.target_table") + "-MVTarget"
+	if target := found[targetId]; target.Classes != "target" {
+		t.Errorf("expected materialized view target edge, got %+v", target)
+	}
+
+	options.outputFormat = CytoscapeHtml
+	options.cytoscapeLayout, err = parseCytoscapeLayout("force")
+	if err != nil {
+		t.Fatalf("failed to parse cytoscape layout: %s", err)
+	}
+	html, err := createTableGraph(options)
+	if err != nil {
+		t.Fatalf("failed to create table graph: %s", err)
+	}
+	for _, expectedLine := range []string{`data-layout="cose"`, `"id":"` + inputId + `","name":"test_db.input_table"`} {
+		if !strings.Contains(html, expectedLine) {
+			t.Errorf("expected '%s' not found in html result:\n%s", expectedLine, html)
+		}
+	}
+	if _, err := parseCytoscapeLayout("circle"); err == nil {
+		t.Errorf("expected error for unknown cytoscape layout")
+	}
+}
+
+func TestCreateGroupedTableGraphFromDdlFiles(t *testing.T) {
+	mermaid, err := createTableGraph(inputOptions{
+		tableInfoProvider: &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+		tablePatterns:     mustParsePatterns(t, "test_db.input_table"),
+		outputFormat:      MermaidMarkdown,
+		grouping:          parseTestGrouping(t, "database"),
+	})
+	if err != nil {
+		t.Fatalf("failed to create table graph: %s", err)
+	}
+	if count := strings.Count(mermaid, "subgraph "); count != 1 {
+		t.Errorf("expected 1 subgraph, got %d:\n%s", count, mermaid)
+	}
+	if !strings.Contains(mermaid, " [\"test_db\"]\n") || !strings.Contains(mermaid, "\n  "+nodeId(t, "test_db.input_table")+"@{") {
+		t.Errorf("expected test_db.input_table in test_db subgraph:\n%s", mermaid)
+	}
+	if _, err := parseGrouping("schema"); err == nil {
+		t.Errorf("expected error for unknown grouping")
+	}
+}
+
+func TestCreateHtmlTableGraphFromDdlFiles(t *testing.T) {
+	html, err := createTableGraph(inputOptions{
+		tableInfoProvider: &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+		tablePatterns:     mustParsePatterns(t, "test_db.input_table"),
+		outputFormat:      MermaidHtml,
+	})
+	if err != nil {
+		t.Fatalf("failed to create table graph: %s", err)
+	}
+	expectedLines := []string{
+		"click " + nodeId(t, "test_db.target_table") + " selectTable",
+		"\"" + nodeId(t, "test_db.target_table") + "\":{\"name\":\"test_db.target_table\",\"exists\":true,\"engine\":\"ReplacingMergeTree\"",
+		"{\"from\":\"" + nodeId(t, "test_db.target_table_mv") + "\",\"to\":\"" + nodeId(t, "test_db.target_table") + "\",\"kind\":\"MVTarget\"}",
+		"\"sorting_key\":\"(id)\"",
+		"function selectTable(nodeId)",
+	}
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(html, expectedLine) {
+			t.Errorf("expected '%s' not found in html result:\n%s", expectedLine, html)
+		}
+	}
+}
+
+func TestCreateStyledTableGraphFromDdlFiles(t *testing.T) {
+	styleConfigPath := filepath.Join(t.TempDir(), "style.json")
+	styleConfig := `{"engine_styles": [{"name": "Entry point", "engines": ["Null"], "shape": "circle", "fill": "#ffffff"}]}`
+	if err := os.WriteFile(styleConfigPath, []byte(styleConfig), 0o644); err != nil {
+		t.Fatalf("failed to write style config: %s", err)
+	}
+	engineStyles, err := loadEngineStyles(styleConfigPath)
+	if err != nil {
+		t.Fatalf("failed to load style config: %s", err)
+	}
+	mermaid, err := createTableGraph(inputOptions{
+		tableInfoProvider: &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+		tablePatterns:     mustParsePatterns(t, "test_db.input_table"),
+		outputFormat:      MermaidMarkdown,
+		engineStyles:      engineStyles,
+		legend:            true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create table graph: %s", err)
+	}
+	expectedLines := []string{
+		nodeId(t, "test_db.input_table") + "@{ shape: circle, label: \"test_db.input_table (Null)\" }",
+		"subgraph legend [\"Legend\"]",
+		"@{ shape: circle, label: \"Entry point\" }",
+		"@{ shape: hex, label: \"MaterializedView\" }",
+		" fill:#ffffff,stroke:#757575\nclass " + nodeId(t, "test_db.input_table") + ",",
+	}
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(mermaid, expectedLine) {
+			t.Errorf("expected '%s' not found in mermaid result:\n%s", expectedLine, mermaid)
+		}
+	}
+}
+
+func TestLoadEngineStyles(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  string
+		wantErr bool
+	}{
+		{name: "engines", config: `{"engine_styles": [{"engines": ["Kafka"], "shape": "h-cyl", "fill": "#fbe9e7", "stroke": "red"}]}`},
+		{name: "engine regex", config: `{"engine_styles": [{"engine_regex": "^Replicated", "fill": "#e8f5e9"}]}`},
+		{name: "invalid json", config: `{"engine_styles": [`, wantErr: true},
+		{name: "invalid regex", config: `{"engine_styles": [{"engine_regex": "^(Replicated"}]}`, wantErr: true},
+		{name: "no engines", config: `{"engine_styles": [{"fill": "#e8f5e9"}]}`, wantErr: true},
+		{name: "invalid shape", config: `{"engine_styles": [{"engines": ["Kafka"], "shape": "h-cyl }"}]}`, wantErr: true},
+		{name: "invalid color", config: `{"engine_styles": [{"engines": ["Kafka"], "fill": "red;stroke-width:9px"}]}`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "style.json")
+			if err := os.WriteFile(path, []byte(tt.config), 0o644); err != nil {
+				t.Fatalf("failed to write style config: %s", err)
+			}
+			_, err := loadEngineStyles(path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("loadEngineStyles() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCreateCycleTableGraphFromDdlFiles(t *testing.T) {
+	mermaid, err := createTableGraph(inputOptions{
+		tableInfoProvider:   &ddl.Files{Paths: []string{filepath.Join("main_test_data", "cycle-db.sql")}},
+		tablePatterns:       mustParsePatterns(t, "cycle_db.events"),
+		outputFormat:        MermaidMarkdown,
+		tableHighlightColor: "red",
+		cycleHighlightColor: "orange",
+	})
+	if err != nil {
+		t.Fatalf("failed to create table graph: %s", err)
+	}
+	expectedLines := []string{
+		"linkStyle 0,1,2,3 stroke:orange,stroke-width:2px",
+		"style " + nodeId(t, "cycle_db.events") + " stroke:red",
+		"style " + nodeId(t, "cycle_db.events_mv") + " stroke:orange,stroke-dasharray:5 5",
+		"style " + nodeId(t, "cycle_db.events_copy") + " stroke:orange,stroke-dasharray:5 5",
+		"style " + nodeId(t, "cycle_db.events_copy_mv") + " stroke:orange,stroke-dasharray:5 5",
+	}
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(mermaid, expectedLine) {
+			t.Errorf("expected '%s' not found in mermaid result:\n%s", expectedLine, mermaid)
+		}
+	}
+	if strings.Contains(mermaid, "style "+nodeId(t, "cycle_db.events")+" stroke:orange") {
+		t.Errorf("expected highlighted initial table not to be styled as a cycle:\n%s", mermaid)
+	}
+}
+
+func TestCreatePathGraphFromDdlFiles(t *testing.T) {
+	mermaid, err := createPathGraph(inputOptions{
+		tableInfoProvider:   &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+		fromTable:           table.Key{Database: "test_db", Name: "input_table"},
+		toTable:             table.Key{Database: "test_db", Name: "join_target"},
+		pathLimit:           10,
+		outputFormat:        MermaidMarkdown,
+		tableHighlightColor: "red",
+		pathHighlightColor:  "orange",
+	})
+	if err != nil {
+		t.Fatalf("failed to create path graph: %s", err)
+	}
+	expectedLines := []string{
+		nodeId(t, "test_db.input_table") + "@{ shape: rounded, label: \"test_db.input_table (Null)\" }\n",
+		nodeId(t, "test_db.join_target_mv") + "@{ shape: hex, label: \"test_db.join_target_mv (MaterializedView)\" }\n",
+		nodeId(t, "test_db.join_target") + "@{ shape: rect, label: \"test_db.join_target (MergeTree)\" }\n",
+		nodeId(t, "test_db.input_table") + " --> " + nodeId(t, "test_db.join_target_mv") + "\n",
+		nodeId(t, "test_db.join_target_mv") + " -->|target| " + nodeId(t, "test_db.join_target") + "\n",
+		"style " + nodeId(t, "test_db.input_table") + " stroke:red",
+		"style " + nodeId(t, "test_db.join_target") + " stroke:red",
+		"linkStyle 0,1 stroke:orange,stroke-width:2px",
+	}
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(mermaid, expectedLine) {
+			t.Errorf("expected '%s' not found in mermaid result:\n%s", expectedLine, mermaid)
+		}
+	}
+	if strings.Contains(mermaid, "test_db.target_table ") {
+		t.Errorf("expected only tables of the paths in mermaid result:\n%s", mermaid)
+	}
+	_, err = createPathGraph(inputOptions{
+		tableInfoProvider: &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+		fromTable:         table.Key{Database: "test_db", Name: "join_target"},
+		toTable:           table.Key{Database: "test_db", Name: "input_table"},
+		outputFormat:      MermaidMarkdown,
+	})
+	if err == nil {
+		t.Errorf("expected error for tables without paths")
+	}
+}
+
+func TestImpactReportFromDdlFiles(t *testing.T) {
+	options := inputOptions{
+		tableInfoProvider: &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+		fromTable:         table.Key{Database: "test_db", Name: "dict_a"},
+		outputFormat:      Text,
+	}
+	text, err := createImpactReport(options)
+	if err != nil {
+		t.Fatalf("failed to create impact report: %s", err)
+	}
+	expectedLines := []string{
+		"Impact of dropping test_db.dict_a: 2 hard breaks, 5 tables stop receiving data",
+		"HardBreak          InsertsFail test_db.target_table_dict_mv_mv (MaterializedView): dictionary test_db.dict_a is dropped",
+		"HardBreak          InsertsFail test_db.input_table (Null): materialized view test_db.target_table_dict_mv_mv fails on insert",
+		"StopsReceivingData NoNewData   test_db.target_table_dict (MergeTree): materialized view test_db.target_table_dict_mv_mv fails on insert",
+		"  via test_db.dict_a -> test_db.target_table_dict_mv_mv (DictionaryLookup)",
+	}
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(text, expectedLine) {
+			t.Errorf("expected '%s' not found in impact report:\n%s", expectedLine, text)
+		}
+	}
+
+	options.outputFormat = JSON
+	jsonText, err := createImpactReport(options)
+	if err != nil {
+		t.Fatalf("failed to create impact report: %s", err)
+	}
+	var report impactReportJSON
+	if err := json.Unmarshal([]byte(jsonText), &report); err != nil {
+		t.Fatalf("failed to parse impact report: %s\n%s", err, jsonText)
+	}
+	if report.Table != "test_db.dict_a" || len(report.Impacts) != 7 {
+		t.Fatalf("unexpected impact report: %+v", report)
+	}
+	first := report.Impacts[0]
+	if first.Table != "test_db.target_table_dict_mv_mv" || first.Severity != "HardBreak" || first.Link.Kind != "DictionaryLookup" {
+		t.Errorf("unexpected first impact: %+v", first)
+	}
+}
+
+func TestOrderTablesFromDdlFiles(t *testing.T) {
+	order, err := orderTables(inputOptions{
+		tableInfoProvider: &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+	})
+	if err != nil {
+		t.Fatalf("failed to order tables: %s", err)
+	}
+	expectedOrder := strings.Join([]string{
+		"test_db.base_1",
+		"test_db.base_2",
+		"test_db.dict_a",
+		"test_db.dict_b",
+		"test_db.input_table",
+		"test_db.join_target",
+		"test_db.join_target_mv",
+		"test_db.target_table",
+		"test_db.target_table_dict",
+		"test_db.target_table_dict_mv_mv",
+		"test_db.target_table_mv",
+	}, "\n") + "\n"
+	if order != expectedOrder {
+		t.Errorf("unexpected creation order:\n%s\nwant:\n%s", order, expectedOrder)
+	}
+
+	statements, err := orderTables(inputOptions{
+		tableInfoProvider: &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+		tablePatterns:     mustParsePatterns(t, "test_db.input_table", "test_db.target_table*"),
+		dropOrder:         true,
+		orderStatements:   true,
+	})
+	if err != nil {
+		t.Fatalf("failed to order tables: %s", err)
+	}
+	expectedStatements := strings.Join([]string{
+		"DROP VIEW IF EXISTS test_db.target_table_mv;",
+		"DROP VIEW IF EXISTS test_db.target_table_dict_mv_mv;",
+		"DROP TABLE IF EXISTS test_db.target_table_dict;",
+		"DROP TABLE IF EXISTS test_db.target_table;",
+		"DROP TABLE IF EXISTS test_db.input_table;",
+	}, "\n") + "\n"
+	if statements != expectedStatements {
+		t.Errorf("unexpected drop statements:\n%s\nwant:\n%s", statements, expectedStatements)
+	}
+}
+
+func TestDiffTablesFromDdlFiles(t *testing.T) {
+	options := inputOptions{
+		oldTableInfoProvider: &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db.sql")}},
+		tableInfoProvider:    &ddl.Files{Paths: []string{filepath.Join("main_test_data", "test-db-v2.sql")}},
+		outputFormat:         Text,
+	}
+	text, err := diffTables(options)
+	if err != nil {
+		t.Fatalf("failed to diff tables: %s", err)
+	}
+	expectedLines := []string{
+		"Tables: 1 added, 1 removed, 1 changed",
+		"Links: 1 added, 4 removed, 0 changed",
+		"- test_db.join_target_mv (MaterializedView)",
+		"~ test_db.target_table (MergeTree): engine changed from ReplacingMergeTree",
+		"+ test_db.target_table_dist (Distributed)",
+		"- test_db.input_table -> test_db.join_target_mv (MVTrigger)",
+		"+ test_db.target_table -> test_db.target_table_dist (DistributedLocal)",
+	}
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(text, expectedLine) {
+			t.Errorf("expected '%s' not found in diff:\n%s", expectedLine, text)
+		}
+	}
+
+	options.outputFormat = MermaidMarkdown
+	mermaid, err := diffTables(options)
+	if err != nil {
+		t.Fatalf("failed to diff tables: %s", err)
+	}
+	expectedLines = []string{
+		nodeId(t, "test_db.join_target_mv") + "@{ shape: hex, label: \"test_db.join_target_mv (MaterializedView)\" }\n",
+		nodeId(t, "test_db.join_target_mv") + " -->|target| " + nodeId(t, "test_db.join_target") + "\n",
+		"style " + nodeId(t, "test_db.join_target_mv") + " stroke:#ff5757",
+		"style " + nodeId(t, "test_db.target_table_dist") + " stroke:#2ea043",
+		"style " + nodeId(t, "test_db.target_table") + " stroke:#f4a622",
+	}
+	for _, expectedLine := range expectedLines {
+		if !strings.Contains(mermaid, expectedLine) {
+			t.Errorf("expected '%s' not found in mermaid result:\n%s", expectedLine, mermaid)
+		}
+	}
+
+	options.outputFormat = JSON
+	jsonText, err := diffTables(options)
+	if err != nil {
+		t.Fatalf("failed to diff tables: %s", err)
+	}
+	var summary diffJSON
+	if err := json.Unmarshal([]byte(jsonText), &summary); err != nil {
+		t.Fatalf("failed to parse diff: %s\n%s", err, jsonText)
+	}
+	if len(summary.Tables) != 3 || len(summary.Links) != 5 || summary.Links[0].From != "test_db.base_1" || summary.Links[0].Change != "Removed" {
+		t.Errorf("unexpected diff summary: %+v", summary)
+	}
+}
+
+// nodeId returns the mermaid node identifier of the table in format <database>.<table>.
+func nodeId(t *testing.T, name string) string {
+	tableKey, err := parseTableKey(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mermaid.NodeId(tableKey)
+}
+
+// dotNodeId returns the DOT node identifier of the table with the specified full name.
+func dotNodeId(t *testing.T, name string) string {
+	tableKey, err := parseTableKey(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dot.NodeId(tableKey)
+}
+
+// cytoscapeNodeId returns the Cytoscape.js node identifier of the table with the specified full name.
+func cytoscapeNodeId(t *testing.T, name string) string {
+	tableKey, err := parseTableKey(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cytoscape.NodeId(tableKey)
+}
+
+// parseTestGrouping returns the grouping of the tables into mermaid subgraphs.
+func parseTestGrouping(t *testing.T, value string) mermaid.Grouping {
+	grouping, err := parseGrouping(value)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return grouping
+}
@@ -0,0 +1,23 @@
+CREATE DATABASE IF NOT EXISTS cycle_db;
+
+CREATE TABLE IF NOT EXISTS cycle_db.events
+(
+    id UInt64,
+    value String
+) ENGINE = MergeTree
+ORDER BY id;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS cycle_db.events_mv TO cycle_db.events_copy AS
+SELECT id, value
+FROM cycle_db.events;
+
+CREATE TABLE IF NOT EXISTS cycle_db.events_copy
+(
+    id UInt64,
+    value String
+) ENGINE = MergeTree
+ORDER BY id;
+
+CREATE MATERIALIZED VIEW IF NOT EXISTS cycle_db.events_copy_mv TO cycle_db.events AS
+SELECT id, value
+FROM cycle_db.events_copy;
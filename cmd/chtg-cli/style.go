@@ -0,0 +1,74 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/mbaksheev/clickhouse-table-graph/mermaid"
+)
+
+// shapePattern matches the mermaid shape names, e.g. "h-cyl".
+var shapePattern = regexp.MustCompile(`^[a-z0-9-]+$`)
+
+// colorPattern matches the colors of the nodes, e.g. "#e8f5e9" or "green". Colors are written into the mermaid diagram as is,
+// so punctuation which could break the class definition is not allowed.
+var colorPattern = regexp.MustCompile(`^#?[A-Za-z0-9]+$`)
+
+// styleConfig represents the style configuration file specified with the style-config flag.
+type styleConfig struct {
+	EngineStyles []engineStyleConfig `json:"engine_styles"`
+}
+
+// engineStyleConfig represents the style of the tables with the matching engines in the style configuration file.
+type engineStyleConfig struct {
+	Name        string   `json:"name"`
+	Engines     []string `json:"engines"`
+	EngineRegex string   `json:"engine_regex"`
+	Shape       string   `json:"shape"`
+	Fill        string   `json:"fill"`
+	Stroke      string   `json:"stroke"`
+}
+
+// loadEngineStyles reads the engine styles from the JSON style configuration file, e.g.
+//
+//	{"engine_styles": [{"name": "Kafka", "engines": ["Kafka"], "shape": "h-cyl", "fill": "#fbe9e7", "stroke": "#d84315"}]}
+func loadEngineStyles(path string) ([]mermaid.EngineStyle, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("loadEngineStyles: failed to read style config: %s, %w", path, err)
+	}
+	var config styleConfig
+	if err := json.Unmarshal(content, &config); err != nil {
+		return nil, fmt.Errorf("loadEngineStyles: failed to parse style config: %s, %w", path, err)
+	}
+	styles := make([]mermaid.EngineStyle, 0, len(config.EngineStyles))
+	for i, styleConfig := range config.EngineStyles {
+		style := mermaid.EngineStyle{
+			Name:    styleConfig.Name,
+			Engines: styleConfig.Engines,
+			Shape:   styleConfig.Shape,
+			Fill:    styleConfig.Fill,
+			Stroke:  styleConfig.Stroke,
+		}
+		if styleConfig.EngineRegex != "" {
+			if style.EngineRegex, err = regexp.Compile(styleConfig.EngineRegex); err != nil {
+				return nil, fmt.Errorf("loadEngineStyles: invalid engine regex of style %d: %s, %w", i, styleConfig.EngineRegex, err)
+			}
+		}
+		if len(style.Engines) == 0 && style.EngineRegex == nil {
+			return nil, fmt.Errorf("loadEngineStyles: style %d does not specify engines or engine regex", i)
+		}
+		if style.Shape != "" && !shapePattern.MatchString(style.Shape) {
+			return nil, fmt.Errorf("loadEngineStyles: invalid shape of style %d: %s", i, style.Shape)
+		}
+		for _, color := range []string{style.Fill, style.Stroke} {
+			if color != "" && !colorPattern.MatchString(color) {
+				return nil, fmt.Errorf("loadEngineStyles: invalid color of style %d: %s", i, color)
+			}
+		}
+		styles = append(styles, style)
+	}
+	return styles, nil
+}
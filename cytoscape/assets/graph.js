@@ -0,0 +1,149 @@
+// Rendering of the Cytoscape.js elements embedded into the document: the search box finds and centers the table,
+// the tap on the node highlights its upstream and downstream tables and dims everything else.
+// Large graphs are rendered with the texture and without edges during panning and zooming, so they stay responsive.
+var largeGraphSize = 2000;
+
+function layoutOptions(name, large) {
+	if (name === "dagre" && typeof cytoscapeDagre === "undefined" && typeof dagre === "undefined") {
+		name = "breadthfirst";
+	}
+	switch (name) {
+	case "dagre":
+		return {name: "dagre", rankDir: "TB", nodeSep: 30, rankSep: 60, animate: false};
+	case "cose":
+		return {name: "cose", animate: false, randomize: true, nodeRepulsion: 400000, idealEdgeLength: 80, numIter: large ? 500 : 1000};
+	default:
+		return {name: name, directed: true, animate: false};
+	}
+}
+
+var graphStyle = [
+	{
+		selector: "node",
+		style: {
+			"label": "data(label)",
+			"shape": "data(shape)",
+			"width": function (node) {
+				return Math.max(60, node.data("label").length * 7 + 20);
+			},
+			"height": 36,
+			"text-valign": "center",
+			"text-halign": "center",
+			"font-size": "12px",
+			"background-color": "#f5f5f5",
+			"border-width": 1,
+			"border-color": "#616161"
+		}
+	},
+	{selector: "node.missing", style: {"border-style": "dashed", "background-color": "#ffffff", "color": "#757575"}},
+	{selector: "node.highlighted", style: {"border-color": "data(color)", "border-width": 3}},
+	{selector: "node.dashed", style: {"border-style": "dashed"}},
+	{
+		selector: "edge",
+		style: {
+			"width": 1.5,
+			"curve-style": "bezier",
+			"line-color": "#9e9e9e",
+			"target-arrow-color": "#9e9e9e",
+			"target-arrow-shape": "triangle"
+		}
+	},
+	{selector: "edge.dashed", style: {"line-style": "dashed"}},
+	{selector: "edge.target", style: {"label": "target", "font-size": "10px", "text-background-color": "#ffffff", "text-background-opacity": 1}},
+	{selector: "edge.highlighted", style: {"line-color": "data(color)", "target-arrow-color": "data(color)", "width": 3}},
+	{selector: ".dimmed", style: {"opacity": 0.15}},
+	{selector: "node:selected", style: {"border-width": 4, "border-color": "#1565c0"}}
+];
+
+window.addEventListener("load", function () {
+	var elements = JSON.parse(document.getElementById("elements").textContent) || [];
+	var container = document.getElementById("graph");
+	var large = elements.length > largeGraphSize;
+	var cy = cytoscape({
+		container: container,
+		elements: elements,
+		style: graphStyle,
+		layout: layoutOptions(container.dataset.layout, large),
+		textureOnViewport: large,
+		hideEdgesOnViewport: large,
+		pixelRatio: large ? 1 : "auto",
+		minZoom: 0.02,
+		maxZoom: 4
+	});
+
+	function focusNode(node) {
+		var chain = node.predecessors().union(node.successors()).union(node);
+		cy.batch(function () {
+			cy.elements().addClass("dimmed");
+			chain.removeClass("dimmed");
+		});
+		cy.elements(":selected").unselect();
+		node.select();
+		document.getElementById("selected-table").textContent = node.data("label");
+	}
+
+	function clearSelection() {
+		cy.batch(function () {
+			cy.elements().removeClass("dimmed");
+		});
+		cy.elements(":selected").unselect();
+		document.getElementById("selected-table").textContent = "";
+	}
+
+	function searchTable(text) {
+		var query = text.trim().toLowerCase();
+		if (query === "") {
+			return;
+		}
+		var nodes = cy.nodes().sort(function (a, b) {
+			return a.data("name") < b.data("name") ? -1 : 1;
+		});
+		var found = nodes.filter(function (node) {
+			return node.data("name").toLowerCase() === query;
+		});
+		if (found.empty()) {
+			found = nodes.filter(function (node) {
+				return node.data("name").toLowerCase().indexOf(query) >= 0;
+			});
+		}
+		if (found.empty()) {
+			return;
+		}
+		var node = found.first();
+		focusNode(node);
+		cy.animate({center: {eles: node}, zoom: Math.max(cy.zoom(), 1)}, {duration: 300});
+	}
+
+	var names = document.getElementById("table-names");
+	cy.nodes().forEach(function (node) {
+		var option = document.createElement("option");
+		option.value = node.data("name");
+		names.appendChild(option);
+	});
+	cy.on("tap", "node", function (event) {
+		focusNode(event.target);
+	});
+	cy.on("tap", function (event) {
+		if (event.target === cy) {
+			clearSelection();
+		}
+	});
+	var search = document.getElementById("table-search");
+	search.addEventListener("keydown", function (event) {
+		if (event.key === "Enter") {
+			searchTable(search.value);
+		}
+	});
+	search.addEventListener("change", function () {
+		searchTable(search.value);
+	});
+	document.getElementById("clear-selection").addEventListener("click", clearSelection);
+	document.getElementById("fit-graph").addEventListener("click", function () {
+		cy.fit();
+	});
+	document.addEventListener("keydown", function (event) {
+		if (event.key === "Escape") {
+			clearSelection();
+		}
+	});
+});
@@ -0,0 +1,178 @@
+// Package cytoscape provides functionality to render the graph with Cytoscape.js, which stays usable with thousands of tables.
+//
+// Use [Elements] function to generate Cytoscape.js elements from the specified [graph.Links].
+// The elements are marshalled with encoding/json into the elements JSON of Cytoscape.js.
+//
+//	elements := cytoscape.Elements(*tableLinks, cytoscape.ElementsOptions{IncludeEngine: true})
+//	elementsJson, err := json.Marshal(elements)
+//
+// Use [Html] function to generate a standalone HTML document which renders the elements with the dagre or force-directed layout.
+//
+//	html, err := cytoscape.Html(elements, cytoscape.HtmlOptions{Layout: cytoscape.Dagre})
+package cytoscape
+
+import (
+	"strings"
+
+	"github.com/mbaksheev/clickhouse-table-graph/graph"
+	"github.com/mbaksheev/clickhouse-table-graph/internal/engine"
+	"github.com/mbaksheev/clickhouse-table-graph/internal/nodeid"
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+// ElementsOptions represents the options for the Cytoscape.js elements.
+type ElementsOptions struct {
+	// IncludeEngine is a flag to include the engine information in the node label. When true, the engine information is included.
+	IncludeEngine bool
+	// InitialTableHighlightColor is the color of the node border for the initial tables.
+	// E.g. "#ff8585", "red". If not specified, the node is not highlighted.
+	InitialTableHighlightColor string
+	// CycleHighlightColor is the color of the links and the node borders of the tables which form cycles, e.g. loops of materialized views.
+	// E.g. "#ff5757", "red". If not specified, the cycles are not highlighted.
+	CycleHighlightColor string
+	// LinkHighlights is a list of groups of links to highlight with the specified colors, e.g. links of the path between two tables.
+	LinkHighlights []graph.LinkHighlight
+	// TableHighlights is a list of groups of tables to highlight with the specified colors, e.g. tables affected by dropping a table.
+	TableHighlights []graph.TableHighlight
+}
+
+// Element represents the node of the table or the edge of the link in the Cytoscape.js elements JSON.
+// See https://js.cytoscape.org/#notation/elements-json
+type Element struct {
+	// Group is "nodes" for tables and "edges" for links.
+	Group string `json:"group"`
+	// Data is the data of the element.
+	Data ElementData `json:"data"`
+	// Classes is a space-separated list of the classes of the element used by the style of the HTML document,
+	// e.g. "missing" for tables which do not exist, "dashed" for JOIN reads and dictionary lookups, "highlighted" for highlighted elements.
+	Classes string `json:"classes,omitempty"`
+}
+
+// ElementData represents the data of the [Element].
+type ElementData struct {
+	// Id is the identifier of the element: the identifier returned by [NodeId] for nodes,
+	// and the identifiers of the nodes and the link kind for edges.
+	Id string `json:"id"`
+	// Name is the full name of the table of the node, which is used by the search of the HTML document.
+	Name string `json:"name,omitempty"`
+	// Label is the label of the node.
+	Label string `json:"label,omitempty"`
+	// Database is the database of the table of the node.
+	Database string `json:"database,omitempty"`
+	// Engine is the engine of the table of the node. It is empty if the table does not exist.
+	Engine string `json:"engine,omitempty"`
+	// Shape is the Cytoscape.js shape of the node depending on the table engine, e.g. "hexagon" for materialized views.
+	Shape string `json:"shape,omitempty"`
+	// Source is the identifier of the node the edge starts from.
+	Source string `json:"source,omitempty"`
+	// Target is the identifier of the node the edge leads to.
+	Target string `json:"target,omitempty"`
+	// Kind is the kind of the link of the edge, e.g. "MVTarget".
+	Kind string `json:"kind,omitempty"`
+	// Color is the highlight color of the node border or the edge. It is empty if the element is not highlighted.
+	Color string `json:"color,omitempty"`
+}
+
+// Elements generates the Cytoscape.js elements from the specified [graph.Links]:
+// nodes of all tables ordered by database and table name, and then edges of the links in the order of the graph links.
+// Node identifiers are returned by [NodeId], the table names are rendered only in the node labels.
+func Elements(graphLinks graph.Links, options ElementsOptions) []Element {
+	highlights := graphLinks.Highlights(graph.HighlightOptions{
+		InitialTableHighlightColor: options.InitialTableHighlightColor,
+		CycleHighlightColor:        options.CycleHighlightColor,
+		LinkHighlights:             options.LinkHighlights,
+		TableHighlights:            options.TableHighlights,
+	})
+	nodes := graphLinks.SortedNodes()
+	elements := make([]Element, 0, len(nodes)+len(graphLinks.Links))
+	for _, tableKey := range nodes {
+		elements = append(elements, nodeOf(graphLinks, tableKey, highlights.Table(tableKey), options))
+	}
+	for _, link := range graphLinks.Links {
+		elements = append(elements, edgeOf(link, highlights.Link(link)))
+	}
+	return elements
+}
+
+// NodeId returns the id of the Cytoscape.js node of the table, which is also the source or the target of the edges of its links.
+// Use it to find the node in the document, e.g. with cy.getElementById. The full table name is in the name field of the node data.
+func NodeId(tableKey table.Key) string {
+	return nodeid.Table(tableKey)
+}
+
+// nodeOf returns the node of the table with its shape, label and highlighting.
+// The node of the table which does not exist gets the "missing" class.
+func nodeOf(graphLinks graph.Links, tableKey table.Key, style graph.TableStyle, options ElementsOptions) Element {
+	classes := make([]string, 0, 3)
+	data := ElementData{Id: NodeId(tableKey), Name: tableKey.String(), Database: tableKey.Database}
+	tableInfo, exists := graphLinks.TableInfo(tableKey)
+	if !exists {
+		data.Label = tableKey.String() + " (table does not exist)"
+		data.Shape = "rectangle"
+		classes = append(classes, "missing")
+	} else {
+		data.Label = tableKey.String()
+		if options.IncludeEngine {
+			data.Label += " (" + tableInfo.Engine + ")"
+		}
+		data.Engine = tableInfo.Engine
+		data.Shape = shapeOf(tableInfo)
+	}
+	if style.Color != "" {
+		data.Color = style.Color
+		classes = append(classes, "highlighted")
+		if style.Dashed {
+			classes = append(classes, "dashed")
+		}
+	}
+	return Element{Group: "nodes", Data: data, Classes: strings.Join(classes, " ")}
+}
+
+// familyShapes are the Cytoscape.js shapes of the engine families.
+var familyShapes = map[engine.Family]string{
+	engine.MaterializedView: "hexagon",
+	engine.View:             "ellipse",
+	engine.Distributed:      "barrel",
+	engine.Null:             "round-rectangle",
+	engine.Dictionary:       "cut-rectangle",
+	engine.Queue:            "tag",
+	engine.Buffer:           "rhomboid",
+	engine.JoinSet:          "octagon",
+	engine.MemoryLog:        "bottom-round-rectangle",
+	engine.External:         "pentagon",
+}
+
+// shapeOf returns the Cytoscape.js shape of the table node depending on the engine family.
+// Tables of the MergeTree families and of the other engines are rectangles.
+func shapeOf(tableInfo table.Info) string {
+	if shape, exists := familyShapes[engine.FamilyOf(tableInfo.Engine)]; exists {
+		return shape
+	}
+	return "rectangle"
+}
+
+// edgeOf returns the edge of the link with the classes depending on the link kind:
+// "dashed" for JOIN reads, dictionary lookups and dictionary sources, "target" for materialized view targets.
+func edgeOf(link graph.Link, color string) Element {
+	classes := make([]string, 0, 2)
+	switch link.Kind {
+	case graph.MVTarget:
+		classes = append(classes, "target")
+	case graph.JoinRead, graph.DictionaryLookup, graph.DictionarySource:
+		classes = append(classes, "dashed")
+	}
+	if color != "" {
+		classes = append(classes, "highlighted")
+	}
+	return Element{
+		Group: "edges",
+		Data: ElementData{
+			Id:     NodeId(link.FromTableKey) + "-" + NodeId(link.ToTableKey) + "-" + link.Kind.String(),
+			Source: NodeId(link.FromTableKey),
+			Target: NodeId(link.ToTableKey),
+			Kind:   link.Kind.String(),
+			Color:  color,
+		},
+		Classes: strings.Join(classes, " "),
+	}
+}
@@ -0,0 +1,200 @@
+package cytoscape
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/mbaksheev/clickhouse-table-graph/graph"
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+func TestElements(t *testing.T) {
+	b := graph.New()
+	b.AddTable(table.Info{Key: table.Key{Database: "raw", Name: "events"}, Engine: "Null"})
+	b.AddTable(table.Info{
+		Key:              table.Key{Database: "stats", Name: "events_mv"},
+		Engine:           "MaterializedView",
+		CreateTableQuery: "CREATE MATERIALIZED VIEW stats.events_mv TO stats.daily AS SELECT * FROM raw.events JOIN stats.users USING id",
+		AsSelect:         "SELECT * FROM raw.events JOIN stats.users USING id",
+	})
+	b.AddTable(table.Info{Key: table.Key{Database: "stats", Name: "daily"}, Engine: "MergeTree"})
+	tableLinks, err := b.TableLinks(table.Key{Database: "raw", Name: "events"})
+	if err != nil {
+		t.Fatalf("TableLinks() error = %v", err)
+	}
+
+	got := Elements(*tableLinks, ElementsOptions{IncludeEngine: true, InitialTableHighlightColor: "red"})
+	events, daily := nodeIdOf("raw", "events"), nodeIdOf("stats", "daily")
+	mv, users := nodeIdOf("stats", "events_mv"), nodeIdOf("stats", "users")
+	want := []Element{
+		{
+			Group: "nodes",
+			Data: ElementData{
+				Id: events, Name: "raw.events", Label: "raw.events (Null)", Database: "raw", Engine: "Null", Shape: "round-rectangle", Color: "red",
+			},
+			Classes: "highlighted",
+		},
+		{
+			Group: "nodes",
+			Data:  ElementData{Id: daily, Name: "stats.daily", Label: "stats.daily (MergeTree)", Database: "stats", Engine: "MergeTree", Shape: "rectangle"},
+		},
+		{
+			Group: "nodes",
+			Data: ElementData{
+				Id: mv, Name: "stats.events_mv", Label: "stats.events_mv (MaterializedView)", Database: "stats", Engine: "MaterializedView", Shape: "hexagon",
+			},
+		},
+		{
+			Group:   "nodes",
+			Data:    ElementData{Id: users, Name: "stats.users", Label: "stats.users (table does not exist)", Database: "stats", Shape: "rectangle"},
+			Classes: "missing",
+		},
+	}
+	if !reflect.DeepEqual(got[:len(want)], want) {
+		t.Errorf("Elements() nodes = %+v, want %+v", got[:len(want)], want)
+	}
+	edges := make(map[string]Element)
+	for _, element := range got[len(want):] {
+		edges[element.Data.Id] = element
+	}
+	wantEdges := map[string]Element{
+		events + "-" + mv + "-MVTrigger": {
+			Group: "edges",
+			Data:  ElementData{Id: events + "-" + mv + "-MVTrigger", Source: events, Target: mv, Kind: "MVTrigger"},
+		},
+		mv + "-" + daily + "-MVTarget": {
+			Group:   "edges",
+			Data:    ElementData{Id: mv + "-" + daily + "-MVTarget", Source: mv, Target: daily, Kind: "MVTarget"},
+			Classes: "target",
+		},
+		users + "-" + mv + "-JoinRead": {
+			Group:   "edges",
+			Data:    ElementData{Id: users + "-" + mv + "-JoinRead", Source: users, Target: mv, Kind: "JoinRead"},
+			Classes: "dashed",
+		},
+	}
+	if !reflect.DeepEqual(edges, wantEdges) {
+		t.Errorf("Elements() edges = %+v, want %+v", edges, wantEdges)
+	}
+
+	elementsJson, err := json.Marshal(got[:1])
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	wantJson := `[{"group":"nodes","data":{"id":"` + events + `","name":"raw.events","label":"raw.events (Null)","database":"raw","engine":"Null","shape":"round-rectangle","color":"red"},"classes":"highlighted"}]`
+	if string(elementsJson) != wantJson {
+		t.Errorf("json.Marshal() = %s, want %s", elementsJson, wantJson)
+	}
+}
+
+func TestElementsHighlights(t *testing.T) {
+	a := table.Key{Database: "db", Name: "a"}
+	b := table.Key{Database: "db", Name: "b"}
+	builder := graph.New()
+	builder.AddTable(table.Info{Key: a, Engine: "Distributed", EngineFull: "Distributed('cluster', 'db', 'b')"})
+	builder.AddTable(table.Info{Key: b, Engine: "Distributed", EngineFull: "Distributed('cluster', 'db', 'a')"})
+	fullGraph := builder.FullGraph()
+	aId, bId := NodeId(a), NodeId(b)
+	ab, ba := aId+"-"+bId+"-DistributedLocal", bId+"-"+aId+"-DistributedLocal"
+
+	tests := []struct {
+		name    string
+		options ElementsOptions
+		want    map[string][2]string
+	}{
+		{
+			name:    "cycle",
+			options: ElementsOptions{CycleHighlightColor: "#ff5757"},
+			want: map[string][2]string{
+				aId: {"#ff5757", "highlighted dashed"},
+				bId: {"#ff5757", "highlighted dashed"},
+				ab:  {"#ff5757", "highlighted"},
+				ba:  {"#ff5757", "highlighted"},
+			},
+		},
+		{
+			name: "table and link highlights win over cycle",
+			options: ElementsOptions{
+				CycleHighlightColor: "#ff5757",
+				LinkHighlights:      []graph.LinkHighlight{{Links: []graph.Link{{FromTableKey: b, ToTableKey: a}}, Color: "green"}},
+				TableHighlights:     []graph.TableHighlight{{Tables: []table.Key{b}, Color: "orange"}},
+			},
+			want: map[string][2]string{
+				aId: {"#ff5757", "highlighted dashed"},
+				bId: {"orange", "highlighted"},
+				ab:  {"#ff5757", "highlighted"},
+				ba:  {"green", "highlighted"},
+			},
+		},
+		{
+			name:    "no highlights",
+			options: ElementsOptions{},
+			want: map[string][2]string{
+				aId: {"", ""},
+				bId: {"", ""},
+				ab:  {"", ""},
+				ba:  {"", ""},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make(map[string][2]string)
+			for _, element := range Elements(*fullGraph, tt.options) {
+				got[element.Data.Id] = [2]string{element.Data.Color, element.Classes}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Elements() highlights = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestShapeOf(t *testing.T) {
+	tests := []struct {
+		engine string
+		want   string
+	}{
+		{engine: "MaterializedView", want: "hexagon"},
+		{engine: "Distributed", want: "barrel"},
+		{engine: "Null", want: "round-rectangle"},
+		{engine: "Dictionary", want: "cut-rectangle"},
+		{engine: "SharedMergeTree", want: "rectangle"},
+		{engine: "RabbitMQ", want: "tag"},
+		{engine: "MySQL", want: "pentagon"},
+		{engine: "GenerateRandom", want: "rectangle"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.engine, func(t *testing.T) {
+			if got := shapeOf(table.Info{Engine: tt.engine}); got != tt.want {
+				t.Errorf("shapeOf(%s) = %s, want %s", tt.engine, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestElementsDottedNames(t *testing.T) {
+	first := table.Key{Database: "x.a", Name: "b"}
+	second := table.Key{Database: "x", Name: "a.b"}
+	builder := graph.New()
+	builder.AddTable(table.Info{Key: first, Engine: "MergeTree"})
+	builder.AddTable(table.Info{Key: second, Engine: "Null"})
+
+	got := Elements(*builder.FullGraph(), ElementsOptions{})
+	want := []Element{
+		{Group: "nodes", Data: ElementData{Id: NodeId(second), Name: "x.a.b", Label: "x.a.b", Database: "x", Engine: "Null", Shape: "round-rectangle"}},
+		{Group: "nodes", Data: ElementData{Id: NodeId(first), Name: "x.a.b", Label: "x.a.b", Database: "x.a", Engine: "MergeTree", Shape: "rectangle"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Elements() = %+v, want %+v", got, want)
+	}
+	if NodeId(first) == NodeId(second) {
+		t.Errorf("NodeId() = %s for both %s and %s", NodeId(first), first, second)
+	}
+}
+
+// nodeIdOf returns the node identifier of the table with the specified database and name.
+func nodeIdOf(database, name string) string {
+	return NodeId(table.Key{Database: database, Name: name})
+}
@@ -0,0 +1,111 @@
+package cytoscape
+
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"strings"
+
+	"github.com/mbaksheev/clickhouse-table-graph/internal/htmlscript"
+)
+
+// assets contains the template of the HTML document and the script which renders the elements.
+//
+//go:embed assets
+var assets embed.FS
+
+// htmlTemplate is the template of the HTML document. The title and the elements are escaped by the template.
+var htmlTemplate = template.Must(template.ParseFS(assets, "assets/graph.html"))
+
+const defaultCytoscapeJsUrl = "https://cdn.jsdelivr.net/npm/cytoscape@3/dist/cytoscape.min.js"
+const defaultDagreJsUrl = "https://cdn.jsdelivr.net/npm/dagre@0.8.5/dist/dagre.min.js"
+const defaultCytoscapeDagreJsUrl = "https://cdn.jsdelivr.net/npm/cytoscape-dagre@2.5.0/cytoscape-dagre.js"
+const defaultTitle = "ClickHouse table dependencies graph"
+
+// Layout represents the layout of the graph in the HTML document.
+type Layout int
+
+// Possible values for the [Layout] type.
+const (
+	// Dagre is a layered layout of the directed graph, links go from top to bottom. It requires the dagre extension of Cytoscape.js.
+	Dagre Layout = iota
+	// Force is a force-directed layout, the built-in cose layout of Cytoscape.js.
+	Force
+)
+
+// name returns the name of the Cytoscape.js layout. Unknown values fall back to the [Dagre] layout.
+func (l Layout) name() string {
+	names := [...]string{"dagre", "cose"}
+	if l < 0 || int(l) >= len(names) {
+		return names[Dagre]
+	}
+	return names[l]
+}
+
+// HtmlOptions represents options for the Html function.
+type HtmlOptions struct {
+	// Title is the title of the HTML document.
+	Title string
+
+	// Layout is the layout of the graph. The default layout is [Dagre].
+	Layout Layout
+
+	// CytoscapeJsUrl is the URL of the Cytoscape.js library. Optional.
+	// The URLs must be absolute http or https URLs, or relative URLs without the scheme.
+	CytoscapeJsUrl string
+
+	// DagreJsUrl is the URL of the dagre library used by the [Dagre] layout. Optional.
+	DagreJsUrl string
+
+	// CytoscapeDagreJsUrl is the URL of the dagre extension of Cytoscape.js used by the [Dagre] layout. Optional.
+	CytoscapeDagreJsUrl string
+}
+
+// htmlData is the data of the HTML document template.
+type htmlData struct {
+	Title      string
+	Layout     string
+	ScriptUrls []string
+	GraphJs    template.JS
+	Elements   []Element
+}
+
+// Html generates a full HTML document which renders the Cytoscape.js elements with the specified layout.
+// The elements are embedded into the document as JSON, the libraries are loaded from the URLs of the options.
+// The document provides the search box which finds and centers the table, and highlights the upstream and downstream tables
+// of the clicked table. The title and the elements are escaped, so table names with HTML markup are rendered as text.
+func Html(elements []Element, options HtmlOptions) (string, error) {
+	data := htmlData{
+		Title:    options.Title,
+		Layout:   options.Layout.name(),
+		Elements: elements,
+	}
+	if data.Title == "" {
+		data.Title = defaultTitle
+	}
+	scriptUrls := [][2]string{{options.CytoscapeJsUrl, defaultCytoscapeJsUrl}}
+	if options.Layout.name() == Dagre.name() {
+		scriptUrls = append(scriptUrls, [2]string{options.DagreJsUrl, defaultDagreJsUrl}, [2]string{options.CytoscapeDagreJsUrl, defaultCytoscapeDagreJsUrl})
+	}
+	for _, scriptUrl := range scriptUrls {
+		if scriptUrl[0] == "" {
+			data.ScriptUrls = append(data.ScriptUrls, scriptUrl[1])
+			continue
+		}
+		if err := htmlscript.ValidateUrl(scriptUrl[0]); err != nil {
+			return "", fmt.Errorf("Html: %w", err)
+		}
+		data.ScriptUrls = append(data.ScriptUrls, scriptUrl[0])
+	}
+	graphJs, err := assets.ReadFile("assets/graph.js")
+	if err != nil {
+		return "", fmt.Errorf("Html: failed to read graph script: %w", err)
+	}
+	data.GraphJs = htmlscript.Content(graphJs)
+
+	var html strings.Builder
+	if err := htmlTemplate.Execute(&html, data); err != nil {
+		return "", fmt.Errorf("Html: failed to render HTML document: %w", err)
+	}
+	return html.String(), nil
+}
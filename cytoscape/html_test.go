@@ -0,0 +1,121 @@
+package cytoscape
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mbaksheev/clickhouse-table-graph/graph"
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+func TestHtml(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     HtmlOptions
+		wantScripts []string
+		wantLayout  string
+	}{
+		{
+			name:        "dagre",
+			options:     HtmlOptions{Title: "test graph"},
+			wantScripts: []string{defaultCytoscapeJsUrl, defaultDagreJsUrl, defaultCytoscapeDagreJsUrl},
+			wantLayout:  "dagre",
+		},
+		{
+			name:        "force",
+			options:     HtmlOptions{Title: "test graph", Layout: Force, CytoscapeJsUrl: "js/cytoscape.min.js"},
+			wantScripts: []string{"js/cytoscape.min.js"},
+			wantLayout:  "cose",
+		},
+		{
+			name:        "unknown layout falls back to dagre",
+			options:     HtmlOptions{Title: "test graph", Layout: Layout(42)},
+			wantScripts: []string{defaultCytoscapeJsUrl, defaultDagreJsUrl, defaultCytoscapeDagreJsUrl},
+			wantLayout:  "dagre",
+		},
+	}
+	b := graph.New()
+	b.AddTable(table.Info{Key: table.Key{Database: "db", Name: "events"}, Engine: "MergeTree"})
+	elements := Elements(*b.FullGraph(), ElementsOptions{})
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := Html(elements, tt.options)
+			if err != nil {
+				t.Fatalf("Html() error = %v", err)
+			}
+			for _, part := range []string{
+				"<title>ClickHouse table graph - test graph</title>",
+				`<div id="graph" data-layout="` + tt.wantLayout + `"></div>`,
+				"function layoutOptions(name, large)",
+			} {
+				if !strings.Contains(html, part) {
+					t.Errorf("Html() does not contain %q:\n%s", part, html)
+				}
+			}
+			if count := strings.Count(html, "<script src="); count != len(tt.wantScripts) {
+				t.Errorf("Html() loads %d scripts, want %d:\n%s", count, len(tt.wantScripts), html)
+			}
+			for _, script := range tt.wantScripts {
+				if !strings.Contains(html, `<script src="`+script+`"></script>`) {
+					t.Errorf("Html() does not load the script %s:\n%s", script, html)
+				}
+			}
+		})
+	}
+}
+
+func TestHtmlHostileNames(t *testing.T) {
+	hostileKey := table.Key{Database: `db"><script>alert(1)</script>`, Name: `t</script><img src=x onerror=alert(2)>`}
+	b := graph.New()
+	b.AddTable(table.Info{Key: hostileKey, Engine: "MergeTree"})
+	elements := Elements(*b.FullGraph(), ElementsOptions{IncludeEngine: true})
+
+	html, err := Html(elements, HtmlOptions{Title: `</title><script>alert(3)</script>`})
+	if err != nil {
+		t.Fatalf("Html() error = %v", err)
+	}
+	for _, injected := range []string{"<script>alert", "<img"} {
+		if strings.Contains(html, injected) {
+			t.Errorf("Html() contains injected markup %q:\n%s", injected, html)
+		}
+	}
+	if count := strings.Count(html, "</title>"); count != 1 {
+		t.Errorf("Html() contains %d closing title tags, want 1", count)
+	}
+	start := strings.Index(html, `<script type="application/json" id="elements">`)
+	if start < 0 {
+		t.Fatalf("Html() does not contain elements:\n%s", html)
+	}
+	content := html[start+len(`<script type="application/json" id="elements">`):]
+	content = content[:strings.Index(content, "</script>")]
+	var got []Element
+	if err := json.Unmarshal([]byte(content), &got); err != nil {
+		t.Fatalf("failed to parse elements: %v\n%s", err, content)
+	}
+	if !reflect.DeepEqual(got, elements) {
+		t.Errorf("embedded elements = %+v, want %+v", got, elements)
+	}
+}
+
+func TestHtmlScriptUrls(t *testing.T) {
+	tests := []struct {
+		name    string
+		options HtmlOptions
+		wantErr bool
+	}{
+		{name: "custom urls", options: HtmlOptions{CytoscapeJsUrl: "https://cdn.example.com/cytoscape.js", DagreJsUrl: "js/dagre.js", CytoscapeDagreJsUrl: "js/cytoscape-dagre.js"}},
+		{name: "invalid cytoscape url", options: HtmlOptions{CytoscapeJsUrl: "javascript:alert(1)"}, wantErr: true},
+		{name: "invalid dagre url", options: HtmlOptions{DagreJsUrl: "data:text/javascript,alert(1)"}, wantErr: true},
+		{name: "dagre url is ignored by force layout", options: HtmlOptions{Layout: Force, DagreJsUrl: "javascript:alert(1)"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Html(nil, tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Html() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+// Package ddl provides a tables information provider which reads ClickHouse DDL statements from .sql files.
+//
+// It allows to build the table graph without running ClickHouse server, e.g. in CI from the schema stored in git repository.
+// Use [*Files.TableInfos] method to get the list of tables from the DDL files:
+//
+//	files := ddl.Files{Paths: []string{"schema/"}}
+//	tables, err := files.TableInfos()
+//
+// CREATE TABLE, CREATE MATERIALIZED VIEW, CREATE VIEW, CREATE DICTIONARY and CREATE DATABASE statements are supported,
+// all other statements are skipped. Table names without database are resolved with the database set by the last USE statement
+// in the same file or with [Files.DefaultDatabase].
+package ddl
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/mbaksheev/clickhouse-table-graph/internal/chsql"
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+const defaultDatabase = "default"
+
+// Files represents a set of .sql files with ClickHouse DDL statements.
+type Files struct {
+	// Paths is a list of .sql files, directories or glob patterns, e.g. "schema/*.sql".
+	// Directories are read recursively and only files with .sql extension are used.
+	Paths []string
+	// DefaultDatabase is the database for table names without database. Optional. Default value is "default".
+	DefaultDatabase string
+}
+
+// String returns a string representation of the DDL files.
+func (f *Files) String() string {
+	return fmt.Sprintf("DDL files [Paths=%s]", strings.Join(f.Paths, ", "))
+}
+
+// TableInfos returns the list of tables defined in the DDL files.
+// The result contains the same information as it is provided by ClickHouse server in the system.tables table,
+// including dependencies of source tables on materialized views.
+func (f *Files) TableInfos() ([]table.Info, error) {
+	fileNames, err := f.fileNames()
+	if err != nil {
+		return nil, err
+	}
+	statements := make([]*chsql.CreateStatement, 0, 100)
+	for _, fileName := range fileNames {
+		content, err := os.ReadFile(fileName)
+		if err != nil {
+			return nil, fmt.Errorf("TableInfos: failed to read file: %s, %w", fileName, err)
+		}
+		fileStatements, err := parse(string(content), f.defaultDatabase())
+		if err != nil {
+			return nil, fmt.Errorf("TableInfos: failed to parse file: %s, %w", fileName, err)
+		}
+		statements = append(statements, fileStatements...)
+	}
+	return tableInfos(statements), nil
+}
+
+// Parse parses the specified DDL statements and returns the list of defined tables.
+// Table names without database are resolved with the database set by the last USE statement or with the specified default database.
+func Parse(ddl string, defaultDatabase string) ([]table.Info, error) {
+	statements, err := parse(ddl, defaultDatabase)
+	if err != nil {
+		return nil, err
+	}
+	return tableInfos(statements), nil
+}
+
+func (f *Files) defaultDatabase() string {
+	if f.DefaultDatabase == "" {
+		return defaultDatabase
+	}
+	return f.DefaultDatabase
+}
+
+// fileNames resolves paths to the sorted list of files without duplicates.
+func (f *Files) fileNames() ([]string, error) {
+	fileNames := make([]string, 0)
+	for _, path := range f.Paths {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("fileNames: invalid path pattern: %s, %w", path, err)
+		}
+		if len(matches) == 0 {
+			return nil, fmt.Errorf("fileNames: no files found: %s", path)
+		}
+		for _, match := range matches {
+			err := filepath.WalkDir(match, func(fileName string, entry fs.DirEntry, err error) error {
+				if err != nil {
+					return err
+				}
+				if !entry.IsDir() && (fileName == match || strings.EqualFold(filepath.Ext(fileName), ".sql")) {
+					fileNames = append(fileNames, fileName)
+				}
+				return nil
+			})
+			if err != nil {
+				return nil, fmt.Errorf("fileNames: failed to read path: %s, %w", match, err)
+			}
+		}
+	}
+	slices.Sort(fileNames)
+	return slices.Compact(fileNames), nil
+}
+
+// parse returns all CREATE statements for tables, views and dictionaries from the specified DDL.
+func parse(ddl string, defaultDatabase string) ([]*chsql.CreateStatement, error) {
+	statements := make([]*chsql.CreateStatement, 0)
+	currentDatabase := defaultDatabase
+	for _, tokens := range chsql.SplitStatements(chsql.Tokenize(ddl)) {
+		if tokens[0].IsKeyword("USE") && len(tokens) == 2 && tokens[1].IsIdent() {
+			currentDatabase = tokens[1].Value
+			continue
+		}
+		statement, err := chsql.ParseCreate(tokens, currentDatabase)
+		if err != nil {
+			return nil, fmt.Errorf("parse: line %d: %w", lineOf(ddl, tokens[0].Pos), err)
+		}
+		switch statement.Kind {
+		case chsql.TableObject, chsql.MaterializedViewObject, chsql.ViewObject, chsql.DictionaryObject:
+			statements = append(statements, statement)
+		}
+	}
+	return statements, nil
+}
+
+// tableInfos converts the statements to tables information.
+// Materialized views are added to dependencies of their source tables, the same way as ClickHouse does it.
+func tableInfos(statements []*chsql.CreateStatement) []table.Info {
+	tables := make([]table.Info, 0, len(statements))
+	definitions := make([]*chsql.CreateStatement, 0, len(statements))
+	indexes := make(map[table.Key]int, len(statements))
+	for _, statement := range statements {
+		key := table.Key{Database: statement.Name.Database, Name: statement.Name.Name}
+		info := table.Info{
+			Key:              key,
+			Engine:           statement.Engine,
+			EngineFull:       statement.EngineFull,
+			CreateTableQuery: statement.Query,
+			AsSelect:         statement.AsSelect,
+		}
+		// the last definition wins if the table is defined several times, e.g. with CREATE OR REPLACE
+		if index, exists := indexes[key]; exists {
+			tables[index] = info
+			definitions[index] = statement
+		} else {
+			indexes[key] = len(tables)
+			tables = append(tables, info)
+			definitions = append(definitions, statement)
+		}
+	}
+	// only the last definitions of the materialized views are the dependencies of their source tables
+	for _, statement := range definitions {
+		if statement.Kind != chsql.MaterializedViewObject || statement.From == (chsql.Name{}) {
+			continue
+		}
+		source, exists := indexes[table.Key{Database: statement.From.Database, Name: statement.From.Name}]
+		if !exists || hasDependency(tables[source], statement.Name) {
+			continue
+		}
+		tables[source].DependenciesDatabase = append(tables[source].DependenciesDatabase, statement.Name.Database)
+		tables[source].DependenciesTable = append(tables[source].DependenciesTable, statement.Name.Name)
+	}
+	return tables
+}
+
+// hasDependency returns true if the specified table is already in the dependencies of the table.
+func hasDependency(tableInfo table.Info, name chsql.Name) bool {
+	for i := range tableInfo.DependenciesTable {
+		if tableInfo.DependenciesDatabase[i] == name.Database && tableInfo.DependenciesTable[i] == name.Name {
+			return true
+		}
+	}
+	return false
+}
+
+func lineOf(text string, pos int) int {
+	return strings.Count(text[:pos], "\n") + 1
+}
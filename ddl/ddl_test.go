@@ -0,0 +1,220 @@
+package ddl
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		ddl  string
+		want []table.Info
+	}{
+		{
+			name: "table with engine and comment",
+			ddl: `CREATE TABLE IF NOT EXISTS db.events ON CLUSTER main
+(
+    id   Int64, -- event id
+    name String
+) ENGINE = ReplacingMergeTree()
+      ORDER BY (id)
+      COMMENT 'events table';`,
+			want: []table.Info{
+				{
+					Key:              table.Key{Database: "db", Name: "events"},
+					Engine:           "ReplacingMergeTree",
+					EngineFull:       "ReplacingMergeTree() ORDER BY (id)",
+					CreateTableQuery: "CREATE TABLE db.events (id Int64, name String) ENGINE = ReplacingMergeTree() ORDER BY (id) COMMENT 'events table'",
+				},
+			},
+		},
+		{
+			name: "distributed table with default database",
+			ddl:  "create table events_distributed as events engine = Distributed('cluster','db','events',rand())",
+			want: []table.Info{
+				{
+					Key:              table.Key{Database: "default", Name: "events_distributed"},
+					Engine:           "Distributed",
+					EngineFull:       "Distributed('cluster', 'db', 'events', rand())",
+					CreateTableQuery: "create table default.events_distributed as events engine = Distributed('cluster', 'db', 'events', rand())",
+				},
+			},
+		},
+		{
+			name: "materialized view with source table and USE statement",
+			ddl: `USE db;
+CREATE TABLE input (id Int64) ENGINE = Null;
+/* target table */
+CREATE TABLE target (id Int64) ENGINE = MergeTree ORDER BY id;
+CREATE MATERIALIZED VIEW target_mv
+TO target
+AS SELECT id FROM input JOIN other.joined USING (id) ARRAY JOIN arr WHERE extract(DAY FROM date) = 1;`,
+			want: []table.Info{
+				{
+					Key:                  table.Key{Database: "db", Name: "input"},
+					Engine:               "Null",
+					EngineFull:           "Null",
+					CreateTableQuery:     "CREATE TABLE db.input (id Int64) ENGINE = Null",
+					DependenciesDatabase: []string{"db"},
+					DependenciesTable:    []string{"target_mv"},
+				},
+				{
+					Key:              table.Key{Database: "db", Name: "target"},
+					Engine:           "MergeTree",
+					EngineFull:       "MergeTree ORDER BY id",
+					CreateTableQuery: "CREATE TABLE db.target (id Int64) ENGINE = MergeTree ORDER BY id",
+				},
+				{
+					Key:              table.Key{Database: "db", Name: "target_mv"},
+					Engine:           "MaterializedView",
+					CreateTableQuery: "CREATE MATERIALIZED VIEW db.target_mv TO db.target AS SELECT id FROM db.input JOIN other.joined USING (id) ARRAY JOIN arr WHERE extract(DAY FROM date) = 1",
+					AsSelect:         "SELECT id FROM db.input JOIN other.joined USING (id) ARRAY JOIN arr WHERE extract(DAY FROM date) = 1",
+				},
+			},
+		},
+		{
+			name: "view with common table expression and table function",
+			ddl:  "CREATE OR REPLACE VIEW `db`.`my view` AS WITH cte AS (SELECT * FROM src) SELECT * FROM cte, numbers(10)",
+			want: []table.Info{
+				{
+					Key:              table.Key{Database: "db", Name: "my view"},
+					Engine:           "View",
+					CreateTableQuery: "CREATE VIEW `db`.`my view` AS WITH cte AS (SELECT * FROM default.src) SELECT * FROM cte, numbers(10)",
+					AsSelect:         "WITH cte AS (SELECT * FROM default.src) SELECT * FROM cte, numbers(10)",
+				},
+			},
+		},
+		{
+			name: "dictionary, database and other statements",
+			ddl: `CREATE DATABASE IF NOT EXISTS dict_db ENGINE = Atomic;
+CREATE DICTIONARY dict_db.dict (id UInt64, value String) PRIMARY KEY id SOURCE(NULL()) LAYOUT(FLAT()) LIFETIME(0);
+INSERT INTO dict_db.source VALUES (1, 'a');
+CREATE USER reader;`,
+			want: []table.Info{
+				{
+					Key:              table.Key{Database: "dict_db", Name: "dict"},
+					Engine:           "Dictionary",
+					CreateTableQuery: "CREATE DICTIONARY dict_db.dict (id UInt64, value String) PRIMARY KEY id SOURCE(NULL()) LAYOUT(FLAT()) LIFETIME(0)",
+				},
+			},
+		},
+		{
+			name: "empty string",
+			ddl:  "",
+			want: []table.Info{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.ddl, "default")
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Parse() returned %d tables, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if !equal(got[i], tt.want[i]) {
+					t.Errorf("Parse() =\n %#v, \nWant =\n %#v", got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestParseError(t *testing.T) {
+	_, err := Parse("CREATE TABLE db.t (id Int64) ENGINE = Null;\nCREATE TABLE (id Int64)", "default")
+	if err == nil {
+		t.Errorf("Parse() expected error for the statement without table name")
+	}
+}
+
+func TestParseReplacedMaterializedView(t *testing.T) {
+	tables := `CREATE TABLE db.a (id Int64) ENGINE = Null;
+CREATE TABLE db.b (id Int64) ENGINE = Null;
+CREATE TABLE db.t (id Int64) ENGINE = MergeTree ORDER BY id;
+`
+	tests := []struct {
+		name     string
+		ddl      string
+		wantDeps map[string][]string
+	}{
+		{
+			name: "replaced with other source",
+			ddl: tables + `CREATE MATERIALIZED VIEW db.mv TO db.t AS SELECT * FROM db.a;
+CREATE OR REPLACE MATERIALIZED VIEW db.mv TO db.t AS SELECT * FROM db.b;`,
+			wantDeps: map[string][]string{"db.a": nil, "db.b": {"db.mv"}, "db.t": nil},
+		},
+		{
+			name: "replaced with the same source",
+			ddl: tables + `CREATE MATERIALIZED VIEW db.mv TO db.t AS SELECT * FROM db.a;
+CREATE OR REPLACE MATERIALIZED VIEW db.mv TO db.t AS SELECT id FROM db.a;`,
+			wantDeps: map[string][]string{"db.a": {"db.mv"}, "db.b": nil, "db.t": nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.ddl, "default")
+			if err != nil {
+				t.Fatalf("Parse() error = %v", err)
+			}
+			if len(got) != 4 {
+				t.Fatalf("Parse() returned %d tables, want 4", len(got))
+			}
+			for _, tableInfo := range got {
+				want, exists := tt.wantDeps[tableInfo.Key.String()]
+				if !exists {
+					continue
+				}
+				deps := make([]string, 0)
+				for i := range tableInfo.DependenciesTable {
+					deps = append(deps, tableInfo.DependenciesDatabase[i]+"."+tableInfo.DependenciesTable[i])
+				}
+				if !slices.Equal(deps, want) {
+					t.Errorf("dependencies of %s = %v, want %v", tableInfo.Key, deps, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFilesTableInfos(t *testing.T) {
+	tests := []struct {
+		name      string
+		paths     []string
+		wantCount int
+		wantErr   bool
+	}{
+		{name: "directory", paths: []string{"testdata"}, wantCount: 11},
+		{name: "glob pattern", paths: []string{"testdata/*.sql"}, wantCount: 11},
+		{name: "duplicated paths", paths: []string{"testdata", "testdata/test-db.sql"}, wantCount: 11},
+		{name: "not existing file", paths: []string{"testdata/not-existing.sql"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := Files{Paths: tt.paths}
+			got, err := files.TableInfos()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Files.TableInfos() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.wantCount {
+				t.Errorf("Files.TableInfos() returned %d tables, want %d", len(got), tt.wantCount)
+			}
+		})
+	}
+}
+
+func equal(a, b table.Info) bool {
+	return a.Key == b.Key &&
+		a.Engine == b.Engine &&
+		a.EngineFull == b.EngineFull &&
+		a.CreateTableQuery == b.CreateTableQuery &&
+		a.AsSelect == b.AsSelect &&
+		slices.Equal(a.DependenciesDatabase, b.DependenciesDatabase) &&
+		slices.Equal(a.DependenciesTable, b.DependenciesTable)
+}
@@ -0,0 +1,200 @@
+// Package dot provides functionality to generate Graphviz DOT diagrams.
+//
+// Use [Graph] function to generate a DOT digraph from the specified [graph.Links].
+//
+//	dotGraph := dot.Graph(*tableLinks, dot.GraphOptions{RankDir: dot.TB, IncludeEngine: true})
+//
+// The result can be rendered with Graphviz, e.g. dot -Tsvg graph.dot -o graph.svg
+package dot
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/mbaksheev/clickhouse-table-graph/graph"
+	"github.com/mbaksheev/clickhouse-table-graph/internal/engine"
+	"github.com/mbaksheev/clickhouse-table-graph/internal/nodeid"
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+// RankDir represents the direction of the graph layout.
+type RankDir int
+
+// Possible values for the [RankDir] type.
+const (
+	// TB is a top to bottom direction.
+	TB RankDir = iota
+	// BT is a bottom to top direction.
+	BT
+	// LR is a left to right direction.
+	LR
+	// RL is a right to left direction.
+	RL
+)
+
+// name returns the textual name of the [RankDir] in order to use it in the graph.
+// Unknown values fall back to the [TB] direction.
+func (r RankDir) name() string {
+	names := [...]string{"TB", "BT", "LR", "RL"}
+	if r < 0 || int(r) >= len(names) {
+		return names[TB]
+	}
+	return names[r]
+}
+
+// GraphOptions represents the options for the DOT graph.
+type GraphOptions struct {
+	// Title is the label of the graph. If not specified, the graph has no label.
+	Title string
+	// RankDir is the direction of the graph layout.
+	RankDir RankDir
+	// IncludeEngine is a flag to include the engine information in the node label. When true, the engine information is included.
+	IncludeEngine bool
+	// InitialTableHighlightColor is the color of the node border for the initial tables.
+	// E.g. "#ff8585", "red". If not specified, the node is not highlighted.
+	InitialTableHighlightColor string
+	// CycleHighlightColor is the color of the links and the node borders of the tables which form cycles, e.g. loops of materialized views.
+	// E.g. "#ff5757", "red". If not specified, the cycles are not highlighted.
+	CycleHighlightColor string
+	// LinkHighlights is a list of groups of links to highlight with the specified colors, e.g. links of the path between two tables.
+	LinkHighlights []graph.LinkHighlight
+	// TableHighlights is a list of groups of tables to highlight with the specified colors, e.g. tables affected by dropping a table.
+	TableHighlights []graph.TableHighlight
+}
+
+// Graph generates a Graphviz DOT digraph from the specified [graph.Links].
+// Tables of every database are grouped into a cluster, nodes are ordered by database and table name,
+// and links are written in the order of the graph links after all nodes.
+// Node identifiers are returned by [NodeId], the table names are rendered only in the node labels.
+func Graph(graphLinks graph.Links, options GraphOptions) string {
+	var dot strings.Builder
+	dot.WriteString("digraph {\n")
+	if options.Title != "" {
+		dot.WriteString("  label=" + quote(options.Title) + ";\n")
+		dot.WriteString("  labelloc=t;\n")
+	}
+	dot.WriteString("  rankdir=" + options.RankDir.name() + ";\n")
+	dot.WriteString("  node [fontname=\"Helvetica\"];\n")
+	dot.WriteString("  edge [fontname=\"Helvetica\"];\n")
+
+	highlights := graphLinks.Highlights(graph.HighlightOptions{
+		InitialTableHighlightColor: options.InitialTableHighlightColor,
+		CycleHighlightColor:        options.CycleHighlightColor,
+		LinkHighlights:             options.LinkHighlights,
+		TableHighlights:            options.TableHighlights,
+	})
+	nodes := graphLinks.SortedNodes()
+	for i, tableKey := range nodes {
+		if i == 0 || nodes[i-1].Database != tableKey.Database {
+			dot.WriteString("  subgraph " + nodeid.Stable("cluster", tableKey.Database) + " {\n")
+			dot.WriteString("    label=" + quote(tableKey.Database) + ";\n")
+			dot.WriteString("    style=rounded;\n")
+		}
+		dot.WriteString("    ")
+		writeNode(&dot, graphLinks, tableKey, highlights.Table(tableKey), options)
+		if i == len(nodes)-1 || nodes[i+1].Database != tableKey.Database {
+			dot.WriteString("  }\n")
+		}
+	}
+	for _, link := range graphLinks.Links {
+		dot.WriteString("  ")
+		writeLink(&dot, link, highlights.Link(link))
+	}
+	dot.WriteString("}\n")
+	return dot.String()
+}
+
+// writeNode writes the node of the table with its shape, label and highlighting.
+// The node of the table which does not exist gets the note shape and the dashed border.
+func writeNode(stringBuildr *strings.Builder, graphLinks graph.Links, tableKey table.Key, style graph.TableStyle, options GraphOptions) {
+	attributes := make([]string, 0, 5)
+	styles := make([]string, 0, 2)
+	tableInfo, exists := graphLinks.TableInfo(tableKey)
+	if !exists {
+		attributes = append(attributes, "label="+quote(tableKey.String()+" (table does not exist)"), "shape=note")
+		styles = append(styles, "dashed")
+	} else {
+		label := tableKey.String()
+		if options.IncludeEngine {
+			label += " (" + tableInfo.Engine + ")"
+		}
+		shape, rounded := shapeOf(tableInfo)
+		attributes = append(attributes, "label="+quote(label), "shape="+shape)
+		if rounded {
+			styles = append(styles, "rounded")
+		}
+	}
+	if style.Color != "" {
+		attributes = append(attributes, "color="+quote(style.Color), "penwidth=2")
+		if style.Dashed && !slices.Contains(styles, "dashed") {
+			styles = append(styles, "dashed")
+		}
+	}
+	if len(styles) > 0 {
+		attributes = append(attributes, "style="+quote(strings.Join(styles, ",")))
+	}
+	stringBuildr.WriteString(NodeId(tableKey))
+	stringBuildr.WriteString(" [" + strings.Join(attributes, ", ") + "];\n")
+}
+
+// familyShapes are the DOT shapes of the engine families and whether the shapes have rounded corners.
+var familyShapes = map[engine.Family]struct {
+	shape   string
+	rounded bool
+}{
+	engine.MaterializedView: {shape: "hexagon"},
+	engine.View:             {shape: "oval"},
+	engine.Distributed:      {shape: "box3d"},
+	engine.Null:             {shape: "box", rounded: true},
+	engine.Dictionary:       {shape: "component"},
+	engine.Queue:            {shape: "cds"},
+	engine.Buffer:           {shape: "parallelogram"},
+	engine.JoinSet:          {shape: "folder"},
+	engine.MemoryLog:        {shape: "tab"},
+	engine.External:         {shape: "cylinder"},
+}
+
+// shapeOf returns the DOT shape of the table node depending on the engine family and whether the shape has rounded corners.
+// Tables of the MergeTree families and of the other engines are boxes.
+func shapeOf(tableInfo table.Info) (string, bool) {
+	if shape, exists := familyShapes[engine.FamilyOf(tableInfo.Engine)]; exists {
+		return shape.shape, shape.rounded
+	}
+	return "box", false
+}
+
+// writeLink writes the edge depending on the link kind:
+// dashed edges for JOIN reads, dictionary lookups and dictionary sources, labelled edges for materialized view targets and
+// regular edges for the rest of the links.
+func writeLink(stringBuildr *strings.Builder, link graph.Link, color string) {
+	attributes := make([]string, 0, 3)
+	switch link.Kind {
+	case graph.MVTarget:
+		attributes = append(attributes, "label=\"target\"")
+	case graph.JoinRead, graph.DictionaryLookup, graph.DictionarySource:
+		attributes = append(attributes, "style=dashed")
+	}
+	if color != "" {
+		attributes = append(attributes, "color="+quote(color), "penwidth=2")
+	}
+	stringBuildr.WriteString(NodeId(link.FromTableKey))
+	stringBuildr.WriteString(" -> ")
+	stringBuildr.WriteString(NodeId(link.ToTableKey))
+	if len(attributes) > 0 {
+		stringBuildr.WriteString(" [" + strings.Join(attributes, ", ") + "]")
+	}
+	stringBuildr.WriteString(";\n")
+}
+
+// NodeId returns the DOT identifier of the node of the table used in the node and edge statements of the [Graph].
+// It contains only ASCII letters, digits and underscores, so it is never quoted, and it is the same as the mermaid node identifier.
+// Use it to reference the node in additional DOT statements, e.g. to add attributes: t_db_events_0a1b2c3d [fillcolor="yellow"].
+func NodeId(tableKey table.Key) string {
+	return nodeid.Table(tableKey)
+}
+
+// quote returns the DOT double-quoted string with the escaped backslashes, double quotes and line breaks.
+func quote(value string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", "")
+	return `"` + replacer.Replace(value) + `"`
+}
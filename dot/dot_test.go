@@ -0,0 +1,190 @@
+package dot
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/mbaksheev/clickhouse-table-graph/graph"
+	"github.com/mbaksheev/clickhouse-table-graph/internal/nodeid"
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+func TestGraph(t *testing.T) {
+	b := graph.New()
+	b.AddTable(table.Info{Key: table.Key{Database: "raw", Name: "events"}, Engine: "Null"})
+	b.AddTable(table.Info{
+		Key:              table.Key{Database: "stats", Name: "events_mv"},
+		Engine:           "MaterializedView",
+		CreateTableQuery: "CREATE MATERIALIZED VIEW stats.events_mv TO stats.daily AS SELECT * FROM raw.events JOIN stats.users USING id",
+		AsSelect:         "SELECT * FROM raw.events JOIN stats.users USING id",
+	})
+	b.AddTable(table.Info{Key: table.Key{Database: "stats", Name: "daily"}, Engine: "MergeTree"})
+	b.AddTable(table.Info{
+		Key:        table.Key{Database: "stats", Name: "daily_dist"},
+		Engine:     "Distributed",
+		EngineFull: "Distributed('cluster', 'stats', 'daily')",
+	})
+	tableLinks, err := b.TableLinks(table.Key{Database: "raw", Name: "events"})
+	if err != nil {
+		t.Fatalf("TableLinks() error = %v", err)
+	}
+
+	got := Graph(*tableLinks, GraphOptions{
+		Title:                      "Graph of \"raw.events\"",
+		RankDir:                    LR,
+		IncludeEngine:              true,
+		InitialTableHighlightColor: "red",
+	})
+	events, daily, dist := nodeOf("raw", "events"), nodeOf("stats", "daily"), nodeOf("stats", "daily_dist")
+	mv, users := nodeOf("stats", "events_mv"), nodeOf("stats", "users")
+	want := []string{
+		"  label=\"Graph of \\\"raw.events\\\"\";\n",
+		"  rankdir=LR;\n",
+		"  subgraph " + nodeid.Stable("cluster", "raw") + " {\n    label=\"raw\";\n    style=rounded;\n" +
+			"    " + events + " [label=\"raw.events (Null)\", shape=box, color=\"red\", penwidth=2, style=\"rounded\"];\n  }\n",
+		"    " + daily + " [label=\"stats.daily (MergeTree)\", shape=box];\n",
+		"    " + dist + " [label=\"stats.daily_dist (Distributed)\", shape=box3d];\n",
+		"    " + mv + " [label=\"stats.events_mv (MaterializedView)\", shape=hexagon];\n",
+		"    " + users + " [label=\"stats.users (table does not exist)\", shape=note, style=\"dashed\"];\n",
+		"  " + events + " -> " + mv + ";\n",
+		"  " + mv + " -> " + daily + " [label=\"target\"];\n",
+		"  " + users + " -> " + mv + " [style=dashed];\n",
+		"  " + daily + " -> " + dist + ";\n",
+	}
+	for _, part := range want {
+		if !strings.Contains(got, part) {
+			t.Errorf("Graph() does not contain\n%s\ngot:\n%s", part, got)
+		}
+	}
+	if strings.Count(got, "subgraph") != 2 {
+		t.Errorf("Graph() has %d clusters, want 2:\n%s", strings.Count(got, "subgraph"), got)
+	}
+}
+
+func TestGraphHighlights(t *testing.T) {
+	a := table.Key{Database: "db", Name: "a"}
+	b := table.Key{Database: "db", Name: "b"}
+	builder := graph.New()
+	builder.AddTable(table.Info{Key: a, Engine: "Distributed", EngineFull: "Distributed('cluster', 'db', 'b')"})
+	builder.AddTable(table.Info{Key: b, Engine: "Distributed", EngineFull: "Distributed('cluster', 'db', 'a')"})
+	fullGraph := builder.FullGraph()
+	aId, bId := NodeId(a), NodeId(b)
+
+	tests := []struct {
+		name    string
+		options GraphOptions
+		want    []string
+	}{
+		{
+			name:    "cycle",
+			options: GraphOptions{CycleHighlightColor: "#ff5757"},
+			want: []string{
+				aId + " [label=\"db.a\", shape=box3d, color=\"#ff5757\", penwidth=2, style=\"dashed\"];",
+				aId + " -> " + bId + " [color=\"#ff5757\", penwidth=2];",
+			},
+		},
+		{
+			name: "table and link highlights win over cycle",
+			options: GraphOptions{
+				CycleHighlightColor: "#ff5757",
+				LinkHighlights:      []graph.LinkHighlight{{Links: []graph.Link{{FromTableKey: b, ToTableKey: a}}, Color: "green"}},
+				TableHighlights:     []graph.TableHighlight{{Tables: []table.Key{b}, Color: "orange"}},
+			},
+			want: []string{
+				bId + " [label=\"db.b\", shape=box3d, color=\"orange\", penwidth=2];",
+				bId + " -> " + aId + " [color=\"green\", penwidth=2];",
+				aId + " -> " + bId + " [color=\"#ff5757\", penwidth=2];",
+			},
+		},
+		{
+			name:    "no highlights",
+			options: GraphOptions{},
+			want: []string{
+				aId + " [label=\"db.a\", shape=box3d];",
+				aId + " -> " + bId + ";",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Graph(*fullGraph, tt.options)
+			for _, part := range tt.want {
+				if !strings.Contains(got, part) {
+					t.Errorf("Graph() does not contain\n%s\ngot:\n%s", part, got)
+				}
+			}
+		})
+	}
+}
+
+func TestGraphDottedNames(t *testing.T) {
+	first := table.Key{Database: "x.a", Name: "b"}
+	second := table.Key{Database: "x", Name: "a.b"}
+	builder := graph.New()
+	builder.AddTable(table.Info{Key: first, Engine: "MergeTree"})
+	builder.AddTable(table.Info{Key: second, Engine: "Null"})
+
+	got := Graph(*builder.FullGraph(), GraphOptions{IncludeEngine: true})
+	want := []string{
+		NodeId(first) + " [label=\"x.a.b (MergeTree)\", shape=box];",
+		NodeId(second) + " [label=\"x.a.b (Null)\", shape=box, style=\"rounded\"];",
+	}
+	for _, part := range want {
+		if !strings.Contains(got, part) {
+			t.Errorf("Graph() does not contain\n%s\ngot:\n%s", part, got)
+		}
+	}
+	if NodeId(first) == NodeId(second) {
+		t.Errorf("NodeId() = %s for both %s and %s", NodeId(first), first, second)
+	}
+}
+
+func TestShapeOf(t *testing.T) {
+	tests := []struct {
+		engine      string
+		wantShape   string
+		wantRounded bool
+	}{
+		{engine: "MaterializedView", wantShape: "hexagon"},
+		{engine: "Distributed", wantShape: "box3d"},
+		{engine: "Null", wantShape: "box", wantRounded: true},
+		{engine: "Dictionary", wantShape: "component"},
+		{engine: "ReplicatedReplacingMergeTree", wantShape: "box"},
+		{engine: "Kafka", wantShape: "cds"},
+		{engine: "S3", wantShape: "cylinder"},
+		{engine: "GenerateRandom", wantShape: "box"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.engine, func(t *testing.T) {
+			shape, rounded := shapeOf(table.Info{Engine: tt.engine})
+			if shape != tt.wantShape || rounded != tt.wantRounded {
+				t.Errorf("shapeOf(%s) = %s, %t, want %s, %t", tt.engine, shape, rounded, tt.wantShape, tt.wantRounded)
+			}
+		})
+	}
+}
+
+func TestRankDirName(t *testing.T) {
+	tests := []struct {
+		rankDir RankDir
+		want    string
+	}{
+		{rankDir: TB, want: "TB"},
+		{rankDir: RL, want: "RL"},
+		{rankDir: RankDir(4), want: "TB"},
+		{rankDir: RankDir(-1), want: "TB"},
+	}
+	for _, tt := range tests {
+		if got := tt.rankDir.name(); got != tt.want {
+			t.Errorf("RankDir(%d).name() = %s, want %s", tt.rankDir, got, tt.want)
+		}
+	}
+	if got := Graph(graph.Links{}, GraphOptions{RankDir: RankDir(42)}); !strings.Contains(got, "  rankdir=TB;\n") {
+		t.Errorf("Graph() with unknown RankDir does not fall back to TB:\n%s", got)
+	}
+}
+
+// nodeOf returns the node identifier of the table with the specified database and name.
+func nodeOf(database, name string) string {
+	return NodeId(table.Key{Database: database, Name: name})
+}
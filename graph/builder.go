@@ -3,19 +3,32 @@
 // The main entry point is the [LinksBuilder] interface, which is implemented by the [New] function.
 // Once the builder is created, you can add tables to it using the [LinksBuilder.AddTable] method.
 // After all tables are added, you can get the list of links for a specific table using the [LinksBuilder.TableLinks] method.
+//
+// Links of the added tables are found by the [Extractor] implementations. Additional extractors,
+// e.g. for in-house conventions like comments in the create query, can be registered with the [WithExtractor] option:
+//
+//	builder := graph.New(graph.WithExtractor(myExtractor{}, "MergeTree"))
 package graph
 
 import (
+	"cmp"
+	"fmt"
 	"github.com/mbaksheev/clickhouse-table-graph/table"
 	"slices"
 )
 
-// Links represents a graph (all linked tables) for the specified table.
+// Links represents a graph (all linked tables) for the specified table, or the full graph of all added tables.
 type Links struct {
-	// InitialTable is the key of the table for which the graph was built.
+	// InitialTable is the key of the table for which the graph was built. It is empty for the full graph.
+	// If the graph was built for several tables, it is the key of the first one.
 	InitialTable table.Key
+	// InitialTables is a list of keys of all tables for which the graph was built. It is empty for the full graph.
+	InitialTables []table.Key
 	// Links is a list of links between tables connected to the InitialTable.
 	Links []Link
+	// Nodes is a list of keys of all tables of the graph, including tables without links.
+	// Tables which are referenced by the added tables, but were not added to the graph themselves, are included as well.
+	Nodes []table.Key
 	// tables is a map of all tables added to the graph.
 	tables map[table.Key]table.Info
 }
@@ -26,6 +39,28 @@ func (links *Links) TableInfo(key table.Key) (table.Info, bool) {
 	return info, exists
 }
 
+// Outgoing returns all links of the graph from the specified table.
+func (links *Links) Outgoing(key table.Key) []Link {
+	outgoing := make([]Link, 0)
+	for _, link := range links.Links {
+		if link.FromTableKey == key {
+			outgoing = append(outgoing, link)
+		}
+	}
+	return outgoing
+}
+
+// Incoming returns all links of the graph to the specified table.
+func (links *Links) Incoming(key table.Key) []Link {
+	incoming := make([]Link, 0)
+	for _, link := range links.Links {
+		if link.ToTableKey == key {
+			incoming = append(incoming, link)
+		}
+	}
+	return incoming
+}
+
 // LinksBuilder is an interface for building a graph of tables.
 // Once the builder is created, you can add tables to it using the [LinksBuilder.AddTable] method.
 // After all tables are added, you can get the list of links for a specific table using the [LinksBuilder.TableLinks] method.
@@ -33,35 +68,96 @@ type LinksBuilder interface {
 	// AddTable adds the specified table to the graph builder.
 	AddTable(table table.Info)
 	// TableLinks returns the graph of tables as a list of all linked tables for the specified TableKey.
-	TableLinks(TableKey table.Key) (*Links, error)
+	// The direction and the maximum depth of the traversal can be specified with the options.
+	TableLinks(TableKey table.Key, options ...TraversalOption) (*Links, error)
+	// TablesLinks returns the merged graph of tables as a list of all linked tables for each of the specified TableKeys.
+	// The direction and the maximum depth of the traversal can be specified with the options.
+	TablesLinks(TableKeys []table.Key, options ...TraversalOption) (*Links, error)
+	// SimplePaths returns at most limit paths from one table to another following the direction of the links. The limit must be positive.
+	SimplePaths(from table.Key, to table.Key, limit int) ([][]Link, error)
+	// ShortestPath returns the path with the minimum number of links from one table to another following the direction of the links.
+	ShortestPath(from table.Key, to table.Key) ([]Link, error)
+	// FullGraph returns the complete graph of all added tables with all links between them.
+	FullGraph() *Links
+	// Cycles returns all cycles of the complete graph, e.g. loops of materialized views.
+	Cycles() []Cycle
+	// Impact returns the impact of dropping the specified table on all other tables of the complete graph.
+	Impact(key table.Key) (*ImpactReport, error)
+	// CreationOrder returns all tables of the complete graph in the order they can be created, so every referenced table exists first.
+	CreationOrder() ([]table.Key, error)
+	// DropOrder returns all tables of the complete graph in the order they can be dropped, i.e. the reversed creation order.
+	DropOrder() ([]table.Key, error)
 }
 
-// New creates a new [LinksBuilder].
-func New() LinksBuilder {
-	return &builder{
-		nodes:  make(map[table.Key]*graphNode),
-		tables: make(map[table.Key]table.Info),
+// New creates a new [LinksBuilder] with the specified options.
+// The built-in extractors are registered first, additional extractors can be registered with the [WithExtractor] option.
+func New(options ...Option) LinksBuilder {
+	b := &builder{
+		nodes:      make(map[table.Key]*graphNode),
+		tables:     make(map[table.Key]table.Info),
+		extractors: slices.Clone(builtinExtractors),
 	}
+	for _, option := range options {
+		option(b)
+	}
+	return b
 }
 
 type builder struct {
-	nodes  map[table.Key]*graphNode
-	tables map[table.Key]table.Info
+	nodes      map[table.Key]*graphNode
+	tables     map[table.Key]table.Info
+	extractors []registeredExtractor
 }
 
 type stackItem struct {
 	tableKey   table.Key
 	isToParent bool
+	depth      depth
 }
 
 // TableLinks returns the graph of tables as a list of all linked tables for the specified table key.
 //
 // The algorithm starts with the specified initialTableKey and finds all linked tables.
 // The result is a list of links between tables connected to the initialTableKey.
-func (b *builder) TableLinks(initialTableKey table.Key) (*Links, error) {
-	// use depth-first search to find all links for the specified initialTableKey
+// The direction and the maximum depth of the traversal can be specified with the [WithDirection] and [WithMaxDepth] options.
+func (b *builder) TableLinks(initialTableKey table.Key, options ...TraversalOption) (*Links, error) {
+	return b.TablesLinks([]table.Key{initialTableKey}, options...)
+}
+
+// TablesLinks returns the merged graph of tables as a list of all linked tables for each of the specified table keys.
+//
+// The graph of each table is built the same way as by the [builder.TableLinks] method,
+// the result contains links and nodes of all graphs without duplicates.
+func (b *builder) TablesLinks(initialTableKeys []table.Key, options ...TraversalOption) (*Links, error) {
+	if len(initialTableKeys) == 0 {
+		return nil, fmt.Errorf("TablesLinks: at least one table is required")
+	}
+	var t traversal
+	for _, option := range options {
+		option(&t)
+	}
 	graphLinks := make([]Link, 0)
-	visited := make(map[table.Key]bool)
+	nodes := make([]table.Key, 0)
+	added := make(map[table.Key]bool)
+	for _, initialTableKey := range initialTableKeys {
+		graphLinks, nodes = b.traverse(initialTableKey, t, graphLinks, nodes, added)
+	}
+	return &Links{
+			InitialTable:  initialTableKeys[0],
+			InitialTables: slices.Clone(initialTableKeys),
+			Links:         graphLinks,
+			Nodes:         nodes,
+			tables:        b.tables,
+		},
+		nil
+}
+
+// traverse finds all links for the specified initialTableKey and appends them and the visited nodes to the specified slices,
+// if they are not there yet. The added map contains the nodes which are already in the slice.
+// Already visited tables are not visited again, but the links to them are kept, so the cycles of the traversed tables are complete.
+func (b *builder) traverse(initialTableKey table.Key, t traversal, graphLinks []Link, nodes []table.Key, added map[table.Key]bool) ([]Link, []table.Key) {
+	// use depth-first search to find all links for the specified initialTableKey
+	visited := make(map[table.Key][]depth)
 	stack := []stackItem{{tableKey: initialTableKey, isToParent: false}}
 
 	for len(stack) > 0 {
@@ -69,81 +165,180 @@ func (b *builder) TableLinks(initialTableKey table.Key) (*Links, error) {
 		currentKey := currentStackItem.tableKey
 		stack = stack[:len(stack)-1]
 
-		if visited[currentKey] {
+		if dominated(visited[currentKey], currentStackItem.depth) {
 			continue
 		}
 
-		visited[currentKey] = true
+		if !added[currentKey] {
+			added[currentKey] = true
+			nodes = append(nodes, currentKey)
+		}
+		visited[currentKey] = append(visited[currentKey], currentStackItem.depth)
 
 		node, exists := b.nodes[currentKey]
 		if !exists {
 			continue
 		}
 
+		followTo := t.direction == Downstream || t.direction == Component || (t.direction == Both && !currentStackItem.isToParent)
 		for _, toLink := range node.toLinks {
-			if !visited[toLink] && !currentStackItem.isToParent {
-				newLink := Link{
-					FromTableKey: currentKey,
-					ToTableKey:   toLink,
-				}
-				if !slices.Contains(graphLinks, newLink) {
-					graphLinks = append(graphLinks, newLink)
-				}
-				stack = append(stack, stackItem{tableKey: toLink, isToParent: false})
+			toDepth, allowed := t.followDownstream(currentStackItem.depth)
+			if !followTo || !allowed {
+				continue
+			}
+			// the link is kept even if the table is already visited, e.g. the link which closes the cycle
+			graphLinks = appendLink(graphLinks, Link{
+				FromTableKey: currentKey,
+				ToTableKey:   toLink.key,
+				Kind:         toLink.kind,
+				Provenance:   slices.Clone(toLink.provenance),
+			})
+			if !dominated(visited[toLink.key], toDepth) {
+				stack = append(stack, stackItem{tableKey: toLink.key, isToParent: false, depth: toDepth})
 			}
-
 		}
 
+		followFrom := t.direction != Downstream
 		for _, link := range node.fromLinks {
-			if !visited[link] {
-				newLink := Link{
-					FromTableKey: link,
-					ToTableKey:   currentKey,
-				}
-				if !slices.Contains(graphLinks, newLink) {
-					graphLinks = append(graphLinks, newLink)
-				}
-				stack = append(stack, stackItem{tableKey: link, isToParent: true})
+			fromDepth, allowed := t.followUpstream(currentStackItem.depth)
+			if !followFrom || !allowed {
+				continue
+			}
+			graphLinks = appendLink(graphLinks, Link{
+				FromTableKey: link.key,
+				ToTableKey:   currentKey,
+				Kind:         link.kind,
+				Provenance:   slices.Clone(link.provenance),
+			})
+			if !dominated(visited[link.key], fromDepth) {
+				stack = append(stack, stackItem{tableKey: link.key, isToParent: true, depth: fromDepth})
 			}
 		}
 	}
+	return graphLinks, nodes
+}
+
+// FullGraph returns the complete graph of all added tables with all links between them.
+//
+// Nodes of the graph are sorted by database and table name, links are sorted by the tables they start from.
+// Links from the same table are in order they were found.
+func (b *builder) FullGraph() *Links {
+	nodes := make([]table.Key, 0, len(b.nodes))
+	for key := range b.nodes {
+		nodes = append(nodes, key)
+	}
+	slices.SortFunc(nodes, compareKeys)
+	graphLinks := make([]Link, 0)
+	for _, key := range nodes {
+		for _, toLink := range b.nodes[key].toLinks {
+			graphLinks = append(graphLinks, Link{
+				FromTableKey: key,
+				ToTableKey:   toLink.key,
+				Kind:         toLink.kind,
+				Provenance:   slices.Clone(toLink.provenance),
+			})
+		}
+	}
 	return &Links{
-			InitialTable: initialTableKey,
-			Links:        graphLinks,
-			tables:       b.tables,
-		},
-		nil
+		Links:  graphLinks,
+		Nodes:  nodes,
+		tables: b.tables,
+	}
+}
+
+// Cycles returns all cycles of the complete graph of all added tables.
+// The traversal methods stop at already visited tables, so cycles are not visible in their results without this method.
+func (b *builder) Cycles() []Cycle {
+	return b.FullGraph().Cycles()
+}
+
+// Impact returns the impact of dropping the specified table on all other tables of the complete graph of all added tables.
+func (b *builder) Impact(key table.Key) (*ImpactReport, error) {
+	return b.FullGraph().Impact(key)
+}
+
+// CreationOrder returns all tables of the complete graph of all added tables in the order they can be created.
+func (b *builder) CreationOrder() ([]table.Key, error) {
+	return b.FullGraph().CreationOrder()
+}
+
+// DropOrder returns all tables of the complete graph of all added tables in the order they can be dropped.
+func (b *builder) DropOrder() ([]table.Key, error) {
+	return b.FullGraph().DropOrder()
+}
+
+// compareKeys compares the table keys by database and table name.
+func compareKeys(a, b table.Key) int {
+	if result := cmp.Compare(a.Database, b.Database); result != 0 {
+		return result
+	}
+	return cmp.Compare(a.Name, b.Name)
 }
 
 // AddTable adds the specified table to the graph builder.
 func (b *builder) AddTable(tableInfo table.Info) {
 	b.tables[tableInfo.Key] = tableInfo
-	newNode := createGraphNode(tableInfo)
+	newNode := createGraphNode(tableInfo, extractorsFor(b.extractors, tableInfo.Engine))
 
 	if node, exists := b.nodes[tableInfo.Key]; exists {
-		node.fromLinks = append(node.fromLinks, newNode.fromLinks...)
-		node.toLinks = append(node.toLinks, newNode.toLinks...)
+		node.fromLinks = appendUnique(node.fromLinks, newNode.fromLinks...)
+		node.toLinks = appendUnique(node.toLinks, newNode.toLinks...)
 	} else {
 		b.nodes[tableInfo.Key] = &newNode
 	}
 	for _, fromLink := range newNode.fromLinks {
-		if node, exists := b.nodes[fromLink]; exists {
-			node.toLinks = append(node.toLinks, tableInfo.Key)
+		link := nodeLink{key: tableInfo.Key, kind: fromLink.kind, provenance: slices.Clone(fromLink.provenance)}
+		if node, exists := b.nodes[fromLink.key]; exists {
+			node.toLinks = appendUnique(node.toLinks, link)
 		} else {
-			b.nodes[fromLink] = &graphNode{
-				fromLinks: make([]table.Key, 0),
-				toLinks:   []table.Key{tableInfo.Key},
+			b.nodes[fromLink.key] = &graphNode{
+				fromLinks: make([]nodeLink, 0),
+				toLinks:   []nodeLink{link},
 			}
 		}
 	}
 	for _, toLink := range newNode.toLinks {
-		if node, exists := b.nodes[toLink]; exists {
-			node.fromLinks = append(node.fromLinks, tableInfo.Key)
+		link := nodeLink{key: tableInfo.Key, kind: toLink.kind, provenance: slices.Clone(toLink.provenance)}
+		if node, exists := b.nodes[toLink.key]; exists {
+			node.fromLinks = appendUnique(node.fromLinks, link)
 		} else {
-			b.nodes[toLink] = &graphNode{
-				fromLinks: []table.Key{tableInfo.Key},
-				toLinks:   make([]table.Key, 0),
+			b.nodes[toLink.key] = &graphNode{
+				fromLinks: []nodeLink{link},
+				toLinks:   make([]nodeLink, 0),
 			}
 		}
 	}
 }
+
+// appendUnique appends the specified links to the slice if there are no links to the same tables in the slice yet.
+// The same link can be found by different extractors, e.g. from the source table dependencies and from the materialized view query.
+// In this case the more specific kind replaces the [DependenciesColumn] kind and the provenance of both links is kept.
+func appendUnique(links []nodeLink, newLinks ...nodeLink) []nodeLink {
+	for _, newLink := range newLinks {
+		index := slices.IndexFunc(links, func(link nodeLink) bool { return link.key == newLink.key })
+		if index < 0 {
+			newLink.provenance = slices.Clone(newLink.provenance)
+			links = append(links, newLink)
+			continue
+		}
+		if links[index].kind == DependenciesColumn {
+			links[index].kind = newLink.kind
+		}
+		for _, provenance := range newLink.provenance {
+			if !slices.Contains(links[index].provenance, provenance) {
+				links[index].provenance = append(links[index].provenance, provenance)
+			}
+		}
+	}
+	return links
+}
+
+// appendLink appends the link to the slice if there is no link between the same tables in the slice yet.
+func appendLink(links []Link, newLink Link) []Link {
+	if slices.ContainsFunc(links, func(link Link) bool {
+		return link.FromTableKey == newLink.FromTableKey && link.ToTableKey == newLink.ToTableKey
+	}) {
+		return links
+	}
+	return append(links, newLink)
+}
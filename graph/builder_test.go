@@ -6,12 +6,14 @@ import (
 	"testing"
 )
 
-func TestBuilder(t *testing.T) {
-	// input_null -> table_materialized_view_1 -> table_merge_tree_1 -> table_distributed_1
-	// input_null -> table_materialized_view_2 -> table_merge_tree_2 -> table_distributed_2
-	// input_null_3 -> table_materialized_view_3 -> table_merge_tree_2
-	// input_merge_tree_4 -> table_distributed_4
-	tables := []table.Info{
+// testTables returns the tables of the test graph:
+//
+//	input_null -> table_materialized_view_1 -> table_merge_tree_1 -> table_distributed_1
+//	input_null -> table_materialized_view_2 -> table_merge_tree_2 -> table_distributed_2
+//	input_null_3 -> table_materialized_view_3 -> table_merge_tree_2
+//	input_merge_tree_4 -> table_distributed_4
+func testTables() []table.Info {
+	return []table.Info{
 		{
 			Key:                  table.Key{Database: "db", Name: "input_null"},
 			Engine:               "Null",
@@ -101,7 +103,10 @@ func TestBuilder(t *testing.T) {
 			DependenciesTable:    nil,
 		},
 	}
+}
 
+func TestBuilder(t *testing.T) {
+	tables := testTables()
 	tests := []struct {
 		name            string
 		initialTableKey table.Key
@@ -115,34 +120,42 @@ func TestBuilder(t *testing.T) {
 				{
 					FromTableKey: table.Key{Database: "db", Name: "input_null"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_materialized_view_1"},
+					Kind:         MVTrigger,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "input_null"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_materialized_view_2"},
+					Kind:         MVTrigger,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_2"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_merge_tree_2"},
+					Kind:         MVTarget,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_merge_tree_2"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_distributed_2"},
+					Kind:         DistributedLocal,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_3"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_merge_tree_2"},
+					Kind:         MVTarget,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "input_null_3"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_materialized_view_3"},
+					Kind:         MVTrigger,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_1"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_merge_tree_1"},
+					Kind:         MVTarget,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_merge_tree_1"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_distributed_1"},
+					Kind:         DistributedLocal,
 				},
 			},
 		},
@@ -153,22 +166,27 @@ func TestBuilder(t *testing.T) {
 				{
 					FromTableKey: table.Key{Database: "db", Name: "input_null_3"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_materialized_view_3"},
+					Kind:         MVTrigger,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_3"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_merge_tree_2"},
+					Kind:         MVTarget,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_merge_tree_2"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_distributed_2"},
+					Kind:         DistributedLocal,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_2"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_merge_tree_2"},
+					Kind:         MVTarget,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "input_null"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_materialized_view_2"},
+					Kind:         MVTrigger,
 				},
 			},
 		},
@@ -179,22 +197,27 @@ func TestBuilder(t *testing.T) {
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_2"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_merge_tree_2"},
+					Kind:         MVTarget,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "input_null"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_materialized_view_2"},
+					Kind:         MVTrigger,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_merge_tree_2"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_distributed_2"},
+					Kind:         DistributedLocal,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_3"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_merge_tree_2"},
+					Kind:         MVTarget,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "input_null_3"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_materialized_view_3"},
+					Kind:         MVTrigger,
 				},
 			},
 		},
@@ -205,22 +228,27 @@ func TestBuilder(t *testing.T) {
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_2"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_merge_tree_2"},
+					Kind:         MVTarget,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "input_null"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_materialized_view_2"},
+					Kind:         MVTrigger,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_merge_tree_2"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_distributed_2"},
+					Kind:         DistributedLocal,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_3"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_merge_tree_2"},
+					Kind:         MVTarget,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "input_null_3"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_materialized_view_3"},
+					Kind:         MVTrigger,
 				},
 			},
 		},
@@ -231,22 +259,27 @@ func TestBuilder(t *testing.T) {
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_merge_tree_2"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_distributed_2"},
+					Kind:         DistributedLocal,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_2"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_merge_tree_2"},
+					Kind:         MVTarget,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_3"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_merge_tree_2"},
+					Kind:         MVTarget,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "input_null_3"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_materialized_view_3"},
+					Kind:         MVTrigger,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "input_null"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_materialized_view_2"},
+					Kind:         MVTrigger,
 				},
 			},
 		},
@@ -257,6 +290,7 @@ func TestBuilder(t *testing.T) {
 				{
 					FromTableKey: table.Key{Database: "db", Name: "input_merge_tree_4"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_distributed_4"},
+					Kind:         DistributedLocal,
 				},
 			},
 		},
@@ -267,14 +301,17 @@ func TestBuilder(t *testing.T) {
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_1"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_merge_tree_1"},
+					Kind:         MVTarget,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "input_null"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_materialized_view_1"},
+					Kind:         MVTrigger,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_merge_tree_1"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_distributed_1"},
+					Kind:         DistributedLocal,
 				},
 			},
 		},
@@ -285,14 +322,17 @@ func TestBuilder(t *testing.T) {
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_merge_tree_1"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_distributed_1"},
+					Kind:         DistributedLocal,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_1"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_merge_tree_1"},
+					Kind:         MVTarget,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "input_null"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_materialized_view_1"},
+					Kind:         MVTrigger,
 				},
 			},
 		},
@@ -303,14 +343,17 @@ func TestBuilder(t *testing.T) {
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_merge_tree_1"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_distributed_1"},
+					Kind:         DistributedLocal,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_1"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_merge_tree_1"},
+					Kind:         MVTarget,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "input_null"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_materialized_view_1"},
+					Kind:         MVTrigger,
 				},
 			},
 		},
@@ -321,22 +364,27 @@ func TestBuilder(t *testing.T) {
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_3"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_merge_tree_2"},
+					Kind:         MVTarget,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "input_null_3"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_materialized_view_3"},
+					Kind:         MVTrigger,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_merge_tree_2"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_distributed_2"},
+					Kind:         DistributedLocal,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_2"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_merge_tree_2"},
+					Kind:         MVTarget,
 				},
 				{
 					FromTableKey: table.Key{Database: "db", Name: "input_null"},
 					ToTableKey:   table.Key{Database: "db", Name: "table_materialized_view_2"},
+					Kind:         MVTrigger,
 				},
 			},
 		},
@@ -352,10 +400,192 @@ func TestBuilder(t *testing.T) {
 				t.Errorf("LinksBuilder.TableLinks() error = %v", err)
 				return
 			}
-			if !slices.Equal(got.Links, tt.wantLinks) {
+			if !slices.EqualFunc(got.Links, tt.wantLinks, sameLink) {
 				t.Errorf("LinksBuilder.TableLinks() =\n %v, \nWant =\n %v", got.Links, tt.wantLinks)
 			}
 		})
 
 	}
 }
+
+func TestBuilderProvenance(t *testing.T) {
+	b := New()
+	for _, tableInfo := range testTables() {
+		b.AddTable(tableInfo)
+	}
+	got, err := b.TableLinks(table.Key{Database: "db", Name: "table_distributed_1"})
+	if err != nil {
+		t.Fatalf("LinksBuilder.TableLinks() error = %v", err)
+	}
+	want := map[[2]table.Key][]Provenance{
+		{table.Key{Database: "db", Name: "table_merge_tree_1"}, table.Key{Database: "db", Name: "table_distributed_1"}}: {
+			{Extractor: "DistributedEngine", Table: table.Key{Database: "db", Name: "table_distributed_1"}, Field: "engine_full", Start: 0, End: 50, Snippet: "Distributed('cluster', 'db', 'table_merge_tree_1')"},
+		},
+		{table.Key{Database: "db", Name: "table_materialized_view_1"}, table.Key{Database: "db", Name: "table_merge_tree_1"}}: {
+			{Extractor: "MaterializedViewTarget", Table: table.Key{Database: "db", Name: "table_materialized_view_1"}, Field: "create_table_query", Start: 54, End: 78, Snippet: "TO db.table_merge_tree_1"},
+		},
+		{table.Key{Database: "db", Name: "input_null"}, table.Key{Database: "db", Name: "table_materialized_view_1"}}: {
+			{Extractor: "DependenciesColumn", Table: table.Key{Database: "db", Name: "input_null"}, Field: "dependencies_table", Start: -1, End: -1, Snippet: "db.table_materialized_view_1"},
+			{Extractor: "MaterializedViewSources", Table: table.Key{Database: "db", Name: "table_materialized_view_1"}, Field: "create_table_query", Start: 96, End: 109, Snippet: "db.input_null"},
+		},
+	}
+	if len(got.Links) != len(want) {
+		t.Fatalf("LinksBuilder.TableLinks() returned %d links, want %d", len(got.Links), len(want))
+	}
+	for _, link := range got.Links {
+		key := [2]table.Key{link.FromTableKey, link.ToTableKey}
+		if !slices.Equal(link.Provenance, want[key]) {
+			t.Errorf("Link %s -> %s provenance =\n %v, \nWant =\n %v", link.FromTableKey, link.ToTableKey, link.Provenance, want[key])
+		}
+	}
+}
+
+func TestBuilderSimplePathsOfTables(t *testing.T) {
+	tables := append(testTables(), table.Info{
+		Key:              table.Key{Database: "db", Name: "table_materialized_view_5"},
+		Engine:           "MaterializedView",
+		CreateTableQuery: "CREATE MATERIALIZED VIEW db.table_materialized_view_5 TO db.table_merge_tree_2 AS SELECT * FROM db.table_merge_tree_1",
+	})
+	tests := []struct {
+		name      string
+		from      table.Key
+		to        table.Key
+		wantPaths [][]string
+		wantErr   bool
+	}{
+		{
+			name: "several paths",
+			from: table.Key{Database: "db", Name: "input_null"},
+			to:   table.Key{Database: "db", Name: "table_distributed_2"},
+			wantPaths: [][]string{
+				{"db.input_null", "db.table_materialized_view_1", "db.table_merge_tree_1", "db.table_materialized_view_5", "db.table_merge_tree_2", "db.table_distributed_2"},
+				{"db.input_null", "db.table_materialized_view_2", "db.table_merge_tree_2", "db.table_distributed_2"},
+			},
+		},
+		{
+			name:      "no paths against the links direction",
+			from:      table.Key{Database: "db", Name: "table_distributed_2"},
+			to:        table.Key{Database: "db", Name: "input_null"},
+			wantPaths: [][]string{},
+		},
+		{
+			name:    "unknown table",
+			from:    table.Key{Database: "db", Name: "input_null"},
+			to:      table.Key{Database: "db", Name: "unknown"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New()
+			for _, tableInfo := range tables {
+				b.AddTable(tableInfo)
+			}
+			got, err := b.SimplePaths(tt.from, tt.to, 10)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LinksBuilder.SimplePaths() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			gotPaths := make([][]string, 0, len(got))
+			for _, path := range got {
+				gotPath := []string{path[0].FromTableKey.String()}
+				for _, link := range path {
+					gotPath = append(gotPath, link.ToTableKey.String())
+				}
+				gotPaths = append(gotPaths, gotPath)
+			}
+			if !slices.EqualFunc(gotPaths, tt.wantPaths, slices.Equal[[]string]) {
+				t.Errorf("LinksBuilder.SimplePaths() =\n %v, \nWant =\n %v", gotPaths, tt.wantPaths)
+			}
+		})
+	}
+}
+
+// sameLink reports whether the links connect the same tables with the same kind. Provenance is not compared.
+func sameLink(a, b Link) bool {
+	return a.FromTableKey == b.FromTableKey && a.ToTableKey == b.ToTableKey && a.Kind == b.Kind
+}
+
+func TestBuilderFullGraph(t *testing.T) {
+	b := New()
+	for _, tableInfo := range testTables() {
+		b.AddTable(tableInfo)
+	}
+	b.AddTable(table.Info{Key: table.Key{Database: "another_db", Name: "isolated"}, Engine: "MergeTree"})
+	got := b.FullGraph()
+
+	wantNodes := []table.Key{
+		{Database: "another_db", Name: "isolated"},
+		{Database: "db", Name: "input_merge_tree_4"},
+		{Database: "db", Name: "input_null"},
+		{Database: "db", Name: "input_null_3"},
+		{Database: "db", Name: "table_distributed_1"},
+		{Database: "db", Name: "table_distributed_2"},
+		{Database: "db", Name: "table_distributed_4"},
+		{Database: "db", Name: "table_materialized_view_1"},
+		{Database: "db", Name: "table_materialized_view_2"},
+		{Database: "db", Name: "table_materialized_view_3"},
+		{Database: "db", Name: "table_merge_tree_1"},
+		{Database: "db", Name: "table_merge_tree_2"},
+	}
+	if !slices.Equal(got.Nodes, wantNodes) {
+		t.Errorf("LinksBuilder.FullGraph() nodes =\n %v, \nWant =\n %v", got.Nodes, wantNodes)
+	}
+	wantLinks := []Link{
+		{FromTableKey: table.Key{Database: "db", Name: "input_merge_tree_4"}, ToTableKey: table.Key{Database: "db", Name: "table_distributed_4"}, Kind: DistributedLocal},
+		{FromTableKey: table.Key{Database: "db", Name: "input_null"}, ToTableKey: table.Key{Database: "db", Name: "table_materialized_view_1"}, Kind: MVTrigger},
+		{FromTableKey: table.Key{Database: "db", Name: "input_null"}, ToTableKey: table.Key{Database: "db", Name: "table_materialized_view_2"}, Kind: MVTrigger},
+		{FromTableKey: table.Key{Database: "db", Name: "input_null_3"}, ToTableKey: table.Key{Database: "db", Name: "table_materialized_view_3"}, Kind: MVTrigger},
+		{FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_1"}, ToTableKey: table.Key{Database: "db", Name: "table_merge_tree_1"}, Kind: MVTarget},
+		{FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_2"}, ToTableKey: table.Key{Database: "db", Name: "table_merge_tree_2"}, Kind: MVTarget},
+		{FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_3"}, ToTableKey: table.Key{Database: "db", Name: "table_merge_tree_2"}, Kind: MVTarget},
+		{FromTableKey: table.Key{Database: "db", Name: "table_merge_tree_1"}, ToTableKey: table.Key{Database: "db", Name: "table_distributed_1"}, Kind: DistributedLocal},
+		{FromTableKey: table.Key{Database: "db", Name: "table_merge_tree_2"}, ToTableKey: table.Key{Database: "db", Name: "table_distributed_2"}, Kind: DistributedLocal},
+	}
+	if !slices.EqualFunc(got.Links, wantLinks, sameLink) {
+		t.Errorf("LinksBuilder.FullGraph() links =\n %v, \nWant =\n %v", got.Links, wantLinks)
+	}
+	mergeTree := table.Key{Database: "db", Name: "table_merge_tree_2"}
+	if incoming := got.Incoming(mergeTree); len(incoming) != 2 {
+		t.Errorf("Links.Incoming() = %v, want 2 links", incoming)
+	}
+	if outgoing := got.Outgoing(mergeTree); len(outgoing) != 1 {
+		t.Errorf("Links.Outgoing() = %v, want 1 link", outgoing)
+	}
+}
+
+func TestBuilderTablesLinks(t *testing.T) {
+	b := New()
+	for _, tableInfo := range testTables() {
+		b.AddTable(tableInfo)
+	}
+	initialTables := []table.Key{
+		{Database: "db", Name: "table_distributed_4"},
+		{Database: "db", Name: "table_materialized_view_1"},
+		{Database: "db", Name: "table_merge_tree_1"},
+	}
+	got, err := b.TablesLinks(initialTables)
+	if err != nil {
+		t.Fatalf("LinksBuilder.TablesLinks() error = %v", err)
+	}
+	wantLinks := []Link{
+		{FromTableKey: table.Key{Database: "db", Name: "input_merge_tree_4"}, ToTableKey: table.Key{Database: "db", Name: "table_distributed_4"}, Kind: DistributedLocal},
+		{FromTableKey: table.Key{Database: "db", Name: "table_materialized_view_1"}, ToTableKey: table.Key{Database: "db", Name: "table_merge_tree_1"}, Kind: MVTarget},
+		{FromTableKey: table.Key{Database: "db", Name: "input_null"}, ToTableKey: table.Key{Database: "db", Name: "table_materialized_view_1"}, Kind: MVTrigger},
+		{FromTableKey: table.Key{Database: "db", Name: "table_merge_tree_1"}, ToTableKey: table.Key{Database: "db", Name: "table_distributed_1"}, Kind: DistributedLocal},
+	}
+	if !slices.EqualFunc(got.Links, wantLinks, sameLink) {
+		t.Errorf("LinksBuilder.TablesLinks() =\n %v, \nWant =\n %v", got.Links, wantLinks)
+	}
+	if !slices.Equal(got.InitialTables, initialTables) || got.InitialTable != initialTables[0] {
+		t.Errorf("LinksBuilder.TablesLinks() initial tables = %v, %v, want %v", got.InitialTable, got.InitialTables, initialTables)
+	}
+	if len(got.Nodes) != 6 {
+		t.Errorf("LinksBuilder.TablesLinks() nodes = %v, want 6 nodes", got.Nodes)
+	}
+	if _, err := b.TablesLinks(nil); err == nil {
+		t.Errorf("LinksBuilder.TablesLinks() expected error for empty list of tables")
+	}
+}
@@ -0,0 +1,136 @@
+package graph
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+// Cycle represents a strongly connected component of the graph: a group of tables, each of which is reachable from any other one.
+// E.g. the materialized view A_mv inserts into the table B and the materialized view B_mv inserts back into the table A.
+// Such loops lead to the infinite insert amplification or errors on inserts.
+// A single table is a cycle only if it is linked to itself.
+type Cycle struct {
+	// Tables is a list of keys of all tables of the cycle sorted by database and table name.
+	Tables []table.Key
+	// Links is a list of all links between the tables of the cycle.
+	Links []Link
+}
+
+// String returns a textual representation of the [Cycle]: the list of its links with their kinds.
+func (c Cycle) String() string {
+	links := make([]string, 0, len(c.Links))
+	for _, link := range c.Links {
+		links = append(links, fmt.Sprintf("%s -> %s (%s)", link.FromTableKey, link.ToTableKey, link.Kind))
+	}
+	return strings.Join(links, ", ")
+}
+
+// Contains reports whether the specified table is a part of the [Cycle].
+func (c Cycle) Contains(key table.Key) bool {
+	_, found := slices.BinarySearchFunc(c.Tables, key, compareKeys)
+	return found
+}
+
+// Cycles returns all cycles of the graph. Cycles are found as strongly connected components with Tarjan's algorithm,
+// so each table belongs to at most one cycle. The result is sorted by the first table of the cycle.
+func (links *Links) Cycles() []Cycle {
+	adjacent := make(map[table.Key][]table.Key)
+	for _, link := range links.Links {
+		adjacent[link.FromTableKey] = append(adjacent[link.FromTableKey], link.ToTableKey)
+	}
+	cycles := make([]Cycle, 0)
+	for _, component := range stronglyConnectedComponents(links.SortedNodes(), adjacent) {
+		slices.SortFunc(component, compareKeys)
+		cycle := Cycle{Tables: component, Links: make([]Link, 0)}
+		for _, link := range links.Links {
+			if cycle.Contains(link.FromTableKey) && cycle.Contains(link.ToTableKey) {
+				cycle.Links = append(cycle.Links, link)
+			}
+		}
+		if len(component) > 1 || len(cycle.Links) > 0 {
+			cycles = append(cycles, cycle)
+		}
+	}
+	slices.SortFunc(cycles, func(a, b Cycle) int {
+		return compareKeys(a.Tables[0], b.Tables[0])
+	})
+	return cycles
+}
+
+// SortedNodes returns the keys of all tables of the graph, including tables which are only referenced by the links, sorted by database and table name.
+// Renderers use this order to write the nodes, so the output of the same graph is always the same.
+func (links *Links) SortedNodes() []table.Key {
+	nodes := slices.Clone(links.Nodes)
+	for _, link := range links.Links {
+		nodes = append(nodes, link.FromTableKey, link.ToTableKey)
+	}
+	slices.SortFunc(nodes, compareKeys)
+	return slices.Compact(nodes)
+}
+
+// tarjanFrame is a frame of the depth-first search stack of the Tarjan's algorithm.
+type tarjanFrame struct {
+	key  table.Key
+	next int
+}
+
+// stronglyConnectedComponents returns the strongly connected components of the graph with the specified nodes and adjacency lists.
+// The iterative version of Tarjan's algorithm is used, so deep graphs do not overflow the call stack.
+func stronglyConnectedComponents(nodes []table.Key, adjacent map[table.Key][]table.Key) [][]table.Key {
+	index := make(map[table.Key]int)
+	lowLink := make(map[table.Key]int)
+	onStack := make(map[table.Key]bool)
+	stack := make([]table.Key, 0)
+	components := make([][]table.Key, 0)
+	visit := func(key table.Key) {
+		index[key] = len(index)
+		lowLink[key] = index[key]
+		stack = append(stack, key)
+		onStack[key] = true
+	}
+	for _, root := range nodes {
+		if _, visited := index[root]; visited {
+			continue
+		}
+		visit(root)
+		frames := []tarjanFrame{{key: root}}
+		for len(frames) > 0 {
+			top := &frames[len(frames)-1]
+			if top.next < len(adjacent[top.key]) {
+				child := adjacent[top.key][top.next]
+				top.next++
+				if _, visited := index[child]; !visited {
+					visit(child)
+					frames = append(frames, tarjanFrame{key: child})
+				} else if onStack[child] {
+					lowLink[top.key] = min(lowLink[top.key], index[child])
+				}
+				continue
+			}
+			key := top.key
+			frames = frames[:len(frames)-1]
+			if len(frames) > 0 {
+				parent := frames[len(frames)-1].key
+				lowLink[parent] = min(lowLink[parent], lowLink[key])
+			}
+			if lowLink[key] != index[key] {
+				continue
+			}
+			component := make([]table.Key, 0)
+			for {
+				member := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[member] = false
+				component = append(component, member)
+				if member == key {
+					break
+				}
+			}
+			components = append(components, component)
+		}
+	}
+	return components
+}
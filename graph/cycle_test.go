@@ -0,0 +1,149 @@
+package graph
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+func TestBuilderCycles(t *testing.T) {
+	tables := []table.Info{
+		{
+			Key:              table.Key{Database: "db", Name: "a"},
+			Engine:           "MergeTree",
+			CreateTableQuery: "CREATE TABLE db.a (id UInt64) ENGINE = MergeTree ORDER BY id",
+		},
+		{
+			Key:              table.Key{Database: "db", Name: "a_mv"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.a_mv TO db.b AS SELECT id FROM db.a",
+		},
+		{
+			Key:              table.Key{Database: "db", Name: "b"},
+			Engine:           "MergeTree",
+			CreateTableQuery: "CREATE TABLE db.b (id UInt64) ENGINE = MergeTree ORDER BY id",
+		},
+		{
+			Key:              table.Key{Database: "db", Name: "b_mv"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.b_mv TO db.a AS SELECT id FROM db.b",
+		},
+		{
+			Key:              table.Key{Database: "db", Name: "b_copy_mv"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.b_copy_mv TO db.c AS SELECT id FROM db.b",
+		},
+		{
+			Key:              table.Key{Database: "db", Name: "c"},
+			Engine:           "MergeTree",
+			CreateTableQuery: "CREATE TABLE db.c (id UInt64) ENGINE = MergeTree ORDER BY id",
+		},
+		{
+			Key:              table.Key{Database: "db", Name: "self_mv"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.self_mv TO db.self_mv AS SELECT id FROM db.self_mv",
+		},
+	}
+	builder := New()
+	for _, tableInfo := range tables {
+		builder.AddTable(tableInfo)
+	}
+
+	cycles := builder.Cycles()
+
+	wantCycles := []struct {
+		tables []string
+		links  []string
+	}{
+		{
+			tables: []string{"db.a", "db.a_mv", "db.b", "db.b_mv"},
+			links: []string{
+				"db.a -> db.a_mv (MVTrigger)",
+				"db.a_mv -> db.b (MVTarget)",
+				"db.b -> db.b_mv (MVTrigger)",
+				"db.b_mv -> db.a (MVTarget)",
+			},
+		},
+		{
+			tables: []string{"db.self_mv"},
+			links:  []string{"db.self_mv -> db.self_mv (MVTrigger)"},
+		},
+	}
+	if len(cycles) != len(wantCycles) {
+		t.Fatalf("expected %d cycles, got %d: %v", len(wantCycles), len(cycles), cycles)
+	}
+	for i, want := range wantCycles {
+		gotTables := make([]string, 0)
+		for _, key := range cycles[i].Tables {
+			gotTables = append(gotTables, key.String())
+		}
+		if !slices.Equal(gotTables, want.tables) {
+			t.Errorf("cycle %d: expected tables %v, got %v", i, want.tables, gotTables)
+		}
+		gotLinks := make([]string, 0)
+		for _, link := range cycles[i].Links {
+			gotLinks = append(gotLinks, link.FromTableKey.String()+" -> "+link.ToTableKey.String()+" ("+link.Kind.String()+")")
+		}
+		slices.Sort(gotLinks)
+		if !slices.Equal(gotLinks, want.links) {
+			t.Errorf("cycle %d: expected links %v, got %v", i, want.links, gotLinks)
+		}
+	}
+	if cycles[0].Contains(table.Key{Database: "db", Name: "c"}) {
+		t.Errorf("expected db.c not to be a part of the cycle")
+	}
+}
+
+func TestLinksCyclesWithoutCycles(t *testing.T) {
+	builder := New()
+	for _, tableInfo := range testTables() {
+		builder.AddTable(tableInfo)
+	}
+	if cycles := builder.Cycles(); len(cycles) != 0 {
+		t.Errorf("expected no cycles, got %v", cycles)
+	}
+	tableLinks, err := builder.TableLinks(table.Key{Database: "db", Name: "input_null"})
+	if err != nil {
+		t.Fatalf("failed to get table links: %s", err)
+	}
+	if cycles := tableLinks.Cycles(); len(cycles) != 0 {
+		t.Errorf("expected no cycles, got %v", cycles)
+	}
+}
+
+func TestTableLinksCyclesWithDirection(t *testing.T) {
+	tables := []table.Info{
+		{Key: table.Key{Database: "db", Name: "a"}, Engine: "MergeTree"},
+		{
+			Key:              table.Key{Database: "db", Name: "mv1"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.mv1 TO db.b AS SELECT id FROM db.a",
+		},
+		{Key: table.Key{Database: "db", Name: "b"}, Engine: "MergeTree"},
+		{
+			Key:              table.Key{Database: "db", Name: "mv2"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.mv2 TO db.a AS SELECT id FROM db.b",
+		},
+	}
+	builder := New()
+	for _, tableInfo := range tables {
+		builder.AddTable(tableInfo)
+	}
+	for _, direction := range []Direction{Both, Upstream, Downstream, Component} {
+		t.Run(direction.String(), func(t *testing.T) {
+			tableLinks, err := builder.TableLinks(table.Key{Database: "db", Name: "a"}, WithDirection(direction))
+			if err != nil {
+				t.Fatalf("failed to get table links: %s", err)
+			}
+			if len(tableLinks.Links) != 4 {
+				t.Errorf("expected 4 links, got %d: %v", len(tableLinks.Links), tableLinks.Links)
+			}
+			cycles := tableLinks.Cycles()
+			if len(cycles) != 1 || len(cycles[0].Tables) != 4 || len(cycles[0].Links) != 4 {
+				t.Errorf("expected 1 cycle of 4 tables and 4 links, got %v", cycles)
+			}
+		})
+	}
+}
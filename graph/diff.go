@@ -0,0 +1,185 @@
+package graph
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+// ChangeKind represents the kind of the change of the table or the link between two graphs.
+type ChangeKind int
+
+// Possible values for the [ChangeKind] type.
+const (
+	// Added means the table or the link exists only in the new graph.
+	Added ChangeKind = iota
+	// Removed means the table or the link exists only in the old graph.
+	Removed
+	// Changed means the table or the link exists in both graphs, but its definition is changed, e.g. the engine of the table or the kind of the link.
+	Changed
+)
+
+// String returns the textual name of the [ChangeKind].
+func (kind ChangeKind) String() string {
+	names := [...]string{"Added", "Removed", "Changed"}
+	if kind < 0 || int(kind) >= len(names) {
+		return fmt.Sprintf("ChangeKind(%d)", kind)
+	}
+	return names[kind]
+}
+
+// TableChange describes the change of the table between two graphs.
+type TableChange struct {
+	// Key is the key of the changed table.
+	Key table.Key
+	// Kind is the kind of the change.
+	Kind ChangeKind
+	// Old is the table information in the old graph. It is empty for the added table.
+	Old table.Info
+	// New is the table information in the new graph. It is empty for the removed table.
+	New table.Info
+	// EngineChanged is true if the engine or the engine parameters of the changed table are different.
+	EngineChanged bool
+	// QueryChanged is true if the select query of the changed materialized view or view is different.
+	QueryChanged bool
+	// CreateQueryChanged is true if the create query of the changed table is different, e.g. columns are added.
+	CreateQueryChanged bool
+}
+
+// LinkChange describes the change of the link between two graphs.
+type LinkChange struct {
+	// Link is the link of the new graph, or the link of the old graph for the removed link.
+	Link Link
+	// Kind is the kind of the change.
+	Kind ChangeKind
+	// OldKind is the kind of the link in the old graph. It is different from the Link.Kind for the changed link.
+	OldKind LinkKind
+}
+
+// GraphDiff is the difference between two graphs, e.g. graphs of the same database on two servers or in two snapshot files.
+type GraphDiff struct {
+	// Tables is a list of added, removed and changed tables sorted by database and table name.
+	Tables []TableChange
+	// Links is a list of added, removed and changed links sorted by the tables they connect.
+	Links []LinkChange
+	// Graph is the merged graph which contains all tables and links of both graphs.
+	// Removed links are placed after the links of the new graph.
+	Graph *Links
+}
+
+// Empty reports whether there are no changes between two graphs.
+func (diff *GraphDiff) Empty() bool {
+	return len(diff.Tables) == 0 && len(diff.Links) == 0
+}
+
+// Diff returns the difference between the old and the new graphs, e.g. the full graphs of two schema versions.
+//
+// Tables are compared by their engine, engine parameters, select query and create query, so the changes of the table definition
+// which do not change the links are reported as well. Links are compared by the tables they connect and their kind.
+func Diff(oldGraph *Links, newGraph *Links) *GraphDiff {
+	diff := &GraphDiff{
+		Tables: make([]TableChange, 0),
+		Links:  make([]LinkChange, 0),
+	}
+	tables := maps.Clone(oldGraph.tables)
+	maps.Copy(tables, newGraph.tables)
+	nodes := slices.Concat(oldGraph.SortedNodes(), newGraph.SortedNodes())
+	slices.SortFunc(nodes, compareKeys)
+	nodes = slices.Compact(nodes)
+
+	for _, key := range nodes {
+		oldInfo, inOld := oldGraph.TableInfo(key)
+		newInfo, inNew := newGraph.TableInfo(key)
+		change := TableChange{Key: key, Old: oldInfo, New: newInfo}
+		switch {
+		case inNew && !inOld:
+			change.Kind = Added
+		case inOld && !inNew:
+			change.Kind = Removed
+		case inOld && inNew:
+			change.Kind = Changed
+			change.EngineChanged = oldInfo.Engine != newInfo.Engine || normalizeQuery(oldInfo.EngineFull) != normalizeQuery(newInfo.EngineFull)
+			change.QueryChanged = normalizeQuery(oldInfo.AsSelect) != normalizeQuery(newInfo.AsSelect)
+			change.CreateQueryChanged = normalizeQuery(oldInfo.CreateTableQuery) != normalizeQuery(newInfo.CreateTableQuery)
+			if !change.EngineChanged && !change.QueryChanged && !change.CreateQueryChanged {
+				continue
+			}
+		default:
+			continue
+		}
+		diff.Tables = append(diff.Tables, change)
+	}
+
+	oldLinks := linksByTables(oldGraph.Links)
+	newLinks := linksByTables(newGraph.Links)
+	mergedLinks := slices.Clone(newGraph.Links)
+	for _, link := range newGraph.Links {
+		oldLink, exists := oldLinks[[2]table.Key{link.FromTableKey, link.ToTableKey}]
+		switch {
+		case !exists:
+			diff.Links = append(diff.Links, LinkChange{Link: link, Kind: Added, OldKind: link.Kind})
+		case oldLink.Kind != link.Kind:
+			diff.Links = append(diff.Links, LinkChange{Link: link, Kind: Changed, OldKind: oldLink.Kind})
+		}
+	}
+	for _, link := range oldGraph.Links {
+		if _, exists := newLinks[[2]table.Key{link.FromTableKey, link.ToTableKey}]; !exists {
+			diff.Links = append(diff.Links, LinkChange{Link: link, Kind: Removed, OldKind: link.Kind})
+			mergedLinks = append(mergedLinks, link)
+		}
+	}
+	slices.SortStableFunc(diff.Links, func(a, b LinkChange) int {
+		if result := compareKeys(a.Link.FromTableKey, b.Link.FromTableKey); result != 0 {
+			return result
+		}
+		return compareKeys(a.Link.ToTableKey, b.Link.ToTableKey)
+	})
+
+	diff.Graph = &Links{
+		InitialTables: make([]table.Key, 0),
+		Links:         mergedLinks,
+		Nodes:         nodes,
+		tables:        tables,
+	}
+	return diff
+}
+
+// DiffProviders returns the difference between the full graphs of the tables of the old and the new providers,
+// e.g. two ClickHouse servers or two snapshot files. The options are used to create both graphs.
+func DiffProviders(oldProvider table.InfoProvider, newProvider table.InfoProvider, options ...Option) (*GraphDiff, error) {
+	oldTables, err := oldProvider.TableInfos()
+	if err != nil {
+		return nil, fmt.Errorf("DiffProviders: failed to get old tables: %w", err)
+	}
+	newTables, err := newProvider.TableInfos()
+	if err != nil {
+		return nil, fmt.Errorf("DiffProviders: failed to get new tables: %w", err)
+	}
+	oldBuilder := New(options...)
+	for _, tableInfo := range oldTables {
+		oldBuilder.AddTable(tableInfo)
+	}
+	newBuilder := New(options...)
+	for _, tableInfo := range newTables {
+		newBuilder.AddTable(tableInfo)
+	}
+	return Diff(oldBuilder.FullGraph(), newBuilder.FullGraph()), nil
+}
+
+// linksByTables returns the map of the links by the keys of the tables they connect.
+func linksByTables(links []Link) map[[2]table.Key]Link {
+	result := make(map[[2]table.Key]Link, len(links))
+	for _, link := range links {
+		result[[2]table.Key{link.FromTableKey, link.ToTableKey}] = link
+	}
+	return result
+}
+
+// normalizeQuery returns the query with all sequences of whitespaces replaced with a single space,
+// so the formatting changes are not reported as the query changes.
+func normalizeQuery(query string) string {
+	return strings.Join(strings.Fields(query), " ")
+}
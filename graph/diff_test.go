@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+type tablesProvider []table.Info
+
+func (p tablesProvider) TableInfos() ([]table.Info, error) {
+	return p, nil
+}
+
+func TestDiffProviders(t *testing.T) {
+	oldTables := tablesProvider{
+		{Key: table.Key{Database: "db", Name: "events"}, Engine: "MergeTree", EngineFull: "MergeTree ORDER BY id"},
+		{
+			Key:              table.Key{Database: "db", Name: "events_mv"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.events_mv TO db.daily AS SELECT * FROM db.events",
+			AsSelect:         "SELECT * FROM db.events",
+		},
+		{Key: table.Key{Database: "db", Name: "daily"}, Engine: "MergeTree", EngineFull: "MergeTree ORDER BY id"},
+		{Key: table.Key{Database: "db", Name: "legacy"}, Engine: "Log"},
+		{
+			Key:              table.Key{Database: "db", Name: "lookup_mv"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.lookup_mv TO db.daily AS SELECT * FROM db.legacy JOIN db.events USING id",
+			AsSelect:         "SELECT * FROM db.legacy JOIN db.events USING id",
+		},
+	}
+	newTables := tablesProvider{
+		{Key: table.Key{Database: "db", Name: "events"}, Engine: "MergeTree", EngineFull: "MergeTree  ORDER BY id"},
+		{
+			Key:              table.Key{Database: "db", Name: "events_mv"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.events_mv TO db.daily AS SELECT id, count() FROM db.events GROUP BY id",
+			AsSelect:         "SELECT id, count() FROM db.events GROUP BY id",
+		},
+		{Key: table.Key{Database: "db", Name: "daily"}, Engine: "SummingMergeTree", EngineFull: "SummingMergeTree ORDER BY id"},
+		{
+			Key:        table.Key{Database: "db", Name: "daily_dist"},
+			Engine:     "Distributed",
+			EngineFull: "Distributed('cluster', 'db', 'daily')",
+		},
+		{
+			Key:              table.Key{Database: "db", Name: "lookup_mv"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.lookup_mv TO db.daily AS SELECT * FROM db.events",
+			AsSelect:         "SELECT * FROM db.events",
+		},
+	}
+
+	diff, err := DiffProviders(oldTables, newTables)
+	if err != nil {
+		t.Fatalf("DiffProviders() error = %v", err)
+	}
+
+	wantTables := []string{
+		"Changed db.daily engine",
+		"Added db.daily_dist",
+		"Changed db.events_mv query",
+		"Removed db.legacy",
+		"Changed db.lookup_mv query",
+	}
+	gotTables := make([]string, 0)
+	for _, change := range diff.Tables {
+		description := change.Kind.String() + " " + change.Key.String()
+		if change.EngineChanged {
+			description += " engine"
+		}
+		if change.QueryChanged {
+			description += " query"
+		}
+		gotTables = append(gotTables, description)
+	}
+	if !slices.Equal(gotTables, wantTables) {
+		t.Errorf("Diff() tables =\n %v, \nWant =\n %v", gotTables, wantTables)
+	}
+
+	wantLinks := []string{
+		"Added db.daily -> db.daily_dist (DistributedLocal)",
+		"Changed db.events -> db.lookup_mv (MVTrigger, was JoinRead)",
+		"Removed db.legacy -> db.lookup_mv (MVTrigger)",
+	}
+	gotLinks := make([]string, 0)
+	for _, change := range diff.Links {
+		description := change.Kind.String() + " " + change.Link.FromTableKey.String() + " -> " + change.Link.ToTableKey.String() + " (" + change.Link.Kind.String()
+		if change.Kind == Changed {
+			description += ", was " + change.OldKind.String()
+		}
+		gotLinks = append(gotLinks, description+")")
+	}
+	if !slices.Equal(gotLinks, wantLinks) {
+		t.Errorf("Diff() links =\n %v, \nWant =\n %v", gotLinks, wantLinks)
+	}
+
+	if len(diff.Graph.Nodes) != 6 {
+		t.Errorf("Diff() graph has %d nodes, want 6", len(diff.Graph.Nodes))
+	}
+	if _, exists := diff.Graph.TableInfo(table.Key{Database: "db", Name: "legacy"}); !exists {
+		t.Errorf("Diff() graph does not contain the removed table")
+	}
+	if diff.Empty() {
+		t.Errorf("Diff() is empty")
+	}
+	if same, _ := DiffProviders(oldTables, oldTables); !same.Empty() {
+		t.Errorf("Diff() of the same tables is not empty: %v, %v", same.Tables, same.Links)
+	}
+}
@@ -0,0 +1,214 @@
+package graph
+
+import (
+	"github.com/mbaksheev/clickhouse-table-graph/internal/deps"
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+	"slices"
+)
+
+// Extractor extracts links of the table from its metadata, e.g. from the create query or the engine definition.
+//
+// Built-in extractors are registered by the [New] function for tables with the corresponding engines.
+// Additional extractors can be registered with the [WithExtractor] option for tables with the specified engines or for all tables.
+type Extractor interface {
+	// Name returns the name of the extractor. It is used as the Extractor of the [Provenance] of the found links.
+	Name() string
+	// Extract returns the links of the specified table found in its metadata.
+	Extract(tableInfo table.Info) []ExtractedLink
+}
+
+// ExtractedLink represents a link found by the [Extractor].
+// One of the FromTableKey and ToTableKey must be the key of the table, for which the link is extracted, otherwise the link is ignored.
+type ExtractedLink struct {
+	// FromTableKey is the key of the table from which the link starts.
+	FromTableKey table.Key
+	// ToTableKey is the key of the table to which the link leads.
+	ToTableKey table.Key
+	// Kind is the kind of the link.
+	Kind LinkKind
+	// Field is the name of the system.tables column containing the evidence of the link, e.g. "create_table_query".
+	Field string
+	// Start is the byte offset of the evidence start in the Field value. It is -1 if the evidence is not a part of the text.
+	// For text columns, i.e. engine, engine_full, create_table_query and as_select, the evidence snippet is taken from the Field value.
+	Start int
+	// End is the byte offset right after the evidence end in the Field value. It is -1 if the evidence is not a part of the text.
+	End int
+}
+
+// Option represents an option of the [LinksBuilder] created by the [New] function.
+type Option func(b *builder)
+
+// WithExtractor registers the extractor for tables with any of the specified engines, e.g. "MergeTree".
+// If no engines are specified, the extractor is used for all tables.
+// Extractors are called in order of registration, built-in extractors are registered first.
+func WithExtractor(extractor Extractor, engines ...string) Option {
+	return func(b *builder) {
+		b.extractors = append(b.extractors, registeredExtractor{extractor: extractor, engines: engines})
+	}
+}
+
+// WithoutBuiltinExtractors removes the built-in extractors, so only extractors registered with the [WithExtractor] option are used.
+// The built-in extractors can be registered again one by one, e.g.:
+//
+//	graph.New(graph.WithoutBuiltinExtractors(), graph.WithExtractor(graph.DistributedEngineExtractor{}, "Distributed"))
+func WithoutBuiltinExtractors() Option {
+	return func(b *builder) {
+		b.extractors = slices.DeleteFunc(b.extractors, func(registered registeredExtractor) bool { return registered.builtin })
+	}
+}
+
+// registeredExtractor represents the extractor registered for tables with the specified engines.
+type registeredExtractor struct {
+	// extractor is the registered extractor.
+	extractor Extractor
+	// engines is a list of engines of tables the extractor is used for. The extractor is used for all tables if it is empty.
+	engines []string
+	// builtin is true for the extractors registered by the [New] function.
+	builtin bool
+}
+
+// builtinExtractors is a list of the built-in extractors registered by the [New] function.
+var builtinExtractors = []registeredExtractor{
+	{extractor: DistributedEngineExtractor{}, engines: []string{"Distributed"}, builtin: true},
+	{extractor: MaterializedViewSourcesExtractor{}, engines: []string{"MaterializedView"}, builtin: true},
+	{extractor: DictionaryFunctionsExtractor{}, engines: []string{"MaterializedView"}, builtin: true},
+	{extractor: MaterializedViewTargetExtractor{}, engines: []string{"MaterializedView"}, builtin: true},
+	{extractor: DictionarySourceExtractor{}, engines: []string{"Dictionary"}, builtin: true},
+	{extractor: DependenciesColumnExtractor{}, builtin: true},
+}
+
+// extractorsFor returns the registered extractors which are used for tables with the specified engine.
+func extractorsFor(registered []registeredExtractor, engine string) []Extractor {
+	extractors := make([]Extractor, 0, len(registered))
+	for _, r := range registered {
+		if len(r.engines) == 0 || slices.Contains(r.engines, engine) {
+			extractors = append(extractors, r.extractor)
+		}
+	}
+	return extractors
+}
+
+// Names of the system.tables columns used in the [ExtractedLink] of the built-in extractors.
+const (
+	engineFullField        = "engine_full"
+	createTableQueryField  = "create_table_query"
+	dependenciesTableField = "dependencies_table"
+)
+
+// DistributedEngineExtractor is a built-in extractor of the [DistributedLocal] links from the engine_full column of the Distributed table.
+type DistributedEngineExtractor struct{}
+
+// Name returns the name of the extractor.
+func (DistributedEngineExtractor) Name() string {
+	return "DistributedEngine"
+}
+
+// Extract returns the link from the underlying table specified in the Distributed engine parameters.
+func (DistributedEngineExtractor) Extract(tableInfo table.Info) []ExtractedLink {
+	links := make([]ExtractedLink, 0)
+	for _, dependency := range deps.FromDistributedEngine(tableInfo.EngineFull) {
+		links = append(links, linkTo(tableInfo.Key, dependency, DistributedLocal, engineFullField))
+	}
+	return links
+}
+
+// MaterializedViewSourcesExtractor is a built-in extractor of the [MVTrigger] and [JoinRead] links from the create query of the materialized view.
+type MaterializedViewSourcesExtractor struct{}
+
+// Name returns the name of the extractor.
+func (MaterializedViewSourcesExtractor) Name() string {
+	return "MaterializedViewSources"
+}
+
+// Extract returns the links from all tables read by the select query of the materialized view.
+// The link from the leftmost table of the FROM clause has the [MVTrigger] kind, links from other tables have the [JoinRead] kind.
+func (MaterializedViewSourcesExtractor) Extract(tableInfo table.Info) []ExtractedLink {
+	links := make([]ExtractedLink, 0)
+	for _, source := range deps.SourcesFromCreateQuery(tableInfo.CreateTableQuery) {
+		kind := JoinRead
+		if source.Kind == deps.TriggerSource {
+			kind = MVTrigger
+		}
+		dependency := deps.Dependency{Key: source.Key, Start: source.Start, End: source.End}
+		links = append(links, linkTo(tableInfo.Key, dependency, kind, createTableQueryField))
+	}
+	return links
+}
+
+// DictionaryFunctionsExtractor is a built-in extractor of the [DictionaryLookup] links from the create query of the materialized view.
+type DictionaryFunctionsExtractor struct{}
+
+// Name returns the name of the extractor.
+func (DictionaryFunctionsExtractor) Name() string {
+	return "DictionaryFunctions"
+}
+
+// Extract returns the links from all dictionaries used in dictionary functions like dictGet.
+func (DictionaryFunctionsExtractor) Extract(tableInfo table.Info) []ExtractedLink {
+	links := make([]ExtractedLink, 0)
+	for _, dependency := range deps.DictionariesFromCreateQuery(tableInfo.CreateTableQuery) {
+		links = append(links, linkTo(tableInfo.Key, dependency, DictionaryLookup, createTableQueryField))
+	}
+	return links
+}
+
+// MaterializedViewTargetExtractor is a built-in extractor of the [MVTarget] links from the create query of the materialized view.
+type MaterializedViewTargetExtractor struct{}
+
+// Name returns the name of the extractor.
+func (MaterializedViewTargetExtractor) Name() string {
+	return "MaterializedViewTarget"
+}
+
+// Extract returns the link to the target table specified in the TO clause.
+func (MaterializedViewTargetExtractor) Extract(tableInfo table.Info) []ExtractedLink {
+	links := make([]ExtractedLink, 0)
+	for _, dependency := range deps.FromCreateQuery(tableInfo.CreateTableQuery) {
+		links = append(links, linkFrom(tableInfo.Key, dependency, MVTarget, createTableQueryField))
+	}
+	return links
+}
+
+// DictionarySourceExtractor is a built-in extractor of the [DictionarySource] links from the create query of the dictionary.
+type DictionarySourceExtractor struct{}
+
+// Name returns the name of the extractor.
+func (DictionarySourceExtractor) Name() string {
+	return "DictionarySource"
+}
+
+// Extract returns the link from the source table specified in the ClickHouse source of the dictionary.
+func (DictionarySourceExtractor) Extract(tableInfo table.Info) []ExtractedLink {
+	links := make([]ExtractedLink, 0)
+	for _, dependency := range deps.DictionarySourceFromCreateQuery(tableInfo.CreateTableQuery) {
+		links = append(links, linkTo(tableInfo.Key, dependency, DictionarySource, createTableQueryField))
+	}
+	return links
+}
+
+// DependenciesColumnExtractor is a built-in extractor of the [DependenciesColumn] links from the dependencies_database and dependencies_table columns.
+type DependenciesColumnExtractor struct{}
+
+// Name returns the name of the extractor.
+func (DependenciesColumnExtractor) Name() string {
+	return "DependenciesColumn"
+}
+
+// Extract returns the links to all dependent tables.
+func (DependenciesColumnExtractor) Extract(tableInfo table.Info) []ExtractedLink {
+	links := make([]ExtractedLink, 0)
+	for _, dependency := range deps.FromDependencies(tableInfo.DependenciesDatabase, tableInfo.DependenciesTable) {
+		links = append(links, linkFrom(tableInfo.Key, dependency, DependenciesColumn, dependenciesTableField))
+	}
+	return links
+}
+
+// linkTo returns the link from the found table to the specified table.
+func linkTo(key table.Key, dependency deps.Dependency, kind LinkKind, field string) ExtractedLink {
+	return ExtractedLink{FromTableKey: dependency.Key, ToTableKey: key, Kind: kind, Field: field, Start: dependency.Start, End: dependency.End}
+}
+
+// linkFrom returns the link from the specified table to the found table.
+func linkFrom(key table.Key, dependency deps.Dependency, kind LinkKind, field string) ExtractedLink {
+	return ExtractedLink{FromTableKey: key, ToTableKey: dependency.Key, Kind: kind, Field: field, Start: dependency.Start, End: dependency.End}
+}
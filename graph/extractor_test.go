@@ -0,0 +1,114 @@
+package graph
+
+import (
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+// feedsCommentRegex matches comments like "-- feeds: db.table" in the create query.
+var feedsCommentRegex = regexp.MustCompile(`--\s*feeds:\s*(\w+)\.(\w+)`)
+
+// feedsCommentExtractor is an example of the user-defined extractor, which links the table to the tables specified in the "-- feeds:" comments.
+type feedsCommentExtractor struct{}
+
+func (feedsCommentExtractor) Name() string {
+	return "FeedsComment"
+}
+
+func (feedsCommentExtractor) Extract(tableInfo table.Info) []ExtractedLink {
+	links := make([]ExtractedLink, 0)
+	for _, match := range feedsCommentRegex.FindAllStringSubmatchIndex(tableInfo.CreateTableQuery, -1) {
+		links = append(links, ExtractedLink{
+			FromTableKey: tableInfo.Key,
+			ToTableKey: table.Key{
+				Database: tableInfo.CreateTableQuery[match[2]:match[3]],
+				Name:     tableInfo.CreateTableQuery[match[4]:match[5]],
+			},
+			Kind:  Custom,
+			Field: "create_table_query",
+			Start: match[0],
+			End:   match[1],
+		})
+	}
+	return links
+}
+
+func TestExtractors(t *testing.T) {
+	tables := []table.Info{
+		{
+			Key:              table.Key{Database: "db", Name: "events"},
+			Engine:           "MergeTree",
+			CreateTableQuery: "CREATE TABLE db.events (id Int64) ENGINE = MergeTree ORDER BY id -- feeds: db.events_export",
+		},
+		{
+			Key:              table.Key{Database: "db", Name: "events_distributed"},
+			Engine:           "Distributed",
+			EngineFull:       "Distributed('cluster', 'db', 'events')",
+			CreateTableQuery: "CREATE TABLE db.events_distributed AS db.events ENGINE = Distributed('cluster', 'db', 'events') -- feeds: db.ignored",
+		},
+	}
+	tests := []struct {
+		name      string
+		options   []Option
+		wantLinks []Link
+	}{
+		{
+			name: "built-in extractors",
+			wantLinks: []Link{
+				{FromTableKey: table.Key{Database: "db", Name: "events"}, ToTableKey: table.Key{Database: "db", Name: "events_distributed"}, Kind: DistributedLocal},
+			},
+		},
+		{
+			name:    "extractor registered for engine",
+			options: []Option{WithExtractor(feedsCommentExtractor{}, "MergeTree", "ReplacingMergeTree")},
+			wantLinks: []Link{
+				{FromTableKey: table.Key{Database: "db", Name: "events"}, ToTableKey: table.Key{Database: "db", Name: "events_export"}, Kind: Custom},
+				{FromTableKey: table.Key{Database: "db", Name: "events"}, ToTableKey: table.Key{Database: "db", Name: "events_distributed"}, Kind: DistributedLocal},
+			},
+		},
+		{
+			name:    "extractor registered for all tables without built-in extractors",
+			options: []Option{WithoutBuiltinExtractors(), WithExtractor(feedsCommentExtractor{})},
+			wantLinks: []Link{
+				{FromTableKey: table.Key{Database: "db", Name: "events"}, ToTableKey: table.Key{Database: "db", Name: "events_export"}, Kind: Custom},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New(tt.options...)
+			for _, tableInfo := range tables {
+				b.AddTable(tableInfo)
+			}
+			got, err := b.TableLinks(table.Key{Database: "db", Name: "events"})
+			if err != nil {
+				t.Fatalf("LinksBuilder.TableLinks() error = %v", err)
+			}
+			if !slices.EqualFunc(got.Links, tt.wantLinks, sameLink) {
+				t.Errorf("LinksBuilder.TableLinks() =\n %v, \nWant =\n %v", got.Links, tt.wantLinks)
+			}
+		})
+	}
+}
+
+func TestExtractorProvenance(t *testing.T) {
+	b := New(WithExtractor(feedsCommentExtractor{}))
+	b.AddTable(table.Info{
+		Key:              table.Key{Database: "db", Name: "events"},
+		Engine:           "MergeTree",
+		CreateTableQuery: "CREATE TABLE db.events (id Int64) ENGINE = MergeTree ORDER BY id -- feeds: db.events_export",
+	})
+	got, err := b.TableLinks(table.Key{Database: "db", Name: "events_export"})
+	if err != nil {
+		t.Fatalf("LinksBuilder.TableLinks() error = %v", err)
+	}
+	want := []Provenance{
+		{Extractor: "FeedsComment", Table: table.Key{Database: "db", Name: "events"}, Field: "create_table_query", Start: 65, End: 91, Snippet: "-- feeds: db.events_export"},
+	}
+	if len(got.Links) != 1 || !slices.Equal(got.Links[0].Provenance, want) {
+		t.Errorf("LinksBuilder.TableLinks() =\n %v, \nWant provenance =\n %v", got.Links, want)
+	}
+}
@@ -0,0 +1,105 @@
+package graph
+
+import "github.com/mbaksheev/clickhouse-table-graph/table"
+
+// LinkHighlight represents the group of links highlighted with the same color.
+type LinkHighlight struct {
+	// Links is a list of links to highlight. Links are matched by the tables they connect.
+	Links []Link
+	// Color is the color of the links. E.g. "#ff5757", "red". If not specified, the links are not highlighted.
+	Color string
+}
+
+// TableHighlight represents the group of tables highlighted with the same color.
+type TableHighlight struct {
+	// Tables is a list of keys of the tables to highlight.
+	Tables []table.Key
+	// Color is the color of the node border. E.g. "#ff5757", "red". If not specified, the tables are not highlighted.
+	Color string
+}
+
+// HighlightOptions represents the highlighting options shared by all renderers of the graph.
+type HighlightOptions struct {
+	// InitialTableHighlightColor is the color of the initial tables. If not specified, the initial tables are not highlighted.
+	InitialTableHighlightColor string
+	// CycleHighlightColor is the color of the tables and the links which form cycles. If not specified, the cycles are not highlighted.
+	CycleHighlightColor string
+	// LinkHighlights is a list of groups of links to highlight with the specified colors.
+	LinkHighlights []LinkHighlight
+	// TableHighlights is a list of groups of tables to highlight with the specified colors.
+	TableHighlights []TableHighlight
+}
+
+// TableStyle represents the highlighting of the table.
+type TableStyle struct {
+	// Color is the highlight color of the table. It is empty if the table is not highlighted.
+	Color string
+	// Dashed is true if the table is highlighted as a part of the cycle.
+	Dashed bool
+}
+
+// Highlights represents the highlighting of the tables and the links of the graph resolved from the [HighlightOptions].
+type Highlights struct {
+	tables map[table.Key]TableStyle
+	links  map[[2]table.Key]string
+}
+
+// Highlights resolves the highlighting of the tables and the links of the graph with the specified options.
+// Cycles are applied first, then the initial tables, the link groups and the table groups in their order, so the later highlight wins.
+func (links *Links) Highlights(options HighlightOptions) *Highlights {
+	highlights := &Highlights{tables: make(map[table.Key]TableStyle), links: make(map[[2]table.Key]string)}
+	if options.CycleHighlightColor != "" {
+		for _, cycle := range links.Cycles() {
+			for _, key := range cycle.Tables {
+				highlights.tables[key] = TableStyle{Color: options.CycleHighlightColor, Dashed: true}
+			}
+			for _, link := range cycle.Links {
+				highlights.links[[2]table.Key{link.FromTableKey, link.ToTableKey}] = options.CycleHighlightColor
+			}
+		}
+	}
+	if options.InitialTableHighlightColor != "" {
+		for _, key := range links.InitialKeys() {
+			highlights.tables[key] = TableStyle{Color: options.InitialTableHighlightColor}
+		}
+	}
+	for _, highlight := range options.LinkHighlights {
+		if highlight.Color == "" {
+			continue
+		}
+		for _, link := range highlight.Links {
+			highlights.links[[2]table.Key{link.FromTableKey, link.ToTableKey}] = highlight.Color
+		}
+	}
+	for _, highlight := range options.TableHighlights {
+		if highlight.Color == "" {
+			continue
+		}
+		for _, key := range highlight.Tables {
+			highlights.tables[key] = TableStyle{Color: highlight.Color}
+		}
+	}
+	return highlights
+}
+
+// Table returns the highlighting of the specified table. The style is empty if the table is not highlighted.
+func (highlights *Highlights) Table(key table.Key) TableStyle {
+	return highlights.tables[key]
+}
+
+// Link returns the highlight color of the specified link, or an empty string if the link is not highlighted.
+func (highlights *Highlights) Link(link Link) string {
+	return highlights.links[[2]table.Key{link.FromTableKey, link.ToTableKey}]
+}
+
+// InitialKeys returns the keys of all tables for which the graph was built: the InitialTables,
+// or the InitialTable if only it is set. The result is empty for the full graph.
+func (links *Links) InitialKeys() []table.Key {
+	if len(links.InitialTables) > 0 {
+		return links.InitialTables
+	}
+	if links.InitialTable != (table.Key{}) {
+		return []table.Key{links.InitialTable}
+	}
+	return []table.Key{}
+}
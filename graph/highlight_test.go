@@ -0,0 +1,105 @@
+package graph
+
+import (
+	"testing"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+func TestLinksHighlights(t *testing.T) {
+	a := table.Key{Database: "db", Name: "a"}
+	b := table.Key{Database: "db", Name: "b"}
+	c := table.Key{Database: "db", Name: "c"}
+	builder := New()
+	builder.AddTable(table.Info{Key: a, Engine: "Distributed", EngineFull: "Distributed('cluster', 'db', 'b')"})
+	builder.AddTable(table.Info{Key: b, Engine: "Distributed", EngineFull: "Distributed('cluster', 'db', 'a')"})
+	builder.AddTable(table.Info{Key: c, Engine: "Distributed", EngineFull: "Distributed('cluster', 'db', 'a')"})
+	tableLinks := builder.FullGraph()
+	tableLinks.InitialTable = c
+	ab := Link{FromTableKey: a, ToTableKey: b}
+	ba := Link{FromTableKey: b, ToTableKey: a}
+	ac := Link{FromTableKey: a, ToTableKey: c}
+
+	tests := []struct {
+		name       string
+		options    HighlightOptions
+		wantTables map[table.Key]TableStyle
+		wantLinks  []linkColor
+	}{
+		{
+			name:       "no highlights",
+			options:    HighlightOptions{},
+			wantTables: map[table.Key]TableStyle{a: {}, b: {}, c: {}},
+			wantLinks:  []linkColor{{ab, ""}, {ba, ""}, {ac, ""}},
+		},
+		{
+			name:       "cycle and initial table",
+			options:    HighlightOptions{InitialTableHighlightColor: "red", CycleHighlightColor: "orange"},
+			wantTables: map[table.Key]TableStyle{a: {Color: "orange", Dashed: true}, b: {Color: "orange", Dashed: true}, c: {Color: "red"}},
+			wantLinks:  []linkColor{{ab, "orange"}, {ba, "orange"}, {ac, ""}},
+		},
+		{
+			name: "later highlights win",
+			options: HighlightOptions{
+				InitialTableHighlightColor: "red",
+				CycleHighlightColor:        "orange",
+				LinkHighlights: []LinkHighlight{
+					{Links: []Link{ab, ac}, Color: "green"},
+					{Links: []Link{ac}, Color: "blue"},
+					{Links: []Link{ba}},
+				},
+				TableHighlights: []TableHighlight{{Tables: []table.Key{a, c}, Color: "purple"}, {Tables: []table.Key{b}}},
+			},
+			wantTables: map[table.Key]TableStyle{a: {Color: "purple"}, b: {Color: "orange", Dashed: true}, c: {Color: "purple"}},
+			wantLinks:  []linkColor{{ab, "green"}, {ba, "orange"}, {ac, "blue"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			highlights := tableLinks.Highlights(tt.options)
+			for key, want := range tt.wantTables {
+				if got := highlights.Table(key); got != want {
+					t.Errorf("Table(%s) = %v, want %v", key, got, want)
+				}
+			}
+			for _, want := range tt.wantLinks {
+				if got := highlights.Link(want.link); got != want.color {
+					t.Errorf("Link(%s -> %s) = %s, want %s", want.link.FromTableKey, want.link.ToTableKey, got, want.color)
+				}
+			}
+		})
+	}
+}
+
+// linkColor is the expected highlight color of the link.
+type linkColor struct {
+	link  Link
+	color string
+}
+
+func TestLinksInitialKeys(t *testing.T) {
+	a := table.Key{Database: "db", Name: "a"}
+	b := table.Key{Database: "db", Name: "b"}
+	tests := []struct {
+		name  string
+		links Links
+		want  []table.Key
+	}{
+		{name: "full graph", links: Links{}, want: []table.Key{}},
+		{name: "single table", links: Links{InitialTable: a}, want: []table.Key{a}},
+		{name: "several tables", links: Links{InitialTable: a, InitialTables: []table.Key{a, b}}, want: []table.Key{a, b}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.links.InitialKeys()
+			if len(got) != len(tt.want) {
+				t.Fatalf("InitialKeys() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("InitialKeys() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
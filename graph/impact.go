@@ -0,0 +1,220 @@
+package graph
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+// Severity represents how badly the table is affected by dropping or altering another table.
+type Severity int
+
+// Possible values for the [Severity] type.
+const (
+	// StopsReceivingData means the table keeps working, but does not receive new data anymore.
+	StopsReceivingData Severity = iota
+	// HardBreak means inserts into the table or reads from the table fail.
+	HardBreak
+)
+
+// String returns the textual name of the [Severity].
+func (severity Severity) String() string {
+	names := [...]string{"StopsReceivingData", "HardBreak"}
+	if severity < 0 || int(severity) >= len(names) {
+		return fmt.Sprintf("Severity(%d)", severity)
+	}
+	return names[severity]
+}
+
+// Effect represents what happens with the affected table.
+type Effect int
+
+// Possible values for the [Effect] type. The effects are ordered from the least to the most severe one.
+const (
+	// NoNewData means the table does not receive new data, e.g. the source table of the materialized view is dropped.
+	NoNewData Effect = iota
+	// InsertsFail means inserts fail, e.g. the materialized view reads the dropped table in JOIN clause.
+	// Inserts into the source tables of the failing materialized view fail as well.
+	InsertsFail
+	// ReadsFail means reads from the table fail, e.g. the underlying table of the Distributed table is dropped.
+	ReadsFail
+)
+
+// String returns the textual name of the [Effect].
+func (effect Effect) String() string {
+	names := [...]string{"NoNewData", "InsertsFail", "ReadsFail"}
+	if effect < 0 || int(effect) >= len(names) {
+		return fmt.Sprintf("Effect(%d)", effect)
+	}
+	return names[effect]
+}
+
+// Severity returns the [Severity] of the [Effect].
+func (effect Effect) Severity() Severity {
+	if effect == NoNewData {
+		return StopsReceivingData
+	}
+	return HardBreak
+}
+
+// Impact describes how the table is affected by dropping or altering another table.
+type Impact struct {
+	// Table is the key of the affected table.
+	Table table.Key
+	// Effect is what happens with the affected table.
+	Effect Effect
+	// Severity is the severity of the Effect.
+	Severity Severity
+	// Reason is the textual description of the cause, e.g. "joined table db.a is dropped".
+	Reason string
+	// Link is the link of the graph by which the impact comes to the table.
+	// It leads from the affected table for the materialized views which insert into the failing tables.
+	Link Link
+	// Depth is the number of links between the dropped table and the affected table.
+	Depth int
+}
+
+// ImpactReport is the result of the impact analysis for the dropped or altered table.
+type ImpactReport struct {
+	// Table is the key of the dropped or altered table.
+	Table table.Key
+	// Impacts is a list of all affected tables ranked by severity: hard breaks first, then by the depth and the table key.
+	Impacts []Impact
+	// Graph is the graph which contains the Table and only the links by which the impacts come.
+	Graph *Links
+}
+
+// Impact returns the impact of dropping the specified table on all other tables of the graph.
+//
+// The dropped table is not readable, so the impact is propagated by the links of the graph:
+//   - tables which read the not readable table, i.e. materialized views with JOIN reads and dictionary lookups,
+//     dictionaries with the ClickHouse source and Distributed tables, are broken: reads or inserts of them fail;
+//   - materialized views which insert into the not readable table fail on insert;
+//   - inserts into the source tables of the failing materialized view fail as well;
+//   - tables which are fed by the not readable or failing tables stop receiving data, e.g. materialized views triggered by the dropped table
+//     and their target tables.
+//
+// The same analysis is applicable to ALTER statements which make the table incompatible with its readers and writers.
+func (links *Links) Impact(key table.Key) (*ImpactReport, error) {
+	if !slices.Contains(links.Nodes, key) {
+		return nil, fmt.Errorf("Impact: table %s is not found in the graph", key)
+	}
+	effects := map[table.Key]Effect{key: ReadsFail}
+	impacts := make(map[table.Key]Impact)
+	queue := []table.Key{key}
+	affect := func(affected table.Key, effect Effect, link Link, cause table.Key, role string) {
+		if affected == key {
+			return
+		}
+		if current, exists := effects[affected]; exists && current >= effect {
+			return
+		}
+		state := effects[cause].describe()
+		if cause == key {
+			state = "is dropped"
+		}
+		effects[affected] = effect
+		impacts[affected] = Impact{
+			Table:    affected,
+			Effect:   effect,
+			Severity: effect.Severity(),
+			Reason:   fmt.Sprintf("%s %s %s", role, cause, state),
+			Link:     link,
+			Depth:    impacts[cause].Depth + 1,
+		}
+		queue = append(queue, affected)
+	}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		effect := effects[current]
+		for _, link := range links.Outgoing(current) {
+			if next, ok := links.downstreamEffect(effect, link); ok {
+				affect(link.ToTableKey, next, link, current, roleOf(link.Kind))
+			}
+		}
+		for _, link := range links.Incoming(current) {
+			switch {
+			case link.Kind == MVTarget && effect == ReadsFail:
+				affect(link.FromTableKey, InsertsFail, link, current, "target table")
+			case (link.Kind == MVTrigger || link.Kind == DependenciesColumn) && effect == InsertsFail && links.engineOf(current) == "MaterializedView":
+				affect(link.FromTableKey, InsertsFail, link, current, "materialized view")
+			}
+		}
+	}
+
+	report := &ImpactReport{Table: key, Impacts: make([]Impact, 0, len(impacts))}
+	for _, impact := range impacts {
+		report.Impacts = append(report.Impacts, impact)
+	}
+	slices.SortFunc(report.Impacts, func(a, b Impact) int {
+		if result := cmp.Compare(b.Effect, a.Effect); result != 0 {
+			return result
+		}
+		if result := cmp.Compare(a.Depth, b.Depth); result != 0 {
+			return result
+		}
+		return compareKeys(a.Table, b.Table)
+	})
+	impactLinks := make([]Link, 0, len(report.Impacts))
+	for _, impact := range report.Impacts {
+		impactLinks = append(impactLinks, impact.Link)
+	}
+	report.Graph = links.Subgraph(impactLinks, key)
+	return report, nil
+}
+
+// downstreamEffect returns the effect on the table to which the link leads from the table with the specified effect.
+// The second result is false if the table is not affected, e.g. the materialized view reads the table in JOIN clause,
+// which does not receive new data, but is still readable.
+func (links *Links) downstreamEffect(effect Effect, link Link) (Effect, bool) {
+	switch link.Kind {
+	case JoinRead, DictionaryLookup, DictionarySource, DistributedLocal:
+		if effect == ReadsFail && links.engineOf(link.ToTableKey) == "MaterializedView" {
+			return InsertsFail, true
+		}
+		if effect == ReadsFail {
+			return ReadsFail, true
+		}
+		return NoNewData, link.Kind == DistributedLocal
+	default:
+		return NoNewData, true
+	}
+}
+
+// engineOf returns the engine of the specified table, or an empty string if the table information is not available.
+func (links *Links) engineOf(key table.Key) string {
+	tableInfo, _ := links.TableInfo(key)
+	return tableInfo.Engine
+}
+
+// describe returns the textual description of the [Effect] used in the [Impact] reason.
+func (effect Effect) describe() string {
+	descriptions := [...]string{"does not receive new data", "fails on insert", "is not readable"}
+	if effect < 0 || int(effect) >= len(descriptions) {
+		return effect.String()
+	}
+	return descriptions[effect]
+}
+
+// roleOf returns the role of the table from which the link of the specified kind starts, used in the [Impact] reason.
+func roleOf(kind LinkKind) string {
+	switch kind {
+	case MVTrigger:
+		return "source table"
+	case MVTarget:
+		return "materialized view"
+	case JoinRead:
+		return "joined table"
+	case DictionaryLookup:
+		return "dictionary"
+	case DictionarySource:
+		return "dictionary source table"
+	case DistributedLocal:
+		return "underlying table"
+	default:
+		return "dependency"
+	}
+}
@@ -0,0 +1,107 @@
+package graph
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+// impactTestTables returns tables which read or write the db.events table in all possible ways.
+func impactTestTables() []table.Info {
+	return []table.Info{
+		{Key: table.Key{Database: "db", Name: "events"}, Engine: "MergeTree"},
+		{
+			Key:        table.Key{Database: "db", Name: "events_dist"},
+			Engine:     "Distributed",
+			EngineFull: "Distributed('cluster', 'db', 'events', rand())",
+		},
+		{
+			Key:              table.Key{Database: "db", Name: "events_mv"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.events_mv TO db.daily AS SELECT * FROM db.events",
+		},
+		{Key: table.Key{Database: "db", Name: "daily"}, Engine: "SummingMergeTree"},
+		{Key: table.Key{Database: "db", Name: "clicks"}, Engine: "MergeTree"},
+		{
+			Key:              table.Key{Database: "db", Name: "clicks_mv"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.clicks_mv TO db.clicks_enriched AS SELECT * FROM db.clicks JOIN db.events USING id",
+		},
+		{Key: table.Key{Database: "db", Name: "clicks_enriched"}, Engine: "MergeTree"},
+		{Key: table.Key{Database: "db", Name: "raw"}, Engine: "Null"},
+		{
+			Key:              table.Key{Database: "db", Name: "archive_mv"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.archive_mv TO db.events AS SELECT * FROM db.raw",
+		},
+		{
+			Key:              table.Key{Database: "db", Name: "events_dict"},
+			Engine:           "Dictionary",
+			CreateTableQuery: "CREATE DICTIONARY db.events_dict (id UInt64, value String) PRIMARY KEY id SOURCE(CLICKHOUSE(TABLE 'events' DB 'db')) LAYOUT(FLAT()) LIFETIME(300)",
+		},
+		{
+			Key:              table.Key{Database: "db", Name: "lookup_mv"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.lookup_mv TO db.lookup_target AS SELECT dictGet('db.events_dict', 'value', id) FROM db.clicks",
+		},
+		{Key: table.Key{Database: "db", Name: "lookup_target"}, Engine: "MergeTree"},
+		{Key: table.Key{Database: "db", Name: "unrelated"}, Engine: "MergeTree"},
+	}
+}
+
+func TestBuilderImpact(t *testing.T) {
+	b := New()
+	for _, tableInfo := range impactTestTables() {
+		b.AddTable(tableInfo)
+	}
+
+	report, err := b.Impact(table.Key{Database: "db", Name: "events"})
+	if err != nil {
+		t.Fatalf("LinksBuilder.Impact() error = %v", err)
+	}
+
+	want := []struct {
+		table    string
+		effect   Effect
+		severity Severity
+		depth    int
+		reason   string
+	}{
+		{"db.events_dict", ReadsFail, HardBreak, 1, "dictionary source table db.events is dropped"},
+		{"db.events_dist", ReadsFail, HardBreak, 1, "underlying table db.events is dropped"},
+		{"db.archive_mv", InsertsFail, HardBreak, 1, "target table db.events is dropped"},
+		{"db.clicks_mv", InsertsFail, HardBreak, 1, "joined table db.events is dropped"},
+		{"db.clicks", InsertsFail, HardBreak, 2, "materialized view db.clicks_mv fails on insert"},
+		{"db.lookup_mv", InsertsFail, HardBreak, 2, "dictionary db.events_dict is not readable"},
+		{"db.raw", InsertsFail, HardBreak, 2, "materialized view db.archive_mv fails on insert"},
+		{"db.events_mv", NoNewData, StopsReceivingData, 1, "source table db.events is dropped"},
+		{"db.clicks_enriched", NoNewData, StopsReceivingData, 2, "materialized view db.clicks_mv fails on insert"},
+		{"db.daily", NoNewData, StopsReceivingData, 2, "materialized view db.events_mv does not receive new data"},
+		{"db.lookup_target", NoNewData, StopsReceivingData, 3, "materialized view db.lookup_mv fails on insert"},
+	}
+	if len(report.Impacts) != len(want) {
+		t.Fatalf("LinksBuilder.Impact() returned %d impacts, want %d: %v", len(report.Impacts), len(want), report.Impacts)
+	}
+	for i, w := range want {
+		got := report.Impacts[i]
+		if got.Table.String() != w.table || got.Effect != w.effect || got.Severity != w.severity || got.Depth != w.depth || got.Reason != w.reason {
+			t.Errorf("impact %d = {%s %s %s %d %q}, want {%s %s %s %d %q}",
+				i, got.Table, got.Effect, got.Severity, got.Depth, got.Reason, w.table, w.effect, w.severity, w.depth, w.reason)
+		}
+	}
+
+	if report.Graph.InitialTable != report.Table {
+		t.Errorf("impact graph initial table = %s, want %s", report.Graph.InitialTable, report.Table)
+	}
+	if len(report.Graph.Links) != len(want) {
+		t.Errorf("impact graph has %d links, want %d", len(report.Graph.Links), len(want))
+	}
+	if slices.Contains(report.Graph.Nodes, table.Key{Database: "db", Name: "unrelated"}) {
+		t.Errorf("impact graph contains unrelated table")
+	}
+
+	if _, err := b.Impact(table.Key{Database: "db", Name: "unknown"}); err == nil {
+		t.Errorf("LinksBuilder.Impact() expected error for unknown table")
+	}
+}
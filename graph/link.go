@@ -1,13 +1,78 @@
 package graph
 
 import (
+	"fmt"
+
 	"github.com/mbaksheev/clickhouse-table-graph/table"
 )
 
+// LinkKind represents the kind of the link between two tables, i.e. how the dependency was found.
+type LinkKind int
+
+// Possible values for the [LinkKind] type.
+const (
+	// DependenciesColumn is a link from the table to the dependent table found in the dependencies_table column of the system.tables table.
+	DependenciesColumn LinkKind = iota
+	// MVTrigger is a link from the source table to the materialized view. Inserts into the source table trigger the materialized view.
+	MVTrigger
+	// MVTarget is a link from the materialized view to its target table specified in the TO clause.
+	MVTarget
+	// JoinRead is a link from the table read by the materialized view in JOIN clause, subquery or other clause to the materialized view.
+	// Inserts into this table do not trigger the materialized view.
+	JoinRead
+	// DictionaryLookup is a link from the dictionary to the materialized view which uses it in dictionary functions like dictGet.
+	DictionaryLookup
+	// DistributedLocal is a link from the underlying local table to the Distributed table.
+	DistributedLocal
+	// DictionarySource is a link from the source table of the ClickHouse dictionary source to the dictionary.
+	// The dictionary reads the source table on load, inserts into the source table do not update the dictionary immediately.
+	DictionarySource
+	// Custom is a link found by the user-defined [Extractor], which does not match any other kind.
+	Custom
+)
+
+// String returns the textual name of the [LinkKind].
+func (kind LinkKind) String() string {
+	names := [...]string{"DependenciesColumn", "MVTrigger", "MVTarget", "JoinRead", "DictionaryLookup", "DistributedLocal", "DictionarySource", "Custom"}
+	if kind < 0 || int(kind) >= len(names) {
+		return fmt.Sprintf("LinkKind(%d)", kind)
+	}
+	return names[kind]
+}
+
 // Link represents a link between two tables.
 type Link struct {
 	// FromTableKey is the key of the table from which the link starts.
 	FromTableKey table.Key
 	// ToTableKey is the key of the table to which the link leads.
 	ToTableKey table.Key
+	// Kind is the kind of the link.
+	Kind LinkKind
+	// Provenance is a list of evidences of the link. The same link can be found by several extractors,
+	// e.g. in the dependencies_table column of the source table and in the select query of the materialized view.
+	Provenance []Provenance
+}
+
+// Provenance describes the evidence of the link: the extractor which found the link and the place in the table metadata it came from.
+type Provenance struct {
+	// Extractor is the name of the extractor which found the link, e.g. "DistributedEngine".
+	Extractor string
+	// Table is the key of the table, metadata of which contains the evidence.
+	Table table.Key
+	// Field is the name of the system.tables column containing the evidence, e.g. "create_table_query" or "engine_full".
+	Field string
+	// Start is the byte offset of the evidence start in the Field value. It is -1 if the evidence is not a part of the text, e.g. for the dependencies_table column.
+	Start int
+	// End is the byte offset right after the evidence end in the Field value. It is -1 if the evidence is not a part of the text.
+	End int
+	// Snippet is the text of the evidence, e.g. "TO db.target" for the target table of the materialized view.
+	Snippet string
+}
+
+// String returns a textual representation of the [Provenance].
+func (p Provenance) String() string {
+	if p.Start < 0 {
+		return fmt.Sprintf("%s: %s.%s = %s", p.Extractor, p.Table, p.Field, p.Snippet)
+	}
+	return fmt.Sprintf("%s: %s.%s[%d:%d] = %s", p.Extractor, p.Table, p.Field, p.Start, p.End, p.Snippet)
 }
@@ -0,0 +1,33 @@
+package graph
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestEnumString(t *testing.T) {
+	tests := []struct {
+		name  string
+		value fmt.Stringer
+		want  string
+	}{
+		{name: "link kind", value: DictionarySource, want: "DictionarySource"},
+		{name: "link kind out of range", value: LinkKind(42), want: "LinkKind(42)"},
+		{name: "negative link kind", value: LinkKind(-1), want: "LinkKind(-1)"},
+		{name: "direction", value: Component, want: "Component"},
+		{name: "direction out of range", value: Direction(4), want: "Direction(4)"},
+		{name: "severity", value: HardBreak, want: "HardBreak"},
+		{name: "severity out of range", value: Severity(2), want: "Severity(2)"},
+		{name: "effect", value: ReadsFail, want: "ReadsFail"},
+		{name: "effect out of range", value: Effect(3), want: "Effect(3)"},
+		{name: "change kind", value: Changed, want: "Changed"},
+		{name: "change kind out of range", value: ChangeKind(-2), want: "ChangeKind(-2)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.value.String(); got != tt.want {
+				t.Errorf("String() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
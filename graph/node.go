@@ -1,35 +1,79 @@
 package graph
 
 import (
-	"github.com/mbaksheev/clickhouse-table-graph/internal/deps"
 	"github.com/mbaksheev/clickhouse-table-graph/table"
 )
 
 // graphNode represents a node in the graph.
 type graphNode struct {
 	// fromLinks is a list of links from the node.
-	fromLinks []table.Key
+	fromLinks []nodeLink
 	// toLinks is a list of links to the node.
-	toLinks []table.Key
+	toLinks []nodeLink
 }
 
-// createGraphNode creates a graph node depending on the Engine or Dependencies information provided in the specified table.Info
-func createGraphNode(tableInfo table.Info) graphNode {
-	fromLinks := make([]table.Key, 0)
-	toLinks := make([]table.Key, 0)
+// nodeLink represents a link of the node to another table.
+type nodeLink struct {
+	// key is the key of the linked table.
+	key table.Key
+	// kind is the kind of the link.
+	kind LinkKind
+	// provenance is a list of evidences of the link.
+	provenance []Provenance
+}
+
+// createGraphNode creates a graph node from the links found by the specified extractors in the specified table.Info
+func createGraphNode(tableInfo table.Info, extractors []Extractor) graphNode {
+	fromLinks := make([]nodeLink, 0)
+	toLinks := make([]nodeLink, 0)
 
-	switch tableInfo.Engine {
-	case "Distributed":
-		fromLinks = append(fromLinks, deps.FromDistributedEngine(tableInfo.EngineFull)...)
-	case "MaterializedView":
-		fromLinks = append(fromLinks, deps.JoinedTablesFromCreateQuery(tableInfo.CreateTableQuery)...)
-		fromLinks = append(fromLinks, deps.DictionariesFromCreateQuery(tableInfo.CreateTableQuery)...)
-		toLinks = append(toLinks, deps.FromCreateQuery(tableInfo.CreateTableQuery)...)
-	default:
-		toLinks = append(toLinks, deps.FromDependencies(tableInfo.DependenciesDatabase, tableInfo.DependenciesTable)...)
+	for _, extractor := range extractors {
+		for _, link := range extractor.Extract(tableInfo) {
+			provenance := newProvenance(tableInfo, extractor.Name(), link)
+			switch tableInfo.Key {
+			case link.FromTableKey:
+				toLinks = appendUnique(toLinks, nodeLink{key: link.ToTableKey, kind: link.Kind, provenance: []Provenance{provenance}})
+			case link.ToTableKey:
+				fromLinks = appendUnique(fromLinks, nodeLink{key: link.FromTableKey, kind: link.Kind, provenance: []Provenance{provenance}})
+			}
+		}
 	}
 	return graphNode{
 		fromLinks: fromLinks,
 		toLinks:   toLinks,
 	}
 }
+
+// newProvenance creates the provenance of the link found by the extractor in the table.
+// The snippet is the evidence text of the text column, or the key of the linked table if the evidence is not a part of the text.
+func newProvenance(tableInfo table.Info, extractor string, link ExtractedLink) Provenance {
+	var text string
+	switch link.Field {
+	case "engine":
+		text = tableInfo.Engine
+	case engineFullField:
+		text = tableInfo.EngineFull
+	case createTableQueryField:
+		text = tableInfo.CreateTableQuery
+	case "as_select":
+		text = tableInfo.AsSelect
+	}
+	linkedKey := link.ToTableKey
+	if linkedKey == tableInfo.Key {
+		linkedKey = link.FromTableKey
+	}
+	provenance := Provenance{
+		Extractor: extractor,
+		Table:     tableInfo.Key,
+		Field:     link.Field,
+		Start:     link.Start,
+		End:       link.End,
+		Snippet:   linkedKey.String(),
+	}
+	if link.Start >= 0 && link.Start < link.End && link.End <= len(text) {
+		provenance.Snippet = text[link.Start:link.End]
+	} else {
+		provenance.Start, provenance.End = -1, -1
+	}
+	return provenance
+}
@@ -9,8 +9,8 @@ func TestCreateGraphNode(t *testing.T) {
 	tests := []struct {
 		name           string
 		inputTableInfo table.Info
-		wantToLinks    []table.Key
-		wantFromLinks  []table.Key
+		wantToLinks    []nodeLink
+		wantFromLinks  []nodeLink
 	}{
 		{
 			name: "Distributed engine",
@@ -21,9 +21,9 @@ func TestCreateGraphNode(t *testing.T) {
 				DependenciesDatabase: nil,
 				DependenciesTable:    nil,
 			},
-			wantToLinks: []table.Key{},
-			wantFromLinks: []table.Key{
-				{Database: "db", Name: "table"},
+			wantToLinks: []nodeLink{},
+			wantFromLinks: []nodeLink{
+				{key: table.Key{Database: "db", Name: "table"}, kind: DistributedLocal},
 			},
 		},
 		{
@@ -35,10 +35,28 @@ func TestCreateGraphNode(t *testing.T) {
 				DependenciesDatabase: nil,
 				DependenciesTable:    nil,
 			},
-			wantToLinks: []table.Key{
-				{Database: "db", Name: "table"},
+			wantToLinks: []nodeLink{
+				{key: table.Key{Database: "db", Name: "table"}, kind: MVTarget},
+			},
+			wantFromLinks: []nodeLink{
+				{key: table.Key{Database: "default", Name: "source"}, kind: MVTrigger},
+			},
+		},
+		{
+			name: "MaterializedView engine with joins, subqueries and dictionaries",
+			inputTableInfo: table.Info{
+				Engine:           "MaterializedView",
+				CreateTableQuery: "CREATE MATERIALIZED VIEW db.view TO db.table AS SELECT dictGet('db.dict', 'v', id) FROM db.source JOIN db.joined USING (id) WHERE id IN (SELECT id FROM db.filter)",
+			},
+			wantToLinks: []nodeLink{
+				{key: table.Key{Database: "db", Name: "table"}, kind: MVTarget},
+			},
+			wantFromLinks: []nodeLink{
+				{key: table.Key{Database: "db", Name: "source"}, kind: MVTrigger},
+				{key: table.Key{Database: "db", Name: "joined"}, kind: JoinRead},
+				{key: table.Key{Database: "db", Name: "filter"}, kind: JoinRead},
+				{key: table.Key{Database: "db", Name: "dict"}, kind: DictionaryLookup},
 			},
-			wantFromLinks: []table.Key{},
 		},
 		{
 			name: "Null engine",
@@ -49,11 +67,11 @@ func TestCreateGraphNode(t *testing.T) {
 				DependenciesDatabase: []string{"db1", "db2"},
 				DependenciesTable:    []string{"table1", "table2"},
 			},
-			wantToLinks: []table.Key{
-				{Database: "db1", Name: "table1"},
-				{Database: "db2", Name: "table2"},
+			wantToLinks: []nodeLink{
+				{key: table.Key{Database: "db1", Name: "table1"}, kind: DependenciesColumn},
+				{key: table.Key{Database: "db2", Name: "table2"}, kind: DependenciesColumn},
 			},
-			wantFromLinks: []table.Key{},
+			wantFromLinks: []nodeLink{},
 		},
 		{
 			name: "Any engine",
@@ -64,15 +82,15 @@ func TestCreateGraphNode(t *testing.T) {
 				DependenciesDatabase: []string{"db1"},
 				DependenciesTable:    []string{"table1"},
 			},
-			wantToLinks: []table.Key{
-				{Database: "db1", Name: "table1"},
+			wantToLinks: []nodeLink{
+				{key: table.Key{Database: "db1", Name: "table1"}, kind: DependenciesColumn},
 			},
-			wantFromLinks: []table.Key{},
+			wantFromLinks: []nodeLink{},
 		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			node := createGraphNode(tt.inputTableInfo)
+			node := createGraphNode(tt.inputTableInfo, extractorsFor(builtinExtractors, tt.inputTableInfo.Engine))
 
 			if !equal(node.fromLinks, tt.wantFromLinks) {
 				t.Errorf("createGraphNode() fromLinks = %v, want %v", node.fromLinks, tt.wantFromLinks)
@@ -84,12 +102,12 @@ func TestCreateGraphNode(t *testing.T) {
 	}
 }
 
-func equal(a, b []table.Key) bool {
+func equal(a, b []nodeLink) bool {
 	if len(a) != len(b) {
 		return false
 	}
 	for i := range a {
-		if a[i] != b[i] {
+		if a[i].key != b[i].key || a[i].kind != b[i].kind {
 			return false
 		}
 	}
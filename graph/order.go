@@ -0,0 +1,131 @@
+package graph
+
+import (
+	"container/heap"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+// CycleError is returned by [Links.CreationOrder] and [Links.DropOrder] if there is no valid order of tables,
+// because the tables depend on each other.
+type CycleError struct {
+	// Cycles is a list of cycles of the tables which depend on each other.
+	Cycles []Cycle
+}
+
+// Error returns the textual description of all cycles of the error.
+func (e *CycleError) Error() string {
+	cycles := make([]string, 0, len(e.Cycles))
+	for _, cycle := range e.Cycles {
+		cycles = append(cycles, cycle.String())
+	}
+	return fmt.Sprintf("tables depend on each other: %s", strings.Join(cycles, "; "))
+}
+
+// CreationOrder returns all tables of the graph in the order they can be created, so every referenced table exists first:
+// source tables, joined tables and dictionaries are created before the materialized views, which read them,
+// target tables are created before the materialized views, which insert into them, underlying tables are created before
+// the Distributed tables and source tables are created before the dictionaries.
+//
+// Tables which can be created at the same step are ordered by database and table name, so the order is deterministic.
+// The [CycleError] is returned if the tables depend on each other.
+func (links *Links) CreationOrder() ([]table.Key, error) {
+	nodes := links.SortedNodes()
+	dependents := make(map[table.Key][]table.Key)
+	dependencies := make(map[table.Key]int)
+	for _, link := range links.Links {
+		first, second := createdFirst(link)
+		if first == second {
+			continue
+		}
+		dependents[first] = append(dependents[first], second)
+		dependencies[second]++
+	}
+	ready := make(keyHeap, 0)
+	for _, key := range nodes {
+		if dependencies[key] == 0 {
+			ready = append(ready, key)
+		}
+	}
+	heap.Init(&ready)
+	order := make([]table.Key, 0, len(nodes))
+	for ready.Len() > 0 {
+		key := heap.Pop(&ready).(table.Key)
+		order = append(order, key)
+		for _, dependent := range dependents[key] {
+			dependencies[dependent]--
+			if dependencies[dependent] == 0 {
+				heap.Push(&ready, dependent)
+			}
+		}
+	}
+	if len(order) < len(nodes) {
+		return nil, fmt.Errorf("CreationOrder: %w", links.creationCycles(nodes, dependents))
+	}
+	return order, nil
+}
+
+// DropOrder returns all tables of the graph in the order they can be dropped, i.e. the reversed [Links.CreationOrder]:
+// materialized views are dropped before their source and target tables, so no inserts fail during the drop.
+func (links *Links) DropOrder() ([]table.Key, error) {
+	order, err := links.CreationOrder()
+	if err != nil {
+		return nil, fmt.Errorf("DropOrder: %w", err)
+	}
+	slices.Reverse(order)
+	return order, nil
+}
+
+// createdFirst returns the table of the link which must be created first and the table which must be created after it.
+// The target table of the materialized view must be created before the materialized view, in all other cases
+// the table from which the link starts is referenced by the table to which the link leads.
+func createdFirst(link Link) (table.Key, table.Key) {
+	if link.Kind == MVTarget {
+		return link.ToTableKey, link.FromTableKey
+	}
+	return link.FromTableKey, link.ToTableKey
+}
+
+// creationCycles returns the error with all cycles of the tables which must be created before each other.
+// Self links are skipped by the creation order, so they are not reported either.
+func (links *Links) creationCycles(nodes []table.Key, dependents map[table.Key][]table.Key) *CycleError {
+	cycleError := &CycleError{Cycles: make([]Cycle, 0)}
+	for _, component := range stronglyConnectedComponents(nodes, dependents) {
+		if len(component) < 2 {
+			continue
+		}
+		slices.SortFunc(component, compareKeys)
+		cycle := Cycle{Tables: component, Links: make([]Link, 0)}
+		for _, link := range links.Links {
+			if link.FromTableKey != link.ToTableKey && cycle.Contains(link.FromTableKey) && cycle.Contains(link.ToTableKey) {
+				cycle.Links = append(cycle.Links, link)
+			}
+		}
+		cycleError.Cycles = append(cycleError.Cycles, cycle)
+	}
+	slices.SortFunc(cycleError.Cycles, func(a, b Cycle) int {
+		return compareKeys(a.Tables[0], b.Tables[0])
+	})
+	return cycleError
+}
+
+// keyHeap is a min-heap of the table keys ordered by database and table name.
+type keyHeap []table.Key
+
+func (h keyHeap) Len() int           { return len(h) }
+func (h keyHeap) Less(i, j int) bool { return compareKeys(h[i], h[j]) < 0 }
+func (h keyHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *keyHeap) Push(x any) {
+	*h = append(*h, x.(table.Key))
+}
+
+func (h *keyHeap) Pop() any {
+	old := *h
+	key := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return key
+}
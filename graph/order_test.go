@@ -0,0 +1,87 @@
+package graph
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+func TestBuilderCreationOrder(t *testing.T) {
+	b := New()
+	for _, tableInfo := range impactTestTables() {
+		b.AddTable(tableInfo)
+	}
+	wantCreation := []string{
+		"db.clicks",
+		"db.clicks_enriched",
+		"db.daily",
+		"db.events",
+		"db.clicks_mv",
+		"db.events_dict",
+		"db.events_dist",
+		"db.events_mv",
+		"db.lookup_target",
+		"db.lookup_mv",
+		"db.raw",
+		"db.archive_mv",
+		"db.unrelated",
+	}
+
+	creationOrder, err := b.CreationOrder()
+	if err != nil {
+		t.Fatalf("LinksBuilder.CreationOrder() error = %v", err)
+	}
+	if got := keysToStrings(creationOrder); !slices.Equal(got, wantCreation) {
+		t.Errorf("LinksBuilder.CreationOrder() =\n %v, \nWant =\n %v", got, wantCreation)
+	}
+
+	dropOrder, err := b.DropOrder()
+	if err != nil {
+		t.Fatalf("LinksBuilder.DropOrder() error = %v", err)
+	}
+	wantDrop := slices.Clone(wantCreation)
+	slices.Reverse(wantDrop)
+	if got := keysToStrings(dropOrder); !slices.Equal(got, wantDrop) {
+		t.Errorf("LinksBuilder.DropOrder() =\n %v, \nWant =\n %v", got, wantDrop)
+	}
+}
+
+func TestBuilderCreationOrderCycle(t *testing.T) {
+	b := New()
+	b.AddTable(table.Info{Key: table.Key{Database: "db", Name: "a"}, Engine: "MergeTree"})
+	b.AddTable(table.Info{
+		Key:        table.Key{Database: "db", Name: "dist_1"},
+		Engine:     "Distributed",
+		EngineFull: "Distributed('cluster', 'db', 'dist_2')",
+	})
+	b.AddTable(table.Info{
+		Key:        table.Key{Database: "db", Name: "dist_2"},
+		Engine:     "Distributed",
+		EngineFull: "Distributed('cluster', 'db', 'dist_1')",
+	})
+
+	_, err := b.CreationOrder()
+	var cycleError *CycleError
+	if !errors.As(err, &cycleError) {
+		t.Fatalf("LinksBuilder.CreationOrder() error = %v, want CycleError", err)
+	}
+	if len(cycleError.Cycles) != 1 {
+		t.Fatalf("expected 1 cycle, got %v", cycleError.Cycles)
+	}
+	if got := keysToStrings(cycleError.Cycles[0].Tables); !slices.Equal(got, []string{"db.dist_1", "db.dist_2"}) {
+		t.Errorf("expected cycle of db.dist_1 and db.dist_2, got %v", got)
+	}
+	if _, err := b.DropOrder(); !errors.As(err, &cycleError) {
+		t.Errorf("LinksBuilder.DropOrder() error = %v, want CycleError", err)
+	}
+}
+
+func keysToStrings(keys []table.Key) []string {
+	result := make([]string, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, key.String())
+	}
+	return result
+}
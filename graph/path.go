@@ -0,0 +1,143 @@
+package graph
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+// SimplePaths returns at most limit paths from one table to another following the direction of the links.
+// The limit must be positive, because the number of paths grows exponentially on dense graphs.
+//
+// Each path is a list of links, where the first link starts at the from table and the last link leads to the to table.
+// A table is visited only once in a path, so cycles are not followed. Paths are found with the depth-first search
+// in the order the links were added, so the same graph always produces the same paths. The result is empty if there are no paths.
+func (b *builder) SimplePaths(from table.Key, to table.Key, limit int) ([][]Link, error) {
+	if limit <= 0 {
+		return nil, fmt.Errorf("SimplePaths: path limit must be positive: %d", limit)
+	}
+	if err := b.checkPathTables("SimplePaths", from, to); err != nil {
+		return nil, err
+	}
+	paths := make([][]Link, 0)
+	path := make([]Link, 0)
+	onPath := map[table.Key]bool{from: true}
+	var walk func(current table.Key)
+	walk = func(current table.Key) {
+		for _, toLink := range b.nodes[current].toLinks {
+			if len(paths) >= limit {
+				return
+			}
+			if onPath[toLink.key] {
+				continue
+			}
+			path = append(path, b.linkOf(current, toLink))
+			if toLink.key == to {
+				paths = append(paths, slices.Clone(path))
+			} else if _, exists := b.nodes[toLink.key]; exists {
+				onPath[toLink.key] = true
+				walk(toLink.key)
+				onPath[toLink.key] = false
+			}
+			path = path[:len(path)-1]
+		}
+	}
+	if from != to {
+		walk(from)
+	}
+	return paths, nil
+}
+
+// ShortestPath returns the path with the minimum number of links from one table to another following the direction of the links.
+//
+// The path is found with the breadth-first search. If there are several shortest paths,
+// the one which follows the earlier added links is returned. The result is empty if there is no path.
+func (b *builder) ShortestPath(from table.Key, to table.Key) ([]Link, error) {
+	if err := b.checkPathTables("ShortestPath", from, to); err != nil {
+		return nil, err
+	}
+	if from == to {
+		return []Link{}, nil
+	}
+	previous := make(map[table.Key]Link)
+	queue := []table.Key{from}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, toLink := range b.nodes[current].toLinks {
+			if _, visited := previous[toLink.key]; visited || toLink.key == from {
+				continue
+			}
+			previous[toLink.key] = b.linkOf(current, toLink)
+			if toLink.key == to {
+				return pathTo(previous, from, to), nil
+			}
+			if _, exists := b.nodes[toLink.key]; exists {
+				queue = append(queue, toLink.key)
+			}
+		}
+	}
+	return []Link{}, nil
+}
+
+// pathTo restores the path from one table to another using the map of links by which the tables were reached.
+func pathTo(previous map[table.Key]Link, from table.Key, to table.Key) []Link {
+	path := make([]Link, 0)
+	for current := to; current != from; {
+		link := previous[current]
+		path = append(path, link)
+		current = link.FromTableKey
+	}
+	slices.Reverse(path)
+	return path
+}
+
+// checkPathTables checks that both tables of the path query are added to the graph.
+func (b *builder) checkPathTables(funcName string, from table.Key, to table.Key) error {
+	if _, exists := b.nodes[from]; !exists {
+		return fmt.Errorf("%s: table %s is not found in the graph", funcName, from)
+	}
+	if _, exists := b.nodes[to]; !exists {
+		return fmt.Errorf("%s: table %s is not found in the graph", funcName, to)
+	}
+	return nil
+}
+
+// linkOf creates the [Link] from the specified table by the link of its graph node.
+func (b *builder) linkOf(from table.Key, toLink nodeLink) Link {
+	return Link{
+		FromTableKey: from,
+		ToTableKey:   toLink.key,
+		Kind:         toLink.kind,
+		Provenance:   slices.Clone(toLink.provenance),
+	}
+}
+
+// Subgraph returns the graph which contains only the specified links of this graph, e.g. links of the paths between two tables.
+// Duplicated links are skipped. The specified initial tables are highlighted as the initial tables of the result.
+func (links *Links) Subgraph(subgraphLinks []Link, initialTables ...table.Key) *Links {
+	result := &Links{
+		InitialTables: slices.Clone(initialTables),
+		Links:         make([]Link, 0),
+		Nodes:         make([]table.Key, 0),
+		tables:        links.tables,
+	}
+	if len(initialTables) > 0 {
+		result.InitialTable = initialTables[0]
+	}
+	addNode := func(key table.Key) {
+		if !slices.Contains(result.Nodes, key) {
+			result.Nodes = append(result.Nodes, key)
+		}
+	}
+	for _, key := range initialTables {
+		addNode(key)
+	}
+	for _, link := range subgraphLinks {
+		result.Links = appendLink(result.Links, link)
+		addNode(link.FromTableKey)
+		addNode(link.ToTableKey)
+	}
+	return result
+}
@@ -0,0 +1,174 @@
+package graph
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+// pathTestTables returns tables with two paths from db.kafka_raw to db.report: the long one via db.parsed and db.enriched
+// and the short one via db.raw_copy.
+func pathTestTables() []table.Info {
+	return []table.Info{
+		{Key: table.Key{Database: "db", Name: "kafka_raw"}, Engine: "Kafka"},
+		{
+			Key:              table.Key{Database: "db", Name: "parsed_mv"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.parsed_mv TO db.parsed AS SELECT * FROM db.kafka_raw",
+		},
+		{Key: table.Key{Database: "db", Name: "parsed"}, Engine: "MergeTree"},
+		{
+			Key:              table.Key{Database: "db", Name: "enriched_mv"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.enriched_mv TO db.enriched AS SELECT * FROM db.parsed",
+		},
+		{Key: table.Key{Database: "db", Name: "enriched"}, Engine: "MergeTree"},
+		{
+			Key:              table.Key{Database: "db", Name: "report_mv"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.report_mv TO db.report AS SELECT * FROM db.enriched",
+		},
+		{
+			Key:              table.Key{Database: "db", Name: "raw_copy_mv"},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.raw_copy_mv TO db.report AS SELECT * FROM db.kafka_raw",
+		},
+		{Key: table.Key{Database: "db", Name: "report"}, Engine: "MergeTree"},
+	}
+}
+
+func pathToStrings(path []Link) []string {
+	result := make([]string, 0, len(path))
+	for _, link := range path {
+		result = append(result, link.FromTableKey.String()+" -> "+link.ToTableKey.String())
+	}
+	return result
+}
+
+func TestBuilderShortestPath(t *testing.T) {
+	b := New()
+	for _, tableInfo := range pathTestTables() {
+		b.AddTable(tableInfo)
+	}
+	tests := []struct {
+		name     string
+		from     table.Key
+		to       table.Key
+		wantPath []string
+		wantErr  bool
+	}{
+		{
+			name: "shortest of two paths",
+			from: table.Key{Database: "db", Name: "kafka_raw"},
+			to:   table.Key{Database: "db", Name: "report"},
+			wantPath: []string{
+				"db.kafka_raw -> db.raw_copy_mv",
+				"db.raw_copy_mv -> db.report",
+			},
+		},
+		{
+			name:     "no path against the direction of links",
+			from:     table.Key{Database: "db", Name: "report"},
+			to:       table.Key{Database: "db", Name: "kafka_raw"},
+			wantPath: []string{},
+		},
+		{
+			name:    "unknown table",
+			from:    table.Key{Database: "db", Name: "kafka_raw"},
+			to:      table.Key{Database: "db", Name: "unknown"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := b.ShortestPath(tt.from, tt.to)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LinksBuilder.ShortestPath() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if gotPath := pathToStrings(got); !slices.Equal(gotPath, tt.wantPath) {
+				t.Errorf("LinksBuilder.ShortestPath() = %v, want %v", gotPath, tt.wantPath)
+			}
+		})
+	}
+}
+
+func TestBuilderSimplePaths(t *testing.T) {
+	b := New()
+	for _, tableInfo := range pathTestTables() {
+		b.AddTable(tableInfo)
+	}
+	from := table.Key{Database: "db", Name: "kafka_raw"}
+	to := table.Key{Database: "db", Name: "report"}
+	longPath := []string{
+		"db.kafka_raw -> db.parsed_mv",
+		"db.parsed_mv -> db.parsed",
+		"db.parsed -> db.enriched_mv",
+		"db.enriched_mv -> db.enriched",
+		"db.enriched -> db.report_mv",
+		"db.report_mv -> db.report",
+	}
+	shortPath := []string{
+		"db.kafka_raw -> db.raw_copy_mv",
+		"db.raw_copy_mv -> db.report",
+	}
+	tests := []struct {
+		name      string
+		limit     int
+		wantPaths [][]string
+		wantErr   bool
+	}{
+		{name: "all paths within the limit", limit: 10, wantPaths: [][]string{longPath, shortPath}},
+		{name: "limited", limit: 1, wantPaths: [][]string{longPath}},
+		{name: "zero limit", limit: 0, wantErr: true},
+		{name: "negative limit", limit: -1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := b.SimplePaths(from, to, tt.limit)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("LinksBuilder.SimplePaths() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			gotPaths := make([][]string, 0, len(got))
+			for _, path := range got {
+				gotPaths = append(gotPaths, pathToStrings(path))
+			}
+			if !slices.EqualFunc(gotPaths, tt.wantPaths, slices.Equal[[]string]) {
+				t.Errorf("LinksBuilder.SimplePaths() =\n %v, \nWant =\n %v", gotPaths, tt.wantPaths)
+			}
+		})
+	}
+}
+
+func TestLinksSubgraph(t *testing.T) {
+	b := New()
+	for _, tableInfo := range pathTestTables() {
+		b.AddTable(tableInfo)
+	}
+	from := table.Key{Database: "db", Name: "kafka_raw"}
+	to := table.Key{Database: "db", Name: "report"}
+	paths, err := b.SimplePaths(from, to, 10)
+	if err != nil {
+		t.Fatalf("LinksBuilder.SimplePaths() error = %v", err)
+	}
+	subgraph := b.FullGraph().Subgraph(slices.Concat(paths...), from, to)
+
+	if !slices.Equal(subgraph.InitialTables, []table.Key{from, to}) {
+		t.Errorf("Subgraph() initial tables = %v, want %v", subgraph.InitialTables, []table.Key{from, to})
+	}
+	if len(subgraph.Links) != 8 {
+		t.Errorf("Subgraph() has %d links, want 8", len(subgraph.Links))
+	}
+	if len(subgraph.Nodes) != 8 {
+		t.Errorf("Subgraph() has %d nodes, want 8", len(subgraph.Nodes))
+	}
+	if _, exists := subgraph.TableInfo(to); !exists {
+		t.Errorf("Subgraph() does not contain table info for %s", to)
+	}
+}
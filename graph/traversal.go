@@ -0,0 +1,94 @@
+package graph
+
+import "fmt"
+
+// Direction represents the direction of the graph traversal in the [LinksBuilder.TableLinks] method.
+type Direction int
+
+// Possible values for the [Direction] type.
+const (
+	// Both is the default direction. The traversal goes down to all tables which depend on the initial table,
+	// and up to all tables the initial table and its dependent tables depend on. Siblings of the parent tables are skipped.
+	Both Direction = iota
+	// Upstream is the direction to the tables the initial table depends on, i.e. against the direction of the links.
+	Upstream
+	// Downstream is the direction to the tables which depend on the initial table, i.e. in the direction of the links.
+	Downstream
+	// Component is the full connected component of the initial table, including siblings of the parent tables.
+	// The direction of the links is ignored.
+	Component
+)
+
+// String returns the textual name of the [Direction].
+func (d Direction) String() string {
+	names := [...]string{"Both", "Upstream", "Downstream", "Component"}
+	if d < 0 || int(d) >= len(names) {
+		return fmt.Sprintf("Direction(%d)", d)
+	}
+	return names[d]
+}
+
+// traversal represents the options of the graph traversal.
+type traversal struct {
+	// direction is the direction of the traversal.
+	direction Direction
+	// maxUpstreamDepth is the maximum number of links followed against their direction. Zero means unlimited.
+	maxUpstreamDepth int
+	// maxDownstreamDepth is the maximum number of links followed in their direction. Zero means unlimited.
+	maxDownstreamDepth int
+}
+
+// TraversalOption represents an option of the graph traversal in the [LinksBuilder.TableLinks] method.
+type TraversalOption func(t *traversal)
+
+// WithDirection sets the direction of the graph traversal. The default direction is [Both].
+func WithDirection(direction Direction) TraversalOption {
+	return func(t *traversal) {
+		t.direction = direction
+	}
+}
+
+// WithMaxDepth sets the maximum number of links followed against their direction (upstream)
+// and in their direction (downstream) from the initial table. Zero means unlimited, it is the default value.
+func WithMaxDepth(upstream int, downstream int) TraversalOption {
+	return func(t *traversal) {
+		t.maxUpstreamDepth = upstream
+		t.maxDownstreamDepth = downstream
+	}
+}
+
+// depth represents the number of links followed upstream and downstream from the initial table to reach a table.
+// Only limited directions are counted, so the depth is always zero if there are no limits.
+type depth struct {
+	upstream   int
+	downstream int
+}
+
+// followDownstream returns the depth of the table reached by following the link in its direction, and whether it is within the limit.
+func (t *traversal) followDownstream(current depth) (depth, bool) {
+	if t.maxDownstreamDepth == 0 {
+		return current, true
+	}
+	current.downstream++
+	return current, current.downstream <= t.maxDownstreamDepth
+}
+
+// followUpstream returns the depth of the table reached by following the link against its direction, and whether it is within the limit.
+func (t *traversal) followUpstream(current depth) (depth, bool) {
+	if t.maxUpstreamDepth == 0 {
+		return current, true
+	}
+	current.upstream++
+	return current, current.upstream <= t.maxUpstreamDepth
+}
+
+// dominated reports whether the table was already visited with the same or smaller depth in both directions,
+// so visiting it again with the specified depth can not find more tables.
+func dominated(visited []depth, d depth) bool {
+	for _, v := range visited {
+		if v.upstream <= d.upstream && v.downstream <= d.downstream {
+			return true
+		}
+	}
+	return false
+}
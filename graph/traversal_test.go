@@ -0,0 +1,103 @@
+package graph
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+func TestTableLinksTraversal(t *testing.T) {
+	tests := []struct {
+		name            string
+		initialTableKey table.Key
+		options         []TraversalOption
+		wantLinks       []string
+	}{
+		{
+			name:            "downstream",
+			initialTableKey: table.Key{Database: "db", Name: "table_materialized_view_2"},
+			options:         []TraversalOption{WithDirection(Downstream)},
+			wantLinks: []string{
+				"db.table_materialized_view_2 -> db.table_merge_tree_2",
+				"db.table_merge_tree_2 -> db.table_distributed_2",
+			},
+		},
+		{
+			name:            "upstream",
+			initialTableKey: table.Key{Database: "db", Name: "table_distributed_2"},
+			options:         []TraversalOption{WithDirection(Upstream)},
+			wantLinks: []string{
+				"db.table_merge_tree_2 -> db.table_distributed_2",
+				"db.table_materialized_view_2 -> db.table_merge_tree_2",
+				"db.table_materialized_view_3 -> db.table_merge_tree_2",
+				"db.input_null_3 -> db.table_materialized_view_3",
+				"db.input_null -> db.table_materialized_view_2",
+			},
+		},
+		{
+			name:            "component includes siblings of parents",
+			initialTableKey: table.Key{Database: "db", Name: "table_distributed_1"},
+			options:         []TraversalOption{WithDirection(Component)},
+			wantLinks: []string{
+				"db.table_merge_tree_1 -> db.table_distributed_1",
+				"db.table_materialized_view_1 -> db.table_merge_tree_1",
+				"db.input_null -> db.table_materialized_view_1",
+				"db.input_null -> db.table_materialized_view_2",
+				"db.table_materialized_view_2 -> db.table_merge_tree_2",
+				"db.table_merge_tree_2 -> db.table_distributed_2",
+				"db.table_materialized_view_3 -> db.table_merge_tree_2",
+				"db.input_null_3 -> db.table_materialized_view_3",
+			},
+		},
+		{
+			name:            "both directions with max depth",
+			initialTableKey: table.Key{Database: "db", Name: "table_merge_tree_2"},
+			options:         []TraversalOption{WithMaxDepth(1, 1)},
+			wantLinks: []string{
+				"db.table_merge_tree_2 -> db.table_distributed_2",
+				"db.table_materialized_view_2 -> db.table_merge_tree_2",
+				"db.table_materialized_view_3 -> db.table_merge_tree_2",
+			},
+		},
+		{
+			name:            "downstream with max depth",
+			initialTableKey: table.Key{Database: "db", Name: "input_null"},
+			options:         []TraversalOption{WithDirection(Downstream), WithMaxDepth(0, 2)},
+			wantLinks: []string{
+				"db.input_null -> db.table_materialized_view_1",
+				"db.input_null -> db.table_materialized_view_2",
+				"db.table_materialized_view_2 -> db.table_merge_tree_2",
+				"db.table_materialized_view_1 -> db.table_merge_tree_1",
+			},
+		},
+		{
+			name:            "component with max upstream depth",
+			initialTableKey: table.Key{Database: "db", Name: "table_merge_tree_1"},
+			options:         []TraversalOption{WithDirection(Component), WithMaxDepth(1, 0)},
+			wantLinks: []string{
+				"db.table_merge_tree_1 -> db.table_distributed_1",
+				"db.table_materialized_view_1 -> db.table_merge_tree_1",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := New()
+			for _, tableInfo := range testTables() {
+				b.AddTable(tableInfo)
+			}
+			got, err := b.TableLinks(tt.initialTableKey, tt.options...)
+			if err != nil {
+				t.Fatalf("LinksBuilder.TableLinks() error = %v", err)
+			}
+			gotLinks := make([]string, 0, len(got.Links))
+			for _, link := range got.Links {
+				gotLinks = append(gotLinks, link.FromTableKey.String()+" -> "+link.ToTableKey.String())
+			}
+			if !slices.Equal(gotLinks, tt.wantLinks) {
+				t.Errorf("LinksBuilder.TableLinks() =\n %q, \nWant =\n %q", gotLinks, tt.wantLinks)
+			}
+		})
+	}
+}
@@ -0,0 +1,371 @@
+package chsql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ObjectKind represents the kind of the object created by the CREATE statement.
+type ObjectKind int
+
+// Possible values for the [ObjectKind] type.
+const (
+	// UnknownObject is an object which is not relevant for the table graph, e.g. USER or FUNCTION.
+	UnknownObject ObjectKind = iota
+	// TableObject is a table created by the CREATE TABLE statement.
+	TableObject
+	// MaterializedViewObject is a materialized view created by the CREATE MATERIALIZED VIEW statement.
+	MaterializedViewObject
+	// ViewObject is a view created by the CREATE VIEW statement.
+	ViewObject
+	// DictionaryObject is a dictionary created by the CREATE DICTIONARY statement.
+	DictionaryObject
+	// DatabaseObject is a database created by the CREATE DATABASE statement.
+	DatabaseObject
+)
+
+// defaultDatabase is the database of the objects without database if the default database is not specified.
+const defaultDatabase = "default"
+
+// Name represents a name of a database object qualified with the database name.
+type Name struct {
+	Database string
+	Name     string
+}
+
+// CreateStatement represents a parsed CREATE statement.
+type CreateStatement struct {
+	// Kind is the kind of the created object.
+	Kind ObjectKind
+	// Name is the qualified name of the created object. Database field is empty for the [DatabaseObject].
+	Name Name
+	// To is the target table of the materialized view specified in the TO clause. Empty if not specified.
+	To Name
+	// From is the leftmost table of the FROM clause of the view select query. Empty if not specified.
+	// Inserts into this table trigger the materialized view.
+	From Name
+	// Select is the parsed select query of the view. Nil if the statement does not contain the select query.
+	Select *SelectQuery
+	// Engine is the engine name, e.g. "MergeTree", or "MaterializedView", "View" and "Dictionary" for views and dictionaries.
+	Engine string
+	// EngineFull is the engine definition with all parameters and clauses like ORDER BY and SETTINGS.
+	EngineFull string
+	// AsSelect is the select query of the view.
+	AsSelect string
+	// Query is the normalized single line statement as it is shown in the system.tables create_table_query column.
+	// Names of the created object and tables used by the statement are qualified with the database name.
+	Query string
+	// Tokens is the list of the statement tokens. Token indexes of the parsed parts, e.g. [TableExpression.Start], refer to this list.
+	Tokens []Token
+	// ToStart is the index of the TO keyword of the materialized view. It is -1 if the TO clause is not specified.
+	ToStart int
+	// ToEnd is the index of the token right after the target table name. It is -1 if the TO clause is not specified.
+	ToEnd int
+	// FromTable is the table expression of the From table. Nil if the From table is not specified.
+	FromTable *TableExpression
+}
+
+// Span returns the byte offsets of the start and the end of the specified range of the statement tokens in the source text.
+// The end index is exclusive.
+func (statement *CreateStatement) Span(start, end int) (int, int) {
+	if start < 0 || end > len(statement.Tokens) || start >= end {
+		return -1, -1
+	}
+	return statement.Tokens[start].Pos, statement.Tokens[end-1].End
+}
+
+// ParseCreate parses the specified CREATE statement tokens.
+// Table names without database are qualified with the specified default database.
+// If the default database is empty, the database of the created object is used, or "default" if it is not specified as well.
+//
+// Statements which are not CREATE statements or create objects which are not relevant for the table graph
+// are returned with the [UnknownObject] kind.
+func ParseCreate(tokens []Token, defaultDatabase string) (*CreateStatement, error) {
+	p := createParser{tokens: tokens, defaultDatabase: defaultDatabase, replace: make(map[int][]Token), skip: make(map[int]bool)}
+	return p.parse()
+}
+
+// ParseCreateQuery parses the first statement of the specified query, e.g. create_table_query column of the system.tables table.
+// Table names without database are qualified with the database of the created object.
+func ParseCreateQuery(query string) (*CreateStatement, error) {
+	statements := SplitStatements(Tokenize(query))
+	if len(statements) == 0 {
+		return nil, fmt.Errorf("ParseCreateQuery: empty query")
+	}
+	return ParseCreate(statements[0], "")
+}
+
+type createParser struct {
+	tokens          []Token
+	pos             int
+	defaultDatabase string
+	// replace contains tokens which are rendered instead of the token with the specified index.
+	replace map[int][]Token
+	// skip contains indexes of tokens which are not rendered.
+	skip map[int]bool
+}
+
+func (p *createParser) peek(offset int) Token {
+	if p.pos+offset < len(p.tokens) {
+		return p.tokens[p.pos+offset]
+	}
+	return Token{Kind: EOF}
+}
+
+// acceptKeywords moves to the next token after the specified keywords sequence if it matches the current position.
+func (p *createParser) acceptKeywords(keywords ...string) bool {
+	for i, keyword := range keywords {
+		if !p.peek(i).IsKeyword(keyword) {
+			return false
+		}
+	}
+	p.pos += len(keywords)
+	return true
+}
+
+// skipKeywords is the same as acceptKeywords, but the accepted keywords are not rendered.
+func (p *createParser) skipKeywords(keywords ...string) bool {
+	start := p.pos
+	if !p.acceptKeywords(keywords...) {
+		return false
+	}
+	for i := start; i < p.pos; i++ {
+		p.skip[i] = true
+	}
+	return true
+}
+
+func (p *createParser) parse() (*CreateStatement, error) {
+	statement := &CreateStatement{Tokens: p.tokens, ToStart: -1, ToEnd: -1}
+	if !(p.acceptKeywords("CREATE") || p.acceptKeywords("ATTACH") || p.acceptKeywords("REPLACE")) {
+		return statement, nil
+	}
+	if !p.tokens[0].IsKeyword("CREATE") {
+		p.replace[0] = []Token{{Kind: Ident, Value: "CREATE", Text: "CREATE", Pos: p.tokens[0].Pos, End: p.tokens[0].End}}
+	}
+	p.skipKeywords("OR", "REPLACE")
+	p.acceptKeywords("TEMPORARY")
+	switch {
+	case p.acceptKeywords("TABLE"):
+		statement.Kind = TableObject
+	case p.acceptKeywords("MATERIALIZED", "VIEW"):
+		statement.Kind = MaterializedViewObject
+		statement.Engine = "MaterializedView"
+	case p.acceptKeywords("VIEW"):
+		statement.Kind = ViewObject
+		statement.Engine = "View"
+	case p.acceptKeywords("DICTIONARY"):
+		statement.Kind = DictionaryObject
+		statement.Engine = "Dictionary"
+	case p.acceptKeywords("DATABASE"):
+		statement.Kind = DatabaseObject
+	default:
+		return statement, nil
+	}
+	p.skipKeywords("IF", "NOT", "EXISTS")
+
+	if statement.Kind == DatabaseObject {
+		name := p.peek(0)
+		if !name.IsIdent() {
+			return nil, p.errorf("database name expected")
+		}
+		statement.Name = Name{Name: name.Value}
+		p.pos++
+		p.skipOnCluster()
+		statement.Query = Format(p.render(0, len(p.tokens)))
+		return statement, nil
+	}
+
+	objectDatabase := p.defaultDatabase
+	if p.defaultDatabase == "" {
+		p.defaultDatabase = defaultDatabase
+	}
+	name, ok := p.qualifiedName()
+	if !ok {
+		return nil, p.errorf("object name expected")
+	}
+	statement.Name = name
+	if objectDatabase == "" {
+		p.defaultDatabase = name.Database
+	}
+	if p.peek(0).IsKeyword("UUID") && p.peek(1).Kind == String {
+		p.skip[p.pos], p.skip[p.pos+1] = true, true
+		p.pos += 2
+	}
+	p.skipOnCluster()
+
+	engineStart, engineEnd, selectStart := -1, -1, -1
+	// targetAllowed is true while the TO clause of the materialized view may follow,
+	// so TO keywords of the engine clauses, e.g. TTL ... TO DISK 'cold', are not taken as the target table.
+	targetAllowed := statement.Kind == MaterializedViewObject
+	depth := 0
+	for ; p.pos < len(p.tokens) && selectStart < 0; p.pos++ {
+		token := p.tokens[p.pos]
+		switch {
+		case token.IsPunct("(") || token.IsPunct("["):
+			depth++
+		case token.IsPunct(")") || token.IsPunct("]"):
+			depth--
+		case depth > 0:
+		case token.IsKeyword("TO") && p.peek(1).IsKeyword("INNER"):
+			targetAllowed = false
+		case token.IsKeyword("TO") && targetAllowed:
+			targetAllowed = false
+			statement.ToStart = p.pos
+			p.pos++
+			to, ok := p.qualifiedName()
+			if !ok {
+				return nil, p.errorf("target table name expected")
+			}
+			statement.To = to
+			statement.ToEnd = p.pos
+			p.pos--
+		case token.IsKeyword("POPULATE"):
+			targetAllowed = false
+		case token.IsKeyword("ENGINE") && engineStart < 0:
+			targetAllowed = false
+			engineStart = p.pos + 1
+			if p.peek(1).IsPunct("=") {
+				engineStart++
+			}
+		case token.IsKeyword("COMMENT") && engineStart >= 0 && engineEnd < 0:
+			engineEnd = p.pos
+		case token.IsKeyword("AS"):
+			targetAllowed = false
+			if engineStart >= 0 && engineEnd < 0 {
+				engineEnd = p.pos
+			}
+			if next := p.peek(1); next.IsKeyword("SELECT") || next.IsKeyword("WITH") || next.IsPunct("(") {
+				selectStart = p.pos + 1
+			}
+		}
+	}
+	if engineStart >= 0 {
+		if engineEnd < 0 {
+			engineEnd = len(p.tokens)
+		}
+		if statement.Kind == TableObject && engineStart < engineEnd {
+			statement.Engine = p.tokens[engineStart].Value
+			statement.EngineFull = Format(p.tokens[engineStart:engineEnd])
+		}
+	}
+	if selectStart >= 0 {
+		query, err := ParseSelect(p.tokens, selectStart)
+		if err != nil {
+			return nil, err
+		}
+		p.qualifySelectTables(query)
+		statement.Select = query
+		if statement.FromTable = triggerTable(query); statement.FromTable != nil {
+			statement.From = statement.FromTable.Name
+		}
+		statement.AsSelect = Format(p.render(query.Start, query.End))
+	} else if statement.Kind == MaterializedViewObject || statement.Kind == ViewObject {
+		return nil, p.errorf("AS SELECT expected")
+	}
+	statement.Query = Format(p.render(0, len(p.tokens)))
+	return statement, nil
+}
+
+// qualifiedName reads the name in format [database.]name at the current position,
+// qualifies it with the default database and moves to the next token.
+func (p *createParser) qualifiedName() (Name, bool) {
+	first := p.peek(0)
+	if !first.IsIdent() {
+		return Name{}, false
+	}
+	if p.peek(1).IsPunct(".") && p.peek(2).IsIdent() {
+		p.pos += 3
+		return Name{Database: first.Value, Name: p.peek(-1).Value}, true
+	}
+	p.replace[p.pos] = p.qualify(first)
+	p.pos++
+	return Name{Database: p.defaultDatabase, Name: first.Value}, true
+}
+
+// qualify returns tokens of the specified name token prefixed with the default database.
+// The returned tokens are positioned to be rendered without whitespaces between them.
+func (p *createParser) qualify(name Token) []Token {
+	database := QuoteIdent(p.defaultDatabase)
+	return []Token{
+		{Kind: Ident, Value: p.defaultDatabase, Text: database, Pos: name.Pos, End: name.Pos},
+		{Kind: Punct, Value: ".", Text: ".", Pos: name.Pos, End: name.Pos},
+		name,
+	}
+}
+
+// skipOnCluster skips the ON CLUSTER clause at the current position, because it is not a part of the create_table_query.
+func (p *createParser) skipOnCluster() {
+	if p.skipKeywords("ON", "CLUSTER") {
+		p.skip[p.pos] = true
+		p.pos++
+	}
+}
+
+// qualifySelectTables qualifies tables in FROM and JOIN clauses of the select query.
+// Names of common table expressions and table functions are not qualified.
+func (p *createParser) qualifySelectTables(query *SelectQuery) {
+	for _, tableExpression := range query.Tables() {
+		if tableExpression.NameStart < 0 || tableExpression.IsCTE || tableExpression.Name.Database != "" {
+			continue
+		}
+		p.replace[tableExpression.NameStart] = p.qualify(p.tokens[tableExpression.NameStart])
+		tableExpression.Name.Database = p.defaultDatabase
+	}
+}
+
+// triggerTable returns the leftmost table expression of the first FROM clause of the query.
+// If the leftmost table is a subquery, the leftmost table of the subquery is returned.
+// Inserts into this table trigger the materialized view. Nil is returned if the leftmost table is a table function.
+func triggerTable(query *SelectQuery) *TableExpression {
+	for query != nil {
+		if len(query.Branches) == 0 || len(query.Branches[0].Tables) == 0 {
+			return nil
+		}
+		first := query.Branches[0].Tables[0]
+		switch {
+		case first.IsCTE:
+			query = FindCTE(query, first.Name.Name)
+		case first.Subquery != nil:
+			query = first.Subquery
+		case first.Function != "":
+			return nil
+		default:
+			return first
+		}
+	}
+	return nil
+}
+
+// FindCTE returns the query of the common table expression with the specified name defined in the query or in its nested queries.
+func FindCTE(query *SelectQuery, name string) *SelectQuery {
+	var found *SelectQuery
+	query.walk(func(q *SelectQuery) {
+		for _, cte := range q.CTEs {
+			if cte.Name == name && found == nil {
+				found = cte.Query
+			}
+		}
+	})
+	return found
+}
+
+// render returns tokens in the specified range with applied replacements and skips.
+func (p *createParser) render(start, end int) []Token {
+	result := make([]Token, 0, end-start)
+	for i := start; i < end; i++ {
+		if p.skip[i] {
+			continue
+		}
+		if replacement, ok := p.replace[i]; ok {
+			result = append(result, replacement...)
+		} else {
+			result = append(result, p.tokens[i])
+		}
+	}
+	return result
+}
+
+func (p *createParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("ParseCreate: %s at position %d near '%s'", fmt.Sprintf(format, args...), p.peek(0).Pos, strings.TrimSpace(p.peek(0).Text))
+}
@@ -0,0 +1,66 @@
+package chsql
+
+import "strings"
+
+// SplitStatements splits the specified tokens into statements separated by semicolons.
+// The semicolons and the [EOF] token are not included into the result. Empty statements are skipped.
+func SplitStatements(tokens []Token) [][]Token {
+	statements := make([][]Token, 0)
+	start := 0
+	for i, token := range tokens {
+		if token.IsPunct(";") || token.Kind == EOF {
+			if i > start {
+				statements = append(statements, tokens[start:i])
+			}
+			start = i + 1
+		}
+	}
+	return statements
+}
+
+// Format renders the specified tokens as a single line text.
+//
+// Whitespaces and comments between tokens are replaced with a single space,
+// a space is always added after a comma and never added before a comma or a closing parenthesis and after an opening parenthesis.
+// Tokens which are written without whitespace between them in the source are rendered without whitespace as well.
+func Format(tokens []Token) string {
+	var sb strings.Builder
+	for i, token := range tokens {
+		if token.Kind == EOF {
+			break
+		}
+		if i > 0 && needSpace(tokens[i-1], token) {
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(token.Text)
+	}
+	return sb.String()
+}
+
+func needSpace(prev, next Token) bool {
+	switch {
+	case next.IsPunct(",") || next.IsPunct(")") || next.IsPunct(";"):
+		return false
+	case prev.IsPunct("("):
+		return false
+	case prev.IsPunct(","):
+		return true
+	default:
+		return next.Pos > prev.End
+	}
+}
+
+// QuoteIdent returns the specified identifier quoted with backticks if it can not be written as a bare identifier.
+func QuoteIdent(ident string) string {
+	if ident == "" {
+		return "``"
+	}
+	bare := isIdentStart(ident[0])
+	for i := 0; i < len(ident) && bare; i++ {
+		bare = isIdentChar(ident[i]) && ident[i] != '$'
+	}
+	if bare {
+		return ident
+	}
+	return "`" + strings.NewReplacer("\\", "\\\\", "`", "\\`").Replace(ident) + "`"
+}
@@ -0,0 +1,187 @@
+// Package chsql provides a lexer for the subset of ClickHouse SQL used in DDL statements and create queries.
+package chsql
+
+import (
+	"strings"
+)
+
+// TokenKind represents the kind of the [Token].
+type TokenKind int
+
+// Possible values for the [TokenKind] type.
+const (
+	// EOF is the end of the input.
+	EOF TokenKind = iota
+	// Ident is a bare identifier or a keyword, e.g. SELECT or my_table.
+	Ident
+	// QuotedIdent is an identifier quoted with backticks or double quotes, e.g. `my table`.
+	QuotedIdent
+	// String is a string literal quoted with single quotes, e.g. 'cluster'.
+	String
+	// Number is a numeric literal, e.g. 42 or 0.5.
+	Number
+	// Punct is an operator or a punctuation character, e.g. "(", ",", ";" or "=".
+	Punct
+)
+
+// Token represents a single lexical token of the SQL text.
+type Token struct {
+	// Kind is the kind of the token.
+	Kind TokenKind
+	// Value is the token value. Quotes are removed and escape sequences are resolved for quoted identifiers and strings.
+	Value string
+	// Text is the raw token text as it is written in the source.
+	Text string
+	// Pos is the byte offset of the token start in the source.
+	Pos int
+	// End is the byte offset right after the token end in the source.
+	End int
+}
+
+// IsKeyword reports whether the token is a bare identifier equal to the specified keyword, ignoring case.
+func (t Token) IsKeyword(keyword string) bool {
+	return t.Kind == Ident && strings.EqualFold(t.Value, keyword)
+}
+
+// IsPunct reports whether the token is the specified punctuation.
+func (t Token) IsPunct(punct string) bool {
+	return t.Kind == Punct && t.Value == punct
+}
+
+// IsIdent reports whether the token is a bare or quoted identifier.
+func (t Token) IsIdent() bool {
+	return t.Kind == Ident || t.Kind == QuotedIdent
+}
+
+// multiCharPunct is a list of operators which consist of more than one character.
+var multiCharPunct = []string{"::", "->", "<=", ">=", "<>", "!=", "==", "||"}
+
+// Tokenize splits the specified SQL text into tokens. Whitespaces and comments are skipped.
+// The result always ends with the [EOF] token.
+func Tokenize(sql string) []Token {
+	tokens := make([]Token, 0, len(sql)/4)
+	pos := 0
+	for pos < len(sql) {
+		c := sql[pos]
+		switch {
+		case isSpace(c):
+			pos++
+		case strings.HasPrefix(sql[pos:], "--") || c == '#':
+			end := strings.IndexByte(sql[pos:], '\n')
+			if end < 0 {
+				end = len(sql)
+			} else {
+				end += pos
+			}
+			pos = end
+		case strings.HasPrefix(sql[pos:], "/*"):
+			end := strings.Index(sql[pos+2:], "*/")
+			if end < 0 {
+				end = len(sql)
+			} else {
+				end += pos + 4
+			}
+			pos = end
+		case c == '\'':
+			end, value := readQuoted(sql, pos, '\'')
+			tokens = append(tokens, Token{Kind: String, Value: value, Text: sql[pos:end], Pos: pos, End: end})
+			pos = end
+		case c == '`' || c == '"':
+			end, value := readQuoted(sql, pos, c)
+			tokens = append(tokens, Token{Kind: QuotedIdent, Value: value, Text: sql[pos:end], Pos: pos, End: end})
+			pos = end
+		case isDigit(c) || (c == '.' && pos+1 < len(sql) && isDigit(sql[pos+1]) && !afterIdent(tokens, pos)):
+			end := pos
+			for end < len(sql) && (isIdentChar(sql[end]) || sql[end] == '.' ||
+				((sql[end] == '+' || sql[end] == '-') && (sql[end-1] == 'e' || sql[end-1] == 'E'))) {
+				end++
+			}
+			tokens = append(tokens, Token{Kind: Number, Value: sql[pos:end], Text: sql[pos:end], Pos: pos, End: end})
+			pos = end
+		case isIdentStart(c):
+			end := pos
+			for end < len(sql) && isIdentChar(sql[end]) {
+				end++
+			}
+			tokens = append(tokens, Token{Kind: Ident, Value: sql[pos:end], Text: sql[pos:end], Pos: pos, End: end})
+			pos = end
+		default:
+			end := pos + 1
+			for _, p := range multiCharPunct {
+				if strings.HasPrefix(sql[pos:], p) {
+					end = pos + len(p)
+					break
+				}
+			}
+			tokens = append(tokens, Token{Kind: Punct, Value: sql[pos:end], Text: sql[pos:end], Pos: pos, End: end})
+			pos = end
+		}
+	}
+	tokens = append(tokens, Token{Kind: EOF, Pos: len(sql), End: len(sql)})
+	return tokens
+}
+
+// readQuoted reads the quoted token started at the specified position and returns the position after the closing quote and unquoted value.
+// Both backslash escapes and doubled quotes are supported.
+func readQuoted(sql string, start int, quote byte) (int, string) {
+	var value strings.Builder
+	pos := start + 1
+	for pos < len(sql) {
+		c := sql[pos]
+		switch {
+		case c == '\\' && pos+1 < len(sql):
+			value.WriteByte(unescape(sql[pos+1]))
+			pos += 2
+		case c == quote && pos+1 < len(sql) && sql[pos+1] == quote:
+			value.WriteByte(quote)
+			pos += 2
+		case c == quote:
+			return pos + 1, value.String()
+		default:
+			value.WriteByte(c)
+			pos++
+		}
+	}
+	return pos, value.String()
+}
+
+func unescape(c byte) byte {
+	switch c {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	case '0':
+		return 0
+	default:
+		return c
+	}
+}
+
+// afterIdent reports whether the previous token is an identifier directly followed by the specified position,
+// so the dot at the position is a qualifier separator and not a start of a number, e.g. t.1 in tuple access.
+func afterIdent(tokens []Token, pos int) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+	last := tokens[len(tokens)-1]
+	return last.End == pos && (last.IsIdent() || last.IsPunct(")"))
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c >= 0x80
+}
+
+func isIdentChar(c byte) bool {
+	return isIdentStart(c) || isDigit(c) || c == '$'
+}
@@ -0,0 +1,101 @@
+package chsql
+
+import (
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want []Token
+	}{
+		{
+			name: "identifiers, strings and numbers",
+			sql:  "SELECT `my col`, \"db\".t, 'it''s', 'a\\'b', 1.5e-3 FROM t",
+			want: []Token{
+				{Kind: Ident, Value: "SELECT"},
+				{Kind: QuotedIdent, Value: "my col"},
+				{Kind: Punct, Value: ","},
+				{Kind: QuotedIdent, Value: "db"},
+				{Kind: Punct, Value: "."},
+				{Kind: Ident, Value: "t"},
+				{Kind: Punct, Value: ","},
+				{Kind: String, Value: "it's"},
+				{Kind: Punct, Value: ","},
+				{Kind: String, Value: "a'b"},
+				{Kind: Punct, Value: ","},
+				{Kind: Number, Value: "1.5e-3"},
+				{Kind: Ident, Value: "FROM"},
+				{Kind: Ident, Value: "t"},
+				{Kind: EOF},
+			},
+		},
+		{
+			name: "comments and operators",
+			sql:  "a -- comment\n/* multi\nline */ >= b::String # comment",
+			want: []Token{
+				{Kind: Ident, Value: "a"},
+				{Kind: Punct, Value: ">="},
+				{Kind: Ident, Value: "b"},
+				{Kind: Punct, Value: "::"},
+				{Kind: Ident, Value: "String"},
+				{Kind: EOF},
+			},
+		},
+		{
+			name: "tuple element access",
+			sql:  "t.1",
+			want: []Token{
+				{Kind: Ident, Value: "t"},
+				{Kind: Punct, Value: "."},
+				{Kind: Number, Value: "1"},
+				{Kind: EOF},
+			},
+		},
+		{
+			name: "empty string",
+			sql:  "",
+			want: []Token{{Kind: EOF}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Tokenize(tt.sql)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Tokenize() returned %d tokens, want %d: %v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				if got[i].Kind != tt.want[i].Kind || got[i].Value != tt.want[i].Value {
+					t.Errorf("Tokenize() token #%d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		want string
+	}{
+		{
+			name: "whitespaces and comments",
+			sql:  "CREATE TABLE  db.t\n(\n  id Int64, -- id\n  name String\n)\nENGINE = MergeTree()",
+			want: "CREATE TABLE db.t (id Int64, name String) ENGINE = MergeTree()",
+		},
+		{
+			name: "commas",
+			sql:  "Distributed('c','db' ,'t')",
+			want: "Distributed('c', 'db', 't')",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Format(Tokenize(tt.sql)); got != tt.want {
+				t.Errorf("Format() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
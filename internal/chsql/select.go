@@ -0,0 +1,560 @@
+package chsql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SelectQuery represents a parsed SELECT query with optional WITH clause and UNION, EXCEPT or INTERSECT branches.
+//
+// Only the parts which are relevant for the table dependencies are parsed:
+// tables in FROM and JOIN clauses, common table expressions, subqueries and function calls.
+type SelectQuery struct {
+	// CTEs is a list of common table expressions defined in the WITH clause, e.g. WITH name AS (SELECT ...).
+	CTEs []CommonTableExpression
+	// Branches is a list of SELECT queries combined with UNION, EXCEPT or INTERSECT. It contains at least one item.
+	Branches []*SelectBranch
+	// Subqueries is a list of subqueries used in expressions, e.g. WHERE id IN (SELECT id FROM t).
+	Subqueries []*SelectQuery
+	// InTables is a list of tables used as the right operand of the IN operator, e.g. WHERE id IN db.ids.
+	// Tables in subqueries are not included.
+	InTables []*TableExpression
+	// Functions is a list of function calls used in expressions of the query. Calls in subqueries are not included.
+	Functions []FunctionCall
+	// Start is the index of the first token of the query.
+	Start int
+	// End is the index of the token right after the query.
+	End int
+}
+
+// CommonTableExpression represents a named subquery defined in the WITH clause.
+type CommonTableExpression struct {
+	// Name is the name of the common table expression.
+	Name string
+	// Query is the subquery of the common table expression.
+	Query *SelectQuery
+}
+
+// SelectBranch represents a single SELECT of the query.
+type SelectBranch struct {
+	// Tables is a list of tables in the FROM clause: the first table and all joined tables in the order of appearance.
+	Tables []*TableExpression
+}
+
+// TableExpression represents a table in the FROM or JOIN clause.
+// Only one of the Name, Subquery and Function fields is set.
+type TableExpression struct {
+	// Name is the table name. The Database field is empty if the name is not qualified.
+	Name Name
+	// Subquery is the subquery used as a table, e.g. FROM (SELECT ...).
+	Subquery *SelectQuery
+	// Function is the name of the table function, e.g. numbers or remote.
+	Function string
+	// Alias is the table alias. Empty if not specified.
+	Alias string
+	// IsJoin is true if the table is joined with JOIN clause or comma.
+	IsJoin bool
+	// IsCTE is true if the Name refers to the common table expression instead of a table.
+	IsCTE bool
+	// NameStart is the index of the first token of the table name. It is -1 for subqueries and table functions.
+	NameStart int
+	// NameEnd is the index of the token right after the table name. It is -1 for subqueries and table functions.
+	NameEnd int
+	// Start is the index of the first token of the table expression.
+	Start int
+	// End is the index of the token right after the table expression.
+	End int
+}
+
+// FunctionCall represents a function call in the expression.
+type FunctionCall struct {
+	// Name is the function name as it is written in the query.
+	Name string
+	// Arguments is a list of function arguments. Each argument is a list of its tokens.
+	Arguments [][]Token
+	// Start is the index of the function name token.
+	Start int
+	// End is the index of the token right after the closing parenthesis.
+	End int
+}
+
+// StringArgument returns the value of the argument with the specified index if the argument is a string literal.
+func (call FunctionCall) StringArgument(index int) (string, bool) {
+	if index >= len(call.Arguments) || len(call.Arguments[index]) != 1 || call.Arguments[index][0].Kind != String {
+		return "", false
+	}
+	return call.Arguments[index][0].Value, true
+}
+
+// ParseSelect parses the SELECT query started at the specified token index.
+// The query ends at the end of the tokens, at a semicolon or at the unmatched closing parenthesis.
+func ParseSelect(tokens []Token, start int) (*SelectQuery, error) {
+	p := selectParser{tokens: tokens, pos: start}
+	query, err := p.parseQuery()
+	if err != nil {
+		return nil, err
+	}
+	return query, nil
+}
+
+// Tables returns all table expressions of the query including tables of subqueries, common table expressions
+// and tables of the IN operator.
+func (query *SelectQuery) Tables() []*TableExpression {
+	tables := make([]*TableExpression, 0)
+	query.walk(func(q *SelectQuery) {
+		for _, branch := range q.Branches {
+			tables = append(tables, branch.Tables...)
+		}
+		tables = append(tables, q.InTables...)
+	})
+	return tables
+}
+
+// AllFunctions returns all function calls of the query including calls in subqueries and common table expressions.
+func (query *SelectQuery) AllFunctions() []FunctionCall {
+	functions := make([]FunctionCall, 0)
+	query.walk(func(q *SelectQuery) {
+		functions = append(functions, q.Functions...)
+	})
+	return functions
+}
+
+// walk calls the specified function for the query and all nested queries in order of their appearance.
+func (query *SelectQuery) walk(visit func(q *SelectQuery)) {
+	visit(query)
+	for _, cte := range query.CTEs {
+		cte.Query.walk(visit)
+	}
+	for _, branch := range query.Branches {
+		for _, tableExpression := range branch.Tables {
+			if tableExpression.Subquery != nil {
+				tableExpression.Subquery.walk(visit)
+			}
+		}
+	}
+	for _, subquery := range query.Subqueries {
+		subquery.walk(visit)
+	}
+}
+
+// clauseKeywords is a list of keywords which start a new clause of the SELECT query.
+var clauseKeywords = map[string]bool{
+	"FROM": true, "WHERE": true, "PREWHERE": true, "GROUP": true, "HAVING": true, "ORDER": true, "LIMIT": true,
+	"OFFSET": true, "SETTINGS": true, "WINDOW": true, "QUALIFY": true, "FORMAT": true, "INTO": true,
+	"UNION": true, "INTERSECT": true, "EXCEPT": true,
+}
+
+// joinKeywords is a list of keywords which can be used before the JOIN keyword.
+var joinKeywords = map[string]bool{
+	"GLOBAL": true, "LOCAL": true, "ANY": true, "ALL": true, "ASOF": true, "SEMI": true, "ANTI": true,
+	"LEFT": true, "RIGHT": true, "FULL": true, "INNER": true, "CROSS": true, "OUTER": true, "PASTE": true, "ARRAY": true,
+}
+
+// operatorKeywords is a list of keywords which can be followed by parentheses in expressions, but are not functions.
+var operatorKeywords = map[string]bool{
+	"AND": true, "OR": true, "NOT": true, "IN": true, "AS": true, "IS": true, "LIKE": true, "ILIKE": true, "BETWEEN": true,
+	"CASE": true, "WHEN": true, "THEN": true, "ELSE": true, "BY": true, "ON": true, "USING": true, "DISTINCT": true,
+	"WHERE": true, "PREWHERE": true, "HAVING": true, "SELECT": true, "EXCEPT": true, "REPLACE": true, "APPLY": true,
+}
+
+// tableKeywords is a list of keywords which can follow the table name, so they can not be used as a table alias.
+var tableKeywords = map[string]bool{
+	"FINAL": true, "SAMPLE": true, "ON": true, "USING": true, "JOIN": true,
+}
+
+type selectParser struct {
+	tokens []Token
+	pos    int
+	// cteScopes is a stack of common table expression names visible in the current query.
+	cteScopes []map[string]bool
+}
+
+func (p *selectParser) peek(offset int) Token {
+	if p.pos+offset >= 0 && p.pos+offset < len(p.tokens) {
+		return p.tokens[p.pos+offset]
+	}
+	return Token{Kind: EOF}
+}
+
+// isQueryEnd reports whether the current token ends the query.
+func (p *selectParser) isQueryEnd() bool {
+	token := p.peek(0)
+	return token.Kind == EOF || token.IsPunct(";") || token.IsPunct(")")
+}
+
+// isSubqueryStart reports whether the token with the specified offset starts a query.
+func (p *selectParser) isSubqueryStart(offset int) bool {
+	return p.peek(offset).IsKeyword("SELECT") || p.peek(offset).IsKeyword("WITH") ||
+		(p.peek(offset).IsPunct("(") && p.isSubqueryStart(offset+1))
+}
+
+// isClauseStart reports whether the current token starts the next clause of the query.
+func (p *selectParser) isClauseStart() bool {
+	token := p.peek(0)
+	if token.Kind != Ident || !clauseKeywords[strings.ToUpper(token.Value)] {
+		return false
+	}
+	switch strings.ToUpper(token.Value) {
+	case "EXCEPT":
+		// SELECT * EXCEPT (column) is a column transformer, not a set operation
+		return p.isSubqueryStart(1) || p.peek(1).IsKeyword("ALL") || p.peek(1).IsKeyword("DISTINCT")
+	case "FORMAT":
+		return !p.peek(1).IsPunct("(")
+	}
+	return true
+}
+
+// joinStart returns the number of tokens of the JOIN keyword with all its modifiers at the current position, or 0 if there is no JOIN.
+func (p *selectParser) joinStart() int {
+	for i := 0; ; i++ {
+		token := p.peek(i)
+		if token.IsKeyword("JOIN") {
+			return i + 1
+		}
+		if token.Kind != Ident || !joinKeywords[strings.ToUpper(token.Value)] {
+			return 0
+		}
+	}
+}
+
+func (p *selectParser) expectPunct(punct string) error {
+	if !p.peek(0).IsPunct(punct) {
+		return p.errorf("'%s' expected", punct)
+	}
+	p.pos++
+	return nil
+}
+
+func (p *selectParser) isCTE(name string) bool {
+	for _, scope := range p.cteScopes {
+		if scope[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// parseQuery parses the query with optional WITH clause and all UNION branches.
+func (p *selectParser) parseQuery() (*SelectQuery, error) {
+	query := &SelectQuery{Start: p.pos}
+	p.cteScopes = append(p.cteScopes, make(map[string]bool))
+	defer func() { p.cteScopes = p.cteScopes[:len(p.cteScopes)-1] }()
+
+	if p.peek(0).IsKeyword("WITH") {
+		p.pos++
+		if p.peek(0).IsKeyword("RECURSIVE") {
+			p.pos++
+		}
+		if err := p.parseWith(query); err != nil {
+			return nil, err
+		}
+	}
+	for {
+		if p.peek(0).IsPunct("(") {
+			p.pos++
+			nested, err := p.parseQuery()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct(")"); err != nil {
+				return nil, err
+			}
+			query.CTEs = append(query.CTEs, nested.CTEs...)
+			query.Branches = append(query.Branches, nested.Branches...)
+			query.Subqueries = append(query.Subqueries, nested.Subqueries...)
+			query.InTables = append(query.InTables, nested.InTables...)
+			query.Functions = append(query.Functions, nested.Functions...)
+		} else {
+			branch, err := p.parseBranch(query)
+			if err != nil {
+				return nil, err
+			}
+			query.Branches = append(query.Branches, branch)
+		}
+		if !(p.peek(0).IsKeyword("UNION") || p.peek(0).IsKeyword("EXCEPT") || p.peek(0).IsKeyword("INTERSECT")) {
+			break
+		}
+		p.pos++
+		if p.peek(0).IsKeyword("ALL") || p.peek(0).IsKeyword("DISTINCT") {
+			p.pos++
+		}
+	}
+	query.End = p.pos
+	return query, nil
+}
+
+// parseWith parses the list of common table expressions and named expressions of the WITH clause.
+func (p *selectParser) parseWith(query *SelectQuery) error {
+	for {
+		if p.peek(0).IsIdent() && p.peek(1).IsKeyword("AS") && p.peek(2).IsPunct("(") && p.isSubqueryStart(3) {
+			name := p.peek(0).Value
+			p.pos += 3
+			cteQuery, err := p.parseQuery()
+			if err != nil {
+				return err
+			}
+			if err := p.expectPunct(")"); err != nil {
+				return err
+			}
+			query.CTEs = append(query.CTEs, CommonTableExpression{Name: name, Query: cteQuery})
+			p.cteScopes[len(p.cteScopes)-1][name] = true
+		} else {
+			err := p.scanExpression(query, func() bool {
+				return p.peek(0).IsPunct(",") || p.peek(0).IsKeyword("SELECT")
+			})
+			if err != nil {
+				return err
+			}
+		}
+		if !p.peek(0).IsPunct(",") {
+			return nil
+		}
+		p.pos++
+	}
+}
+
+// parseBranch parses the single SELECT query with all its clauses.
+func (p *selectParser) parseBranch(query *SelectQuery) (*SelectBranch, error) {
+	if !p.peek(0).IsKeyword("SELECT") {
+		return nil, p.errorf("SELECT expected")
+	}
+	p.pos++
+	branch := &SelectBranch{Tables: make([]*TableExpression, 0)}
+	for !p.isQueryEnd() {
+		switch {
+		case p.peek(0).IsKeyword("FROM"):
+			p.pos++
+			if err := p.parseTables(query, branch); err != nil {
+				return nil, err
+			}
+		case p.peek(0).IsKeyword("UNION") || p.peek(0).IsKeyword("INTERSECT") || (p.peek(0).IsKeyword("EXCEPT") && p.isClauseStart()):
+			return branch, nil
+		default:
+			start := p.pos
+			if p.isClauseStart() {
+				p.pos++
+			}
+			err := p.scanExpression(query, p.isClauseStart)
+			if err != nil {
+				return nil, err
+			}
+			if p.pos == start {
+				return nil, p.errorf("unexpected token")
+			}
+		}
+	}
+	return branch, nil
+}
+
+// parseTables parses the list of tables of the FROM clause with all joined tables.
+func (p *selectParser) parseTables(query *SelectQuery, branch *SelectBranch) error {
+	first, err := p.parseTableExpression(query)
+	if err != nil {
+		return err
+	}
+	branch.Tables = append(branch.Tables, first)
+	for {
+		isTableEnd := func() bool { return p.isClauseStart() || p.joinStart() > 0 || p.peek(0).IsPunct(",") }
+		switch {
+		case p.peek(0).IsPunct(","):
+			p.pos++
+			joined, err := p.parseTableExpression(query)
+			if err != nil {
+				return err
+			}
+			joined.IsJoin = true
+			branch.Tables = append(branch.Tables, joined)
+		case p.joinStart() > 0:
+			joinLength := p.joinStart()
+			isArrayJoin, isCrossJoin := false, false
+			for i := 0; i < joinLength; i++ {
+				isArrayJoin = isArrayJoin || p.peek(i).IsKeyword("ARRAY")
+				isCrossJoin = isCrossJoin || p.peek(i).IsKeyword("CROSS") || p.peek(i).IsKeyword("PASTE")
+			}
+			p.pos += joinLength
+			if isArrayJoin {
+				// ARRAY JOIN is followed by array expressions, not tables
+				if err := p.scanExpression(query, func() bool { return p.isClauseStart() || p.joinStart() > 0 }); err != nil {
+					return err
+				}
+				continue
+			}
+			joined, err := p.parseTableExpression(query)
+			if err != nil {
+				return err
+			}
+			joined.IsJoin = true
+			branch.Tables = append(branch.Tables, joined)
+			switch {
+			case p.peek(0).IsKeyword("ON") || p.peek(0).IsKeyword("USING"):
+				p.pos++
+				if err := p.scanExpression(query, isTableEnd); err != nil {
+					return err
+				}
+			case !isCrossJoin:
+				return p.errorf("ON or USING expected")
+			}
+		default:
+			return nil
+		}
+	}
+}
+
+// parseTableExpression parses the table name, subquery or table function with alias and modifiers.
+func (p *selectParser) parseTableExpression(query *SelectQuery) (*TableExpression, error) {
+	tableExpression := &TableExpression{NameStart: -1, NameEnd: -1, Start: p.pos}
+	token := p.peek(0)
+	switch {
+	case token.IsPunct("(") && p.isSubqueryStart(1):
+		p.pos++
+		subquery, err := p.parseQuery()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(")"); err != nil {
+			return nil, err
+		}
+		tableExpression.Subquery = subquery
+	case token.IsIdent() && p.peek(1).IsPunct("("):
+		tableExpression.Function = token.Value
+		p.pos++
+		if err := p.scanParentheses(query, nil); err != nil {
+			return nil, err
+		}
+	case token.IsIdent():
+		tableExpression.NameStart = p.pos
+		if p.peek(1).IsPunct(".") && p.peek(2).IsIdent() {
+			tableExpression.Name = Name{Database: token.Value, Name: p.peek(2).Value}
+			p.pos += 3
+		} else {
+			tableExpression.Name = Name{Name: token.Value}
+			tableExpression.IsCTE = p.isCTE(token.Value)
+			p.pos++
+		}
+		tableExpression.NameEnd = p.pos
+	default:
+		return nil, p.errorf("table expected")
+	}
+
+	if p.peek(0).IsKeyword("AS") && p.peek(1).IsIdent() {
+		tableExpression.Alias = p.peek(1).Value
+		p.pos += 2
+	} else if token := p.peek(0); token.Kind == QuotedIdent ||
+		(token.Kind == Ident && !p.isClauseStart() && p.joinStart() == 0 && !tableKeywords[strings.ToUpper(token.Value)]) {
+		tableExpression.Alias = token.Value
+		p.pos++
+	}
+	if p.peek(0).IsKeyword("FINAL") {
+		p.pos++
+	}
+	if p.peek(0).IsKeyword("SAMPLE") {
+		p.pos++
+		err := p.scanExpression(query, func() bool {
+			return p.isClauseStart() || p.joinStart() > 0 || p.peek(0).IsPunct(",") || p.peek(0).IsKeyword("ON") || p.peek(0).IsKeyword("USING")
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	tableExpression.End = p.pos
+	return tableExpression, nil
+}
+
+// scanExpression skips the expression tokens until the stop function returns true or the query ends.
+// Subqueries and function calls found in the expression are added to the specified query.
+func (p *selectParser) scanExpression(query *SelectQuery, stop func() bool) error {
+	for !p.isQueryEnd() && !p.peek(0).IsPunct("]") && !stop() {
+		token := p.peek(0)
+		switch {
+		case token.IsPunct("(") && p.isSubqueryStart(1):
+			p.pos++
+			subquery, err := p.parseQuery()
+			if err != nil {
+				return err
+			}
+			if err := p.expectPunct(")"); err != nil {
+				return err
+			}
+			query.Subqueries = append(query.Subqueries, subquery)
+		case token.IsPunct("(") && p.peek(-1).Kind == Ident && !operatorKeywords[strings.ToUpper(p.peek(-1).Value)]:
+			index := len(query.Functions)
+			query.Functions = append(query.Functions, FunctionCall{Name: p.peek(-1).Value, Start: p.pos - 1})
+			var call FunctionCall
+			if err := p.scanParentheses(query, &call); err != nil {
+				return err
+			}
+			query.Functions[index].Arguments = call.Arguments
+			query.Functions[index].End = p.pos
+		case token.IsPunct("(") || token.IsPunct("["):
+			if err := p.scanParentheses(query, nil); err != nil {
+				return err
+			}
+		case p.isInTable():
+			p.pos++
+			query.InTables = append(query.InTables, p.parseInTable())
+		default:
+			p.pos++
+		}
+	}
+	return nil
+}
+
+// isInTable reports whether the current token is the IN operator followed by the table name, e.g. IN db.ids,
+// and not by the tuple, the subquery or the function call.
+func (p *selectParser) isInTable() bool {
+	if !p.peek(0).IsKeyword("IN") || !p.peek(1).IsIdent() {
+		return false
+	}
+	if p.peek(2).IsPunct(".") {
+		return p.peek(3).IsIdent() && !p.peek(4).IsPunct("(")
+	}
+	return !p.peek(2).IsPunct("(")
+}
+
+// parseInTable parses the table name of the IN operator at the current position.
+func (p *selectParser) parseInTable() *TableExpression {
+	tableExpression := &TableExpression{NameStart: p.pos, Start: p.pos}
+	token := p.peek(0)
+	if p.peek(1).IsPunct(".") {
+		tableExpression.Name = Name{Database: token.Value, Name: p.peek(2).Value}
+		p.pos += 3
+	} else {
+		tableExpression.Name = Name{Name: token.Value}
+		tableExpression.IsCTE = p.isCTE(token.Value)
+		p.pos++
+	}
+	tableExpression.NameEnd, tableExpression.End = p.pos, p.pos
+	return tableExpression
+}
+
+// scanParentheses skips tokens in parentheses or brackets at the current position.
+// If the call is specified, the arguments separated by commas are added to it.
+func (p *selectParser) scanParentheses(query *SelectQuery, call *FunctionCall) error {
+	closing := ")"
+	if p.peek(0).IsPunct("[") {
+		closing = "]"
+	}
+	p.pos++
+	for {
+		start := p.pos
+		if err := p.scanExpression(query, func() bool { return p.peek(0).IsPunct(",") }); err != nil {
+			return err
+		}
+		if call != nil && p.pos > start {
+			call.Arguments = append(call.Arguments, p.tokens[start:p.pos])
+		}
+		switch {
+		case p.peek(0).IsPunct(","):
+			p.pos++
+		case p.peek(0).IsPunct(closing):
+			p.pos++
+			return nil
+		default:
+			return p.errorf("'%s' expected", closing)
+		}
+	}
+}
+
+func (p *selectParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("ParseSelect: %s at position %d near '%s'", fmt.Sprintf(format, args...), p.peek(0).Pos, p.peek(0).Text)
+}
@@ -0,0 +1,115 @@
+package chsql
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSelect(t *testing.T) {
+	tests := []struct {
+		name          string
+		sql           string
+		wantTables    []string
+		wantFunctions []string
+		wantErr       bool
+	}{
+		{
+			name:          "from and joins",
+			sql:           "SELECT a.id, count() FROM db.a AS a FINAL LEFT JOIN b USING (id) GLOBAL ANY INNER JOIN `c` c ON a.id = c.id, d WHERE a.id > 0 GROUP BY a.id",
+			wantTables:    []string{"db.a", "+b", "+c", "+d"},
+			wantFunctions: []string{"count"},
+		},
+		{
+			name:          "subqueries and union",
+			sql:           "SELECT * FROM (SELECT id FROM a UNION ALL SELECT id FROM b) WHERE id IN (SELECT id FROM c) UNION DISTINCT (SELECT id FROM d)",
+			wantTables:    []string{"(subquery)", "d", "a", "b", "c"},
+			wantFunctions: []string{},
+		},
+		{
+			name:          "common table expressions",
+			sql:           "WITH x AS (SELECT id FROM a), 10 AS limit_value SELECT * FROM x JOIN x AS y USING (id) LIMIT limit_value",
+			wantTables:    []string{"cte:x", "+cte:x", "a"},
+			wantFunctions: []string{},
+		},
+		{
+			name:          "keywords in expressions",
+			sql:           "SELECT extract(DAY FROM date), trim(BOTH ' ' FROM name), * EXCEPT (id), CASE WHEN x IN (1, 2) THEN 1 ELSE 0 END FROM a ARRAY JOIN arr SAMPLE 0.1",
+			wantTables:    []string{"a"},
+			wantFunctions: []string{"extract", "trim"},
+		},
+		{
+			name:          "tables of IN operator",
+			sql:           "WITH ids AS (SELECT id FROM a) SELECT * FROM b WHERE id IN db.c AND id GLOBAL NOT IN d AND id IN ids AND id IN (1, 2) AND id IN tuple(1, 2)",
+			wantTables:    []string{"b", "db.c", "d", "cte:ids", "a"},
+			wantFunctions: []string{"tuple"},
+		},
+		{
+			name:          "table function",
+			sql:           "SELECT * FROM numbers(10) JOIN remote('host', db, t) USING (number)",
+			wantTables:    []string{"numbers()", "+remote()"},
+			wantFunctions: []string{},
+		},
+		{
+			name:    "join without condition",
+			sql:     "SELECT * FROM a JOIN b",
+			wantErr: true,
+		},
+		{
+			name:    "unbalanced parentheses",
+			sql:     "SELECT count( FROM a",
+			wantErr: true,
+		},
+		{
+			name:    "not a select",
+			sql:     "INSERT INTO a VALUES (1)",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := ParseSelect(Tokenize(tt.sql), 0)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseSelect() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			gotTables := make([]string, 0)
+			for _, tableExpression := range query.Tables() {
+				gotTables = append(gotTables, describe(tableExpression))
+			}
+			if !slices.Equal(gotTables, tt.wantTables) {
+				t.Errorf("ParseSelect() tables = %v, want %v", gotTables, tt.wantTables)
+			}
+			gotFunctions := make([]string, 0)
+			for _, call := range query.AllFunctions() {
+				gotFunctions = append(gotFunctions, call.Name)
+			}
+			if !slices.Equal(gotFunctions, tt.wantFunctions) {
+				t.Errorf("ParseSelect() functions = %v, want %v", gotFunctions, tt.wantFunctions)
+			}
+		})
+	}
+}
+
+// describe returns a short description of the table expression: "+" prefix for joined tables,
+// "cte:" prefix for common table expressions, "()" suffix for table functions and "(subquery)" for subqueries.
+func describe(tableExpression *TableExpression) string {
+	var description string
+	switch {
+	case tableExpression.Subquery != nil:
+		description = "(subquery)"
+	case tableExpression.Function != "":
+		description = tableExpression.Function + "()"
+	case tableExpression.IsCTE:
+		description = "cte:" + tableExpression.Name.Name
+	case tableExpression.Name.Database != "":
+		description = tableExpression.Name.Database + "." + tableExpression.Name.Name
+	default:
+		description = tableExpression.Name.Name
+	}
+	if tableExpression.IsJoin {
+		description = "+" + description
+	}
+	return description
+}
@@ -2,101 +2,294 @@
 package deps
 
 import (
+	"github.com/mbaksheev/clickhouse-table-graph/internal/chsql"
 	"github.com/mbaksheev/clickhouse-table-graph/table"
 	"regexp"
 	"strings"
 )
 
-var (
-	// distributedTableExtractorRegex is a regex to extract links from Distributed engine definition.
-	distributedTableExtractorRegex = regexp.MustCompile(`Distributed\('.*?', '(.*?)', '(.*?)'.*?\)`)
-	// materializedViewExtractorRegex is a regex to extract links from MaterializedView create query.
-	materializedViewExtractorRegex = regexp.MustCompile(`CREATE MATERIALIZED VIEW .*? TO (\S+)\.(\S+) .*?`)
-	// materializedViewJoinedTablesExtractorRegex is a regex to extract joined tables from MaterializedView create query.
-	materializedViewJoinedTablesExtractorRegex = regexp.MustCompile(`JOIN\s+(\S+)\.(\S+)\s.*?`)
-	// materializedViewDictionariesExtractorRegex is a regex to extract dictionaries from MaterializedView create query.
-	materializedViewDictionariesExtractorRegex = regexp.MustCompile(`dict[A-Z]\w*\('([^']+)',\s*?`)
+// dictionaryFunctionRegex is a regex to match names of dictionary functions, e.g. dictGet or dictHas.
+var dictionaryFunctionRegex = regexp.MustCompile(`^dict[A-Z]\w*$`)
+
+// SourceKind represents the kind of the table read by the materialized view.
+type SourceKind int
+
+// Possible values for the [SourceKind] type.
+const (
+	// TriggerSource is the leftmost table of the FROM clause. Inserts into this table trigger the materialized view.
+	TriggerSource SourceKind = iota
+	// SecondarySource is any other table read by the materialized view: joined tables,
+	// tables of subqueries, common table expressions, UNION branches and tables used in joinGet function.
+	SecondarySource
 )
 
+// Dependency represents a table found in the table metadata together with the position of the evidence.
+type Dependency struct {
+	// Key is the key of the found table.
+	Key table.Key
+	// Start is the byte offset of the evidence start in the parsed text. It is -1 if the evidence is not a part of the text.
+	Start int
+	// End is the byte offset right after the evidence end in the parsed text. It is -1 if the evidence is not a part of the text.
+	End int
+}
+
+// Source represents a table read by the materialized view.
+type Source struct {
+	// Key is the key of the source table.
+	Key table.Key
+	// Kind is the kind of the source table.
+	Kind SourceKind
+	// Start is the byte offset of the table name or the joinGet function call start in the create query.
+	Start int
+	// End is the byte offset right after the table name or the joinGet function call end in the create query.
+	End int
+}
+
 // FromDistributedEngine extracts links from Distributed engine definition.
-func FromDistributedEngine(fullEngine string) []table.Key {
-	links := make([]table.Key, 0)
-	matches := distributedTableExtractorRegex.FindStringSubmatch(fullEngine)
-	if len(matches) < 3 {
-		return links
-	} else {
-		links = append(links, table.Key{
-			Database: matches[1],
-			Name:     matches[2],
-		})
+// The link is the underlying table specified in the second and third engine parameters as string literals or identifiers.
+// The evidence is the whole engine definition with parameters.
+func FromDistributedEngine(fullEngine string) []Dependency {
+	links := make([]Dependency, 0)
+	tokens := chsql.Tokenize(fullEngine)
+	arguments, end := distributedArguments(tokens)
+	if len(arguments) < 3 || !isName(arguments[1]) || !isName(arguments[2]) {
 		return links
 	}
+	return append(links, Dependency{
+		Key: table.Key{
+			Database: arguments[1].Value,
+			Name:     arguments[2].Value,
+		},
+		Start: tokens[0].Pos,
+		End:   end,
+	})
+}
+
+// ClusterFromDistributedEngine extracts the cluster name from Distributed engine definition.
+// The cluster is the first engine parameter specified as string literal or identifier.
+// An empty string is returned if the engine is not Distributed or the cluster is an expression.
+func ClusterFromDistributedEngine(fullEngine string) string {
+	arguments, _ := distributedArguments(chsql.Tokenize(fullEngine))
+	if len(arguments) < 1 || !isName(arguments[0]) {
+		return ""
+	}
+	return arguments[0].Value
+}
+
+// SortingKeyFromEngine extracts the sorting key from the ORDER BY clause of the engine definition, e.g. "(id, date)" for
+// "MergeTree PARTITION BY date ORDER BY (id, date) SETTINGS index_granularity = 8192".
+// An empty string is returned if the engine definition does not have the ORDER BY clause.
+func SortingKeyFromEngine(fullEngine string) string {
+	tokens := chsql.Tokenize(fullEngine)
+	start, end := -1, len(tokens)-1
+	depth := 0
+	for i, token := range tokens {
+		switch {
+		case token.IsPunct("("):
+			depth++
+		case token.IsPunct(")"):
+			depth--
+		case depth != 0:
+		case start < 0 && token.IsKeyword("BY") && i > 0 && tokens[i-1].IsKeyword("ORDER"):
+			start = i + 1
+		case start >= 0 && isEngineClause(token):
+			end = i
+		}
+		if end < len(tokens)-1 {
+			break
+		}
+	}
+	if start < 0 {
+		return ""
+	}
+	return chsql.Format(tokens[start:end])
+}
+
+// isEngineClause reports whether the token starts the clause of the engine definition which follows the ORDER BY clause.
+func isEngineClause(token chsql.Token) bool {
+	for _, keyword := range []string{"PARTITION", "PRIMARY", "SAMPLE", "TTL", "SETTINGS", "COMMENT"} {
+		if token.IsKeyword(keyword) {
+			return true
+		}
+	}
+	return false
+}
+
+// distributedArguments returns the token of each parameter of Distributed engine definition, or an empty token if the parameter is an expression,
+// and the byte offset right after the closing parenthesis of the parameters.
+// No parameters are returned if the tokens are not Distributed engine definition.
+func distributedArguments(tokens []chsql.Token) ([]chsql.Token, int) {
+	if len(tokens) < 2 || !tokens[0].IsKeyword("Distributed") || !tokens[1].IsPunct("(") {
+		return nil, -1
+	}
+	arguments := make([]chsql.Token, 0, 3)
+	argument := make([]chsql.Token, 0, 1)
+	depth := 0
+	end := -1
+	for _, token := range tokens[2:] {
+		if depth == 0 && (token.IsPunct(",") || token.IsPunct(")")) {
+			end = token.End
+			if len(argument) == 1 {
+				arguments = append(arguments, argument[0])
+			} else {
+				arguments = append(arguments, chsql.Token{})
+			}
+			argument = argument[:0]
+			if token.IsPunct(")") {
+				break
+			}
+			continue
+		}
+		if token.IsPunct("(") {
+			depth++
+		} else if token.IsPunct(")") {
+			depth--
+		}
+		argument = append(argument, token)
+	}
+	return arguments, end
 }
 
 // FromCreateQuery extracts links from MaterializedView create query.
-func FromCreateQuery(createQuery string) []table.Key {
-	links := make([]table.Key, 0)
-	matches := materializedViewExtractorRegex.FindStringSubmatch(createQuery)
-	if len(matches) < 3 {
-		return links
-	} else {
-		links = append(links, table.Key{
-			Database: matches[1],
-			Name:     matches[2],
-		})
+// The link is the target table specified in the TO clause. The evidence is the TO clause.
+func FromCreateQuery(createQuery string) []Dependency {
+	links := make([]Dependency, 0)
+	statement, err := chsql.ParseCreateQuery(createQuery)
+	if err != nil || statement.Kind != chsql.MaterializedViewObject || statement.To == (chsql.Name{}) {
 		return links
 	}
+	start, end := statement.Span(statement.ToStart, statement.ToEnd)
+	return append(links, Dependency{Key: toKey(statement.To), Start: start, End: end})
 }
 
-// JoinedTablesFromCreateQuery extracts all joined tables from MaterializedView create query
-func JoinedTablesFromCreateQuery(createQuery string) []table.Key {
-	links := make([]table.Key, 0)
-	matches := materializedViewJoinedTablesExtractorRegex.FindAllStringSubmatch(createQuery, -1)
-	for _, match := range matches {
-		if len(match) < 3 {
+// DictionariesFromCreateQuery extracts all dictionaries used in dictionary functions like dictGet from MaterializedView create query.
+// Dictionaries without database are resolved to the database of the view, the same as unqualified tables and joinGet tables,
+// because ClickHouse resolves them against the current database. The evidence is the dictionary function call.
+func DictionariesFromCreateQuery(createQuery string) []Dependency {
+	links := make([]Dependency, 0)
+	statement, err := chsql.ParseCreateQuery(createQuery)
+	if err != nil || statement.Select == nil {
+		return links
+	}
+	for _, call := range statement.Select.AllFunctions() {
+		if !dictionaryFunctionRegex.MatchString(call.Name) {
 			continue
 		}
-		links = append(links, table.Key{
-			Database: match[1],
-			Name:     match[2],
-		})
+		if dictionary, ok := call.StringArgument(0); ok {
+			start, end := statement.Span(call.Start, call.End)
+			links = append(links, Dependency{Key: keyFromString(dictionary, statement.Name.Database), Start: start, End: end})
+		}
 	}
 	return links
 }
 
-func DictionariesFromCreateQuery(createQuery string) []table.Key {
-	links := make([]table.Key, 0)
-	matches := materializedViewDictionariesExtractorRegex.FindAllStringSubmatch(createQuery, -1)
-	for _, match := range matches {
-		if len(match) < 2 {
+// DictionarySourceFromCreateQuery extracts the source table of the dictionary with the ClickHouse source from Dictionary create query,
+// e.g. SOURCE(CLICKHOUSE(TABLE 'source_table' DB 'db')). The source table without database is resolved to the database of the dictionary.
+// Dictionaries with the QUERY parameter instead of the TABLE one are skipped. The evidence is the CLICKHOUSE source definition.
+func DictionarySourceFromCreateQuery(createQuery string) []Dependency {
+	links := make([]Dependency, 0)
+	statement, err := chsql.ParseCreateQuery(createQuery)
+	if err != nil || statement.Kind != chsql.DictionaryObject {
+		return links
+	}
+	tokens := statement.Tokens
+	for i := 0; i+4 < len(tokens); i++ {
+		if !tokens[i].IsKeyword("SOURCE") || !tokens[i+1].IsPunct("(") || !tokens[i+2].IsKeyword("CLICKHOUSE") || !tokens[i+3].IsPunct("(") {
 			continue
 		}
-		parts := strings.Split(match[1], ".")
-		if len(parts) < 2 {
-			links = append(links, table.Key{
-				Database: "default",
-				Name:     match[1],
-			})
-		} else {
-			links = append(links, table.Key{
-				Database: parts[0],
-				Name:     parts[1],
-			})
+		var database, name string
+		end := i + 4
+		for ; end < len(tokens) && !tokens[end].IsPunct(")"); end++ {
+			if end+1 >= len(tokens) || !isName(tokens[end+1]) {
+				continue
+			}
+			if tokens[end].IsKeyword("DB") {
+				database = tokens[end+1].Value
+			} else if tokens[end].IsKeyword("TABLE") {
+				name = tokens[end+1].Value
+			}
 		}
+		if name == "" {
+			return links
+		}
+		if database == "" {
+			database = statement.Name.Database
+		}
+		start, endPos := statement.Span(i+2, min(end+1, len(tokens)))
+		return append(links, Dependency{Key: table.Key{Database: database, Name: name}, Start: start, End: endPos})
 	}
 	return links
 }
 
+// SourcesFromCreateQuery extracts all tables read by the select query of MaterializedView create query,
+// including tables of the IN operator, e.g. WHERE id IN db.ids.
+// The trigger table is the first item of the result if it exists, each table is returned only once.
+func SourcesFromCreateQuery(createQuery string) []Source {
+	sources := make([]Source, 0)
+	statement, err := chsql.ParseCreateQuery(createQuery)
+	if err != nil || statement.Select == nil {
+		return sources
+	}
+	added := make(map[table.Key]bool)
+	addSource := func(key table.Key, kind SourceKind, startToken, endToken int) {
+		if !added[key] {
+			added[key] = true
+			start, end := statement.Span(startToken, endToken)
+			sources = append(sources, Source{Key: key, Kind: kind, Start: start, End: end})
+		}
+	}
+	if statement.FromTable != nil {
+		addSource(toKey(statement.From), TriggerSource, statement.FromTable.NameStart, statement.FromTable.NameEnd)
+	}
+	for _, tableExpression := range statement.Select.Tables() {
+		if tableExpression.NameStart >= 0 && !tableExpression.IsCTE {
+			addSource(toKey(tableExpression.Name), SecondarySource, tableExpression.NameStart, tableExpression.NameEnd)
+		}
+	}
+	for _, call := range statement.Select.AllFunctions() {
+		if !strings.EqualFold(call.Name, "joinGet") && !strings.EqualFold(call.Name, "joinGetOrNull") {
+			continue
+		}
+		if joinTable, ok := call.StringArgument(0); ok {
+			addSource(keyFromString(joinTable, statement.Name.Database), SecondarySource, call.Start, call.End)
+		}
+	}
+	return sources
+}
+
+// isName reports whether the token is a string literal or an identifier which can be used as a database or table name.
+func isName(token chsql.Token) bool {
+	return token.Kind == chsql.String || token.IsIdent()
+}
+
+// keyFromString returns the key of the table specified as a string in format [database.]name.
+func keyFromString(name string, defaultDatabase string) table.Key {
+	parts := strings.SplitN(name, ".", 2)
+	if len(parts) < 2 {
+		return table.Key{Database: defaultDatabase, Name: name}
+	}
+	return table.Key{Database: parts[0], Name: parts[1]}
+}
+
+func toKey(name chsql.Name) table.Key {
+	return table.Key{Database: name.Database, Name: name.Name}
+}
+
 // FromDependencies extracts links from dependencies.
-func FromDependencies(dependenciesDatabase []string, dependenciesTable []string) []table.Key {
-	links := make([]table.Key, 0)
+// The evidence is not a part of the text, so the Start and End of the dependencies are -1.
+func FromDependencies(dependenciesDatabase []string, dependenciesTable []string) []Dependency {
+	links := make([]Dependency, 0)
 	for i, depTable := range dependenciesTable {
 		if i >= len(dependenciesDatabase) {
 			break
 		}
-		links = append(links, table.Key{
-			Database: dependenciesDatabase[i],
-			Name:     depTable,
+		links = append(links, Dependency{
+			Key: table.Key{
+				Database: dependenciesDatabase[i],
+				Name:     depTable,
+			},
+			Start: -1,
+			End:   -1,
 		})
 	}
 	return links
@@ -2,6 +2,7 @@ package deps
 
 import (
 	"github.com/mbaksheev/clickhouse-table-graph/table"
+	"slices"
 	"testing"
 )
 
@@ -37,6 +38,18 @@ func TestFromDistributedEngine(t *testing.T) {
 			fullEngine: "Distributed('cluster', 'db')",
 			want:       []table.Key{},
 		},
+		{
+			name:       "distributed engine with identifiers and sharding expression",
+			fullEngine: "Distributed(cluster, db, `my table`, cityHash64(id, 'salt'))",
+			want: []table.Key{
+				{Database: "db", Name: "my table"},
+			},
+		},
+		{
+			name:       "distributed engine with database expression",
+			fullEngine: "Distributed('cluster', currentDatabase(), 'table')",
+			want:       []table.Key{},
+		},
 		{
 			name:       "empty string",
 			fullEngine: "",
@@ -46,13 +59,60 @@ func TestFromDistributedEngine(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := FromDistributedEngine(tt.fullEngine); !equal(got, tt.want) {
+			if got := keys(FromDistributedEngine(tt.fullEngine)); !equal(got, tt.want) {
 				t.Errorf("FromDistributedEngine() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
+func TestClusterFromDistributedEngine(t *testing.T) {
+	tests := []struct {
+		name       string
+		fullEngine string
+		want       string
+	}{
+		{name: "string literal", fullEngine: "Distributed('cluster', 'db', 'table')", want: "cluster"},
+		{name: "identifier", fullEngine: "Distributed(my_cluster, db, `my table`, cityHash64(id))", want: "my_cluster"},
+		{name: "macro", fullEngine: "Distributed('{cluster}', 'db', 'table')", want: "{cluster}"},
+		{name: "expression", fullEngine: "Distributed(getMacro('cluster'), 'db', 'table')", want: ""},
+		{name: "not distributed", fullEngine: "MergeTree ORDER BY id", want: ""},
+		{name: "empty string", fullEngine: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ClusterFromDistributedEngine(tt.fullEngine); got != tt.want {
+				t.Errorf("ClusterFromDistributedEngine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortingKeyFromEngine(t *testing.T) {
+	tests := []struct {
+		name       string
+		fullEngine string
+		want       string
+	}{
+		{name: "single column", fullEngine: "MergeTree ORDER BY id", want: "id"},
+		{name: "tuple with settings", fullEngine: "MergeTree PARTITION BY toYYYYMM(date) ORDER BY (id, date) SETTINGS index_granularity = 8192", want: "(id, date)"},
+		{name: "primary key after order by", fullEngine: "ReplacingMergeTree(version) ORDER BY (id, cityHash64(name)) PRIMARY KEY id TTL date + INTERVAL 1 DAY", want: "(id, cityHash64(name))"},
+		{name: "lowercase", fullEngine: "MergeTree order by tuple()", want: "tuple()"},
+		{name: "order by in engine parameters", fullEngine: "Join(ANY, LEFT, id)", want: ""},
+		{name: "no order by", fullEngine: "Null", want: ""},
+		{name: "empty string", fullEngine: "", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := SortingKeyFromEngine(tt.fullEngine); got != tt.want {
+				t.Errorf("SortingKeyFromEngine() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestFromCreateQuery(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -66,6 +126,61 @@ func TestFromCreateQuery(t *testing.T) {
 				{Database: "db", Name: "table"},
 			},
 		},
+		{
+			name:        "lowercase keywords",
+			createQuery: "create materialized view db.view to db.table as select * from db.source",
+			want: []table.Key{
+				{Database: "db", Name: "table"},
+			},
+		},
+		{
+			name:        "quoted identifiers",
+			createQuery: "CREATE MATERIALIZED VIEW `db`.`view` TO `my db`.\"my table\" AS SELECT * FROM db.source",
+			want: []table.Key{
+				{Database: "my db", Name: "my table"},
+			},
+		},
+		{
+			name:        "newlines and comments",
+			createQuery: "CREATE MATERIALIZED VIEW db.view\n-- target table\nTO\n\tdb.table /* comment */ (id UInt64)\nAS SELECT id FROM db.source",
+			want: []table.Key{
+				{Database: "db", Name: "table"},
+			},
+		},
+		{
+			name:        "unqualified target table",
+			createQuery: "CREATE MATERIALIZED VIEW db.view TO table AS SELECT * FROM source",
+			want: []table.Key{
+				{Database: "db", Name: "table"},
+			},
+		},
+		{
+			name:        "TO keyword in select query",
+			createQuery: "CREATE MATERIALIZED VIEW db.view ENGINE = MergeTree ORDER BY id AS SELECT toDate(ts) AS to FROM db.source",
+			want:        []table.Key{},
+		},
+		{
+			name:        "TTL TO DISK clause of inner engine",
+			createQuery: "CREATE MATERIALIZED VIEW db.view ENGINE = MergeTree ORDER BY id TTL d + INTERVAL 1 DAY TO DISK 'cold' AS SELECT * FROM db.source",
+			want:        []table.Key{},
+		},
+		{
+			name:        "TTL TO VOLUME clause of inner engine",
+			createQuery: "CREATE MATERIALIZED VIEW db.view ENGINE = MergeTree ORDER BY id TTL d TO VOLUME 'slow', d + INTERVAL 1 YEAR DELETE POPULATE AS SELECT * FROM db.source",
+			want:        []table.Key{},
+		},
+		{
+			name:        "target table after ON CLUSTER",
+			createQuery: "CREATE MATERIALIZED VIEW db.view UUID '3a5e3c7e-0b0a-4c3e-9d5f-5f1b2a3c4d5e' ON CLUSTER main TO db.table AS SELECT * FROM db.source",
+			want: []table.Key{
+				{Database: "db", Name: "table"},
+			},
+		},
+		{
+			name:        "inner table UUID",
+			createQuery: "ATTACH MATERIALIZED VIEW db.view UUID '3a5e3c7e-0b0a-4c3e-9d5f-5f1b2a3c4d5e' TO INNER UUID '4b6f4d8f-1c1b-4d4f-8e6a-6a2c3b4d5e6f' (id UInt64) ENGINE = MergeTree ORDER BY id AS SELECT id FROM db.source",
+			want:        []table.Key{},
+		},
 		{
 			name:        "invalid materialized view",
 			createQuery: "CREATE MATERIALIZED VIEW view TO db",
@@ -80,98 +195,219 @@ func TestFromCreateQuery(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := FromCreateQuery(tt.createQuery); !equal(got, tt.want) {
+			if got := keys(FromCreateQuery(tt.createQuery)); !equal(got, tt.want) {
 				t.Errorf("FromCreateQuery() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
 
-func TestJoinedTablesFromCreateQuery(t *testing.T) {
+func TestDictionariesFromCreateQuery(t *testing.T) {
 	tests := []struct {
 		name        string
 		createQuery string
 		want        []table.Key
 	}{
 		{
-			name:        "valid materialized view with JOIN and with AS",
-			createQuery: "CREATE MATERIALIZED VIEW db.view TO tb.table AS SELECT * FROM db.table_a as a JOIN db.table_b as b ON a.id = b.id;",
+			name:        "materialized view with dictionaries",
+			createQuery: "CREATE MATERIALIZED VIEW db.view TO db.table AS SELECT col_a, dictGet('dict_1', 'dict_key_1', t.col_b), dictGet('dict_db.dict_2', 'dict_key_1', t.col_b), dictGetOrNull('dict_3', 'dict_key_1', t.col_b) as col_c, dictGetOrDefault('dict_4', 'dict_key_1', t.col_b, 'default') as col_d, dictIsIn('dict_5', 'foo', 'bar') FROM db.table_a;",
 			want: []table.Key{
-				{Database: "db", Name: "table_b"},
+				{Database: "db", Name: "dict_1"},
+				{Database: "dict_db", Name: "dict_2"},
+				{Database: "db", Name: "dict_3"},
+				{Database: "db", Name: "dict_4"},
+				{Database: "db", Name: "dict_5"},
 			},
 		},
 		{
-			name:        "valid materialized view with JOIN and without AS",
-			createQuery: "CREATE MATERIALIZED VIEW db.view TO tb.table AS SELECT * FROM db.table_a JOIN db.table_b ON db.table_a.id =  db.table_b.id;",
+			name:        "dictionaries in subqueries, lowercase keywords and comments",
+			createQuery: "create materialized view db.view to db.table as select dictGet('db.dict_1', 'value', id) from (select id from db.table_a where dictHas(\"db\".dict_x, id) and id in (select dictGetUInt64('db.dict_2', 'id', key) from db.table_b)) -- dictGet('db.commented', 'value', id)",
 			want: []table.Key{
-				{Database: "db", Name: "table_b"},
+				{Database: "db", Name: "dict_1"},
+				{Database: "db", Name: "dict_2"},
 			},
 		},
 		{
-			name:        "valid materialized view with multiple JOINs and AS",
-			createQuery: "CREATE MATERIALIZED VIEW db.view TO tb.table AS SELECT * FROM db.table_a as a JOIN db.table_b as b ON a.id = b.id JOIN db.table_c as c ON a.id = c.id;",
-			want: []table.Key{
-				{Database: "db", Name: "table_b"},
-				{Database: "db", Name: "table_c"},
-			},
+			name:        "dictionary name in string",
+			createQuery: "CREATE MATERIALIZED VIEW db.view TO db.table AS SELECT 'dictGet(\\'db.dict\\', \\'value\\', id)' AS text FROM db.table_a",
+			want:        []table.Key{},
 		},
 		{
-			name:        "valid materialized view with multiple JOINs without AS",
-			createQuery: "CREATE MATERIALIZED VIEW db.view TO tb.table AS SELECT * FROM db.table_a JOIN db.table_b ON db.table_a.id = db.table_b.id JOIN db.table_c ON db.table_a.id = db.table_c.id;",
-			want: []table.Key{
-				{Database: "db", Name: "table_b"},
-				{Database: "db", Name: "table_c"},
-			},
+			name:        "empty string",
+			createQuery: "",
+			want:        []table.Key{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keys(DictionariesFromCreateQuery(tt.createQuery)); !equal(got, tt.want) {
+				t.Errorf("DictionariesFromCreateQuery() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDictionarySourceFromCreateQuery(t *testing.T) {
+	tests := []struct {
+		name        string
+		createQuery string
+		want        []table.Key
+		wantSnippet string
+	}{
+		{
+			name:        "clickhouse source with database",
+			createQuery: "CREATE DICTIONARY db.dict (id UInt64, value String) PRIMARY KEY id SOURCE(CLICKHOUSE(HOST 'localhost' PORT 9000 USER 'default' TABLE 'source_table' DB 'source_db')) LAYOUT(FLAT()) LIFETIME(300)",
+			want:        []table.Key{{Database: "source_db", Name: "source_table"}},
+			wantSnippet: "CLICKHOUSE(HOST 'localhost' PORT 9000 USER 'default' TABLE 'source_table' DB 'source_db')",
 		},
 		{
-			name:        "invalid materialized view",
-			createQuery: "CREATE MATERIALIZED VIEW view TO db.table AS SELECT * FROM source JOIN db2",
+			name:        "clickhouse source without database, lowercase keywords",
+			createQuery: "create dictionary db.dict (id UInt64, value String) primary key id source(clickhouse(table source_table)) layout(hashed()) lifetime(0)",
+			want:        []table.Key{{Database: "db", Name: "source_table"}},
+			wantSnippet: "clickhouse(table source_table)",
+		},
+		{
+			name:        "clickhouse source with query",
+			createQuery: "CREATE DICTIONARY db.dict (id UInt64, value String) PRIMARY KEY id SOURCE(CLICKHOUSE(QUERY 'SELECT id, value FROM db.source_table')) LAYOUT(FLAT()) LIFETIME(0)",
 			want:        []table.Key{},
 		},
 		{
-			name:        "empty string",
-			createQuery: "",
+			name:        "null source",
+			createQuery: "CREATE DICTIONARY db.dict (id UInt64, value String) PRIMARY KEY id SOURCE(NULL()) LAYOUT(FLAT()) LIFETIME(0)",
+			want:        []table.Key{},
+		},
+		{
+			name:        "not a dictionary",
+			createQuery: "CREATE TABLE db.table (id UInt64) ENGINE = MergeTree ORDER BY id COMMENT 'SOURCE(CLICKHOUSE(TABLE t))'",
 			want:        []table.Key{},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := JoinedTablesFromCreateQuery(tt.createQuery); !equal(got, tt.want) {
-				t.Errorf("JoinedTablesFromCreateQuery() = %v, want %v", got, tt.want)
+			dependencies := DictionarySourceFromCreateQuery(tt.createQuery)
+			if got := keys(dependencies); !equal(got, tt.want) {
+				t.Errorf("DictionarySourceFromCreateQuery() = %v, want %v", got, tt.want)
+			}
+			if tt.wantSnippet == "" {
+				return
+			}
+			if snippet := tt.createQuery[dependencies[0].Start:dependencies[0].End]; snippet != tt.wantSnippet {
+				t.Errorf("DictionarySourceFromCreateQuery() snippet = %s, want %s", snippet, tt.wantSnippet)
 			}
 		})
 	}
 }
 
-func TestDictionariesFromCreateQuery(t *testing.T) {
+func TestSourcesFromCreateQuery(t *testing.T) {
 	tests := []struct {
 		name        string
 		createQuery string
-		want        []table.Key
+		want        []Source
 	}{
 		{
-			name:        "materialized view with dictionaries",
-			createQuery: "CREATE MATERIALIZED VIEW db.view TO db.table AS SELECT col_a, dictGet('dict_1', 'dict_key_1', t.col_b), dictGet('dict_db.dict_2', 'dict_key_1', t.col_b), dictGetOrNull('dict_3', 'dict_key_1', t.col_b) as col_c, dictGetOrDefault('dict_4', 'dict_key_1', t.col_b, 'default') as col_d, dictIsIn('dict_5', 'foo', 'bar') FROM db.table_a;",
-			want: []table.Key{
-				{Database: "default", Name: "dict_1"},
-				{Database: "dict_db", Name: "dict_2"},
-				{Database: "default", Name: "dict_3"},
-				{Database: "default", Name: "dict_4"},
-				{Database: "default", Name: "dict_5"},
+			name:        "single source table",
+			createQuery: "CREATE MATERIALIZED VIEW db.view TO db.target AS SELECT * FROM db.source",
+			want: []Source{
+				{Key: table.Key{Database: "db", Name: "source"}, Kind: TriggerSource},
+			},
+		},
+		{
+			name:        "joins and subqueries in WHERE clause",
+			createQuery: "CREATE MATERIALIZED VIEW db.view TO db.target AS SELECT * FROM db.source AS s JOIN db.joined AS j ON s.id = j.id WHERE s.id NOT IN (SELECT id FROM db.excluded)",
+			want: []Source{
+				{Key: table.Key{Database: "db", Name: "source"}, Kind: TriggerSource},
+				{Key: table.Key{Database: "db", Name: "joined"}, Kind: SecondarySource},
+				{Key: table.Key{Database: "db", Name: "excluded"}, Kind: SecondarySource},
+			},
+		},
+		{
+			name:        "trigger table in subquery",
+			createQuery: "CREATE MATERIALIZED VIEW db.view TO db.target AS SELECT * FROM (SELECT * FROM (SELECT * FROM db.source) JOIN db.joined USING (id))",
+			want: []Source{
+				{Key: table.Key{Database: "db", Name: "source"}, Kind: TriggerSource},
+				{Key: table.Key{Database: "db", Name: "joined"}, Kind: SecondarySource},
+			},
+		},
+		{
+			name:        "common table expressions",
+			createQuery: "CREATE MATERIALIZED VIEW db.view TO db.target AS WITH src AS (SELECT * FROM db.source), lookup AS (SELECT * FROM db.lookup) SELECT * FROM src JOIN lookup USING (id)",
+			want: []Source{
+				{Key: table.Key{Database: "db", Name: "source"}, Kind: TriggerSource},
+				{Key: table.Key{Database: "db", Name: "lookup"}, Kind: SecondarySource},
+			},
+		},
+		{
+			name:        "union all branches",
+			createQuery: "CREATE MATERIALIZED VIEW db.view TO db.target AS SELECT id FROM db.source UNION ALL SELECT id FROM db.other UNION ALL SELECT id FROM db.source",
+			want: []Source{
+				{Key: table.Key{Database: "db", Name: "source"}, Kind: TriggerSource},
+				{Key: table.Key{Database: "db", Name: "other"}, Kind: SecondarySource},
+			},
+		},
+		{
+			name:        "unqualified tables and joinGet",
+			createQuery: "CREATE MATERIALIZED VIEW db.view TO db.target AS SELECT joinGet('join_table', 'value', id) FROM source JOIN other.joined USING (id)",
+			want: []Source{
+				{Key: table.Key{Database: "db", Name: "source"}, Kind: TriggerSource},
+				{Key: table.Key{Database: "other", Name: "joined"}, Kind: SecondarySource},
+				{Key: table.Key{Database: "db", Name: "join_table"}, Kind: SecondarySource},
 			},
 		},
+		{
+			name:        "unqualified dictionary and joinGet tables resolve to the same database",
+			createQuery: "CREATE MATERIALIZED VIEW db.view TO db.target AS SELECT joinGet('j', 'value', id), dictGet('d', 'value', id) FROM source",
+			want: []Source{
+				{Key: table.Key{Database: "db", Name: "source"}, Kind: TriggerSource},
+				{Key: table.Key{Database: "db", Name: "j"}, Kind: SecondarySource},
+			},
+		},
+		{
+			name:        "tables of IN operator",
+			createQuery: "CREATE MATERIALIZED VIEW db.view TO db.target AS SELECT * FROM db.source WHERE id IN db.ids AND id GLOBAL NOT IN excluded AND id IN (1, 2)",
+			want: []Source{
+				{Key: table.Key{Database: "db", Name: "source"}, Kind: TriggerSource},
+				{Key: table.Key{Database: "db", Name: "ids"}, Kind: SecondarySource},
+				{Key: table.Key{Database: "db", Name: "excluded"}, Kind: SecondarySource},
+			},
+		},
+		{
+			name:        "join modifiers, quoted identifiers and comments",
+			createQuery: "create materialized view db.view to db.target as select * from db.source a left any join `my db`.\"my table\" b on a.id = b.id -- JOIN db.commented ON 1\nglobal inner join db.other using (id)",
+			want: []Source{
+				{Key: table.Key{Database: "db", Name: "source"}, Kind: TriggerSource},
+				{Key: table.Key{Database: "my db", Name: "my table"}, Kind: SecondarySource},
+				{Key: table.Key{Database: "db", Name: "other"}, Kind: SecondarySource},
+			},
+		},
+		{
+			name:        "array join and joined table function",
+			createQuery: "CREATE MATERIALIZED VIEW db.view TO db.target AS SELECT * FROM db.source ARRAY JOIN arr AS a JOIN numbers(10) AS n ON a = n.number",
+			want: []Source{
+				{Key: table.Key{Database: "db", Name: "source"}, Kind: TriggerSource},
+			},
+		},
+		{
+			name:        "table function",
+			createQuery: "CREATE MATERIALIZED VIEW db.view TO db.target AS SELECT * FROM numbers(10)",
+			want:        []Source{},
+		},
 		{
 			name:        "empty string",
 			createQuery: "",
-			want:        []table.Key{},
+			want:        []Source{},
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := DictionariesFromCreateQuery(tt.createQuery); !equal(got, tt.want) {
-				t.Errorf("DictionariesFromCreateQuery() = %v, want %v", got, tt.want)
+			got := SourcesFromCreateQuery(tt.createQuery)
+			for i := range got {
+				got[i].Start, got[i].End = 0, 0
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("SourcesFromCreateQuery() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -219,7 +455,7 @@ func TestFromDependencies(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := FromDependencies(tt.dependenciesDatabase, tt.dependenciesTable); !equal(got, tt.want) {
+			if got := keys(FromDependencies(tt.dependenciesDatabase, tt.dependenciesTable)); !equal(got, tt.want) {
 				t.Errorf("FromDependencies() = %v, want %v", got, tt.want)
 			}
 		})
@@ -237,3 +473,66 @@ func equal(a, b []table.Key) bool {
 	}
 	return true
 }
+
+func TestEvidence(t *testing.T) {
+	createQuery := "CREATE MATERIALIZED VIEW db.view TO db.target AS SELECT dictGet('db.dict', 'value', id), joinGet('db.join_table', 'value', id) FROM db.source JOIN `other`.joined USING (id)"
+	tests := []struct {
+		name         string
+		text         string
+		dependencies []Dependency
+		want         []string
+	}{
+		{
+			name:         "distributed engine",
+			text:         "Distributed('cluster', 'db', 'table', rand()) SETTINGS fsync_after_insert = 1",
+			dependencies: FromDistributedEngine("Distributed('cluster', 'db', 'table', rand()) SETTINGS fsync_after_insert = 1"),
+			want:         []string{"Distributed('cluster', 'db', 'table', rand())"},
+		},
+		{
+			name:         "target table",
+			text:         createQuery,
+			dependencies: FromCreateQuery(createQuery),
+			want:         []string{"TO db.target"},
+		},
+		{
+			name:         "dictionaries",
+			text:         createQuery,
+			dependencies: DictionariesFromCreateQuery(createQuery),
+			want:         []string{"dictGet('db.dict', 'value', id)"},
+		},
+		{
+			name:         "sources",
+			text:         createQuery,
+			dependencies: sourceDependencies(SourcesFromCreateQuery(createQuery)),
+			want:         []string{"db.source", "`other`.joined", "joinGet('db.join_table', 'value', id)"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := make([]string, 0, len(tt.dependencies))
+			for _, dependency := range tt.dependencies {
+				got = append(got, tt.text[dependency.Start:dependency.End])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("evidence = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func keys(dependencies []Dependency) []table.Key {
+	result := make([]table.Key, 0, len(dependencies))
+	for _, dependency := range dependencies {
+		result = append(result, dependency.Key)
+	}
+	return result
+}
+
+func sourceDependencies(sources []Source) []Dependency {
+	result := make([]Dependency, 0, len(sources))
+	for _, source := range sources {
+		result = append(result, Dependency{Key: source.Key, Start: source.Start, End: source.End})
+	}
+	return result
+}
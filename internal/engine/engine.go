@@ -0,0 +1,124 @@
+// Package engine provides the classification of the table engines into families shared by the renderers of the graph.
+// Every renderer draws the tables of the same family with the same shape.
+package engine
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+)
+
+// Family represents the family of the table engines, e.g. MergeTree tables or queues like Kafka.
+type Family int
+
+// Possible values for the [Family] type.
+const (
+	// Other is the family of the engines which do not match any other family.
+	Other Family = iota
+	// MaterializedView is the family of materialized views.
+	MaterializedView
+	// View is the family of views, live views and window views.
+	View
+	// Distributed is the family of Distributed tables.
+	Distributed
+	// Null is the family of Null tables.
+	Null
+	// Dictionary is the family of dictionaries.
+	Dictionary
+	// AggregatingMergeTree is the family of MergeTree tables which aggregate rows: Aggregating, Summing and Coalescing, replicated or not.
+	AggregatingMergeTree
+	// ReplicatedMergeTree is the family of the rest of replicated and shared MergeTree tables.
+	ReplicatedMergeTree
+	// MergeTree is the family of the rest of MergeTree tables.
+	MergeTree
+	// Queue is the family of the tables which consume the queues and the files, e.g. Kafka.
+	Queue
+	// Buffer is the family of Buffer tables.
+	Buffer
+	// JoinSet is the family of Join and Set tables.
+	JoinSet
+	// MemoryLog is the family of in-memory and log tables.
+	MemoryLog
+	// External is the family of the tables of the external storages, e.g. MySQL or S3.
+	External
+)
+
+// matcher represents the engines of the family.
+type matcher struct {
+	name        string
+	engines     []string
+	engineRegex *regexp.Regexp
+}
+
+// matchers contains the engines of every family, the order of the families is the order they are matched.
+var matchers = [...]matcher{
+	Other:            {name: "Other"},
+	MaterializedView: {name: "MaterializedView", engines: []string{"MaterializedView"}},
+	View:             {name: "View", engines: []string{"View", "LiveView", "WindowView"}},
+	Distributed:      {name: "Distributed", engines: []string{"Distributed"}},
+	Null:             {name: "Null", engines: []string{"Null"}},
+	Dictionary:       {name: "Dictionary", engines: []string{"Dictionary"}},
+	AggregatingMergeTree: {
+		name:        "Aggregating MergeTree",
+		engineRegex: regexp.MustCompile(`^(Replicated|Shared)?(Aggregating|Summing|Coalescing)MergeTree$`),
+	},
+	ReplicatedMergeTree: {name: "Replicated MergeTree", engineRegex: regexp.MustCompile(`^(Replicated|Shared)\w*MergeTree$`)},
+	MergeTree:           {name: "MergeTree", engineRegex: regexp.MustCompile(`^\w*MergeTree$`)},
+	Queue:               {name: "Queue", engines: []string{"Kafka", "RabbitMQ", "NATS", "S3Queue", "AzureQueue", "FileLog"}},
+	Buffer:              {name: "Buffer", engines: []string{"Buffer"}},
+	JoinSet:             {name: "Join, Set", engines: []string{"Join", "Set"}},
+	MemoryLog:           {name: "Memory, Log", engines: []string{"Memory", "Log", "TinyLog", "StripeLog"}},
+	External: {
+		name: "External",
+		engines: []string{
+			"MySQL", "PostgreSQL", "MaterializedPostgreSQL", "MongoDB", "Redis", "SQLite", "JDBC", "ODBC",
+			"S3", "URL", "File", "HDFS", "AzureBlobStorage", "Iceberg", "DeltaLake", "Hudi",
+		},
+	},
+}
+
+// String returns the name of the [Family], e.g. "Replicated MergeTree".
+func (family Family) String() string {
+	if family < 0 || int(family) >= len(matchers) {
+		return fmt.Sprintf("Family(%d)", family)
+	}
+	return matchers[family].name
+}
+
+// Engines returns the names of the engines of the [Family]. It is empty for the families matched by [Family.EngineRegex].
+func (family Family) Engines() []string {
+	if family < 0 || int(family) >= len(matchers) {
+		return nil
+	}
+	return slices.Clone(matchers[family].engines)
+}
+
+// EngineRegex returns the regular expression matched against the engine name, or nil if the [Family] has the list of the engines.
+func (family Family) EngineRegex() *regexp.Regexp {
+	if family < 0 || int(family) >= len(matchers) {
+		return nil
+	}
+	return matchers[family].engineRegex
+}
+
+// Families returns all families except [Other] in the order they are matched against the engine name.
+func Families() []Family {
+	families := make([]Family, 0, len(matchers)-1)
+	for family := range matchers {
+		if Family(family) != Other {
+			families = append(families, Family(family))
+		}
+	}
+	return families
+}
+
+// FamilyOf returns the first [Family] which matches the specified engine name, or [Other] if there is no such family.
+func FamilyOf(engine string) Family {
+	for _, family := range Families() {
+		matcher := matchers[family]
+		if slices.Contains(matcher.engines, engine) || matcher.engineRegex != nil && matcher.engineRegex.MatchString(engine) {
+			return family
+		}
+	}
+	return Other
+}
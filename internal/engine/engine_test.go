@@ -0,0 +1,57 @@
+package engine
+
+import "testing"
+
+func TestFamilyOf(t *testing.T) {
+	tests := []struct {
+		engine string
+		want   Family
+	}{
+		{engine: "MaterializedView", want: MaterializedView},
+		{engine: "WindowView", want: View},
+		{engine: "Distributed", want: Distributed},
+		{engine: "Null", want: Null},
+		{engine: "Dictionary", want: Dictionary},
+		{engine: "ReplicatedSummingMergeTree", want: AggregatingMergeTree},
+		{engine: "SharedReplacingMergeTree", want: ReplicatedMergeTree},
+		{engine: "ReplacingMergeTree", want: MergeTree},
+		{engine: "Kafka", want: Queue},
+		{engine: "Buffer", want: Buffer},
+		{engine: "Set", want: JoinSet},
+		{engine: "TinyLog", want: MemoryLog},
+		{engine: "PostgreSQL", want: External},
+		{engine: "GenerateRandom", want: Other},
+		{engine: "", want: Other},
+	}
+	for _, tt := range tests {
+		t.Run(tt.engine, func(t *testing.T) {
+			if got := FamilyOf(tt.engine); got != tt.want {
+				t.Errorf("FamilyOf(%q) = %s, want %s", tt.engine, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFamilyString(t *testing.T) {
+	tests := []struct {
+		family Family
+		want   string
+	}{
+		{family: Other, want: "Other"},
+		{family: JoinSet, want: "Join, Set"},
+		{family: External, want: "External"},
+		{family: Family(42), want: "Family(42)"},
+		{family: Family(-1), want: "Family(-1)"},
+	}
+	for _, tt := range tests {
+		if got := tt.family.String(); got != tt.want {
+			t.Errorf("String() = %s, want %s", got, tt.want)
+		}
+	}
+	if Family(42).Engines() != nil || Family(42).EngineRegex() != nil {
+		t.Errorf("Engines() and EngineRegex() of the unknown family are not empty")
+	}
+	if families := Families(); len(families) != int(External) || families[0] != MaterializedView {
+		t.Errorf("Families() = %v, want all families except Other in the match order", families)
+	}
+}
@@ -0,0 +1,34 @@
+// Package htmlscript provides functions to add scripts to the HTML documents generated with html/template.
+package htmlscript
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+)
+
+// ValidateUrl returns an error if the URL of the script is not an absolute http or https URL, or a relative URL.
+func ValidateUrl(scriptUrl string) error {
+	parsedUrl, err := url.Parse(scriptUrl)
+	if err != nil {
+		return fmt.Errorf("ValidateUrl: invalid script URL: %s, %w", scriptUrl, err)
+	}
+	switch {
+	case parsedUrl.Scheme == "http" || parsedUrl.Scheme == "https":
+		if parsedUrl.Host == "" {
+			return fmt.Errorf("ValidateUrl: script URL without host: %s", scriptUrl)
+		}
+	case parsedUrl.Scheme != "":
+		return fmt.Errorf("ValidateUrl: unsupported script URL scheme: %s", scriptUrl)
+	case parsedUrl.Path == "":
+		return fmt.Errorf("ValidateUrl: script URL without path: %s", scriptUrl)
+	}
+	return nil
+}
+
+// Content returns the trusted JavaScript code of the embedded script.
+// Closing script tags inside the code are escaped, so they do not end the script element.
+func Content(js []byte) template.JS {
+	return template.JS(strings.ReplaceAll(string(js), "</script", `<\/script`))
+}
@@ -0,0 +1,35 @@
+package htmlscript
+
+import "testing"
+
+func TestValidateUrl(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{url: "https://cdn.example.com/script.min.js"},
+		{url: "http://localhost:8080/script.min.js"},
+		{url: "js/script.min.js"},
+		{url: "javascript:alert(1)", wantErr: true},
+		{url: "data:text/javascript,alert(1)", wantErr: true},
+		{url: "https:///script.min.js", wantErr: true},
+		{url: "file:///tmp/script.min.js", wantErr: true},
+		{url: "https://example.com/%zz", wantErr: true},
+		{url: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			if err := ValidateUrl(tt.url); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateUrl() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestContent(t *testing.T) {
+	got := Content([]byte(`var s = "</script>";`))
+	want := `var s = "<\/script>";`
+	if string(got) != want {
+		t.Errorf("Content() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,44 @@
+// Package nodeid provides the stable identifiers of the table nodes shared by the renderers of the graph.
+package nodeid
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+// Table returns the stable identifier of the node of the table, which is valid for any database and table name.
+// It contains the database and table name with all characters except ASCII letters, digits and underscores replaced with underscores,
+// and the hash of the table key, so different tables never get the same identifier, e.g. "x.a" + "b" and "x" + "a.b".
+func Table(tableKey table.Key) string {
+	hash := fnv.New32a()
+	hash.Write([]byte(tableKey.Database))
+	hash.Write([]byte{0})
+	hash.Write([]byte(tableKey.Name))
+	return fmt.Sprintf("t_%s_%s_%08x", sanitize(tableKey.Database), sanitize(tableKey.Name), hash.Sum32())
+}
+
+// Stable returns the stable identifier with the specified prefix and names, e.g. the database and the cluster of the subgraph.
+// The names are sanitized and joined with underscores, and the hash of the names is appended, so different names never get the same identifier.
+func Stable(prefix string, names ...string) string {
+	hash := fnv.New32a()
+	sanitized := make([]string, 0, len(names))
+	for _, name := range names {
+		hash.Write([]byte(name))
+		hash.Write([]byte{0})
+		sanitized = append(sanitized, sanitize(name))
+	}
+	return fmt.Sprintf("%s_%s_%08x", prefix, strings.Join(sanitized, "_"), hash.Sum32())
+}
+
+// sanitize returns the name with all characters except ASCII letters, digits and underscores replaced with underscores.
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' {
+			return r
+		}
+		return '_'
+	}, name)
+}
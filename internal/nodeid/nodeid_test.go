@@ -0,0 +1,45 @@
+package nodeid
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+var validId = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+func TestTable(t *testing.T) {
+	tests := []struct {
+		name  string
+		key   table.Key
+		other table.Key
+	}{
+		{name: "dotted names", key: table.Key{Database: "x.a", Name: "b"}, other: table.Key{Database: "x", Name: "a.b"}},
+		{name: "sanitized characters", key: table.Key{Database: "db", Name: "my-table"}, other: table.Key{Database: "db", Name: "my table"}},
+		{name: "underscores", key: table.Key{Database: "a_b", Name: "c"}, other: table.Key{Database: "a", Name: "b_c"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, otherId := Table(tt.key), Table(tt.other)
+			if !validId.MatchString(id) || !validId.MatchString(otherId) {
+				t.Errorf("Table() = %s, %s, want only ASCII letters, digits and underscores", id, otherId)
+			}
+			if id == otherId {
+				t.Errorf("Table() = %s for both %s and %s, want different identifiers", id, tt.key, tt.other)
+			}
+			if id != Table(tt.key) {
+				t.Errorf("Table() is not stable for %s", tt.key)
+			}
+		})
+	}
+}
+
+func TestStable(t *testing.T) {
+	if Stable("c", "db", "cluster") == Stable("c", "dbc", "luster") {
+		t.Errorf("Stable() returns the same identifier for different names")
+	}
+	if got := Stable("d", "my db"); !regexp.MustCompile(`^d_my_db_[0-9a-f]{8}$`).MatchString(got) {
+		t.Errorf("Stable() = %s, want d_my_db_<hash>", got)
+	}
+}
@@ -0,0 +1,217 @@
+// Interactive explorer of the rendered graph: the search box finds and centers the table, the click on the node highlights
+// its upstream and downstream tables and shows the side panel with the table details, and the tables of the selected engines can be hidden.
+// The tables and the links of the graph are embedded into the document as JSON by the node identifiers, so the explorer works without network access.
+var graphData = null;
+var diagram = null;
+var hiddenEngines = {};
+
+// missingEngine is the engine toggle of the tables which do not exist.
+var missingEngine = "table does not exist";
+
+function loadGraphData() {
+	if (graphData === null) {
+		graphData = JSON.parse(document.getElementById("graph-data").textContent);
+	}
+	return graphData;
+}
+
+function engineOf(details) {
+	return details.exists ? details.engine : missingEngine;
+}
+
+// nodeElements returns the elements of the rendered node. Mermaid sets the node identifier to the data-id attribute,
+// older versions render it as a part of the element id, e.g. "flowchart-<node>-0".
+function nodeElements(nodeId) {
+	if (diagram === null) {
+		return [];
+	}
+	return diagram.svg.querySelectorAll('g.node[data-id="' + nodeId + '"], g.node[id*="-' + nodeId + '-"]');
+}
+
+// linkElements returns the path and the label of the rendered link, identified as "L_<from>_<to>_<index>" or "L-<from>-<to>-<index>".
+function linkElements(link) {
+	if (diagram === null) {
+		return [];
+	}
+	var selectors = [];
+	["L_" + link.from + "_" + link.to + "_", "L-" + link.from + "-" + link.to + "-"].forEach(function (prefix) {
+		selectors.push('[id^="' + prefix + '"]', '[data-id^="' + prefix + '"]');
+	});
+	return diagram.svg.querySelectorAll(selectors.join(", "));
+}
+
+function setClass(elements, className, enabled) {
+	elements.forEach(function (element) {
+		element.classList.toggle(className, enabled);
+	});
+}
+
+// chainOf returns the node identifiers of the upstream and downstream tables of the node, including the node itself.
+function chainOf(nodeId) {
+	var links = loadGraphData().links;
+	var chain = {};
+	chain[nodeId] = true;
+	[["to", "from"], ["from", "to"]].forEach(function (direction) {
+		var queue = [nodeId];
+		var visited = {};
+		visited[nodeId] = true;
+		while (queue.length > 0) {
+			var current = queue.shift();
+			links.forEach(function (link) {
+				var next = link[direction[1]];
+				if (link[direction[0]] === current && !visited[next]) {
+					visited[next] = true;
+					chain[next] = true;
+					queue.push(next);
+				}
+			});
+		}
+	});
+	return chain;
+}
+
+// focusTable highlights the upstream and downstream tables of the node and the links between them, and dims everything else.
+// All tables and links are highlighted if the node identifier is null.
+function focusTable(nodeId) {
+	var data = loadGraphData();
+	var chain = nodeId === null ? null : chainOf(nodeId);
+	Object.keys(data.tables).forEach(function (id) {
+		setClass(nodeElements(id), "dimmed", chain !== null && !chain[id]);
+	});
+	data.links.forEach(function (link) {
+		setClass(linkElements(link), "dimmed", chain !== null && !(chain[link.from] && chain[link.to]));
+	});
+}
+
+function showTable(nodeId) {
+	var details = loadGraphData().tables[nodeId];
+	if (!details) {
+		return;
+	}
+	document.getElementById("table-name").textContent = details.name;
+	document.getElementById("table-engine-full").textContent = details.exists ? details.engine_full || details.engine : missingEngine;
+	document.getElementById("table-sorting-key").textContent = details.sorting_key || "-";
+	document.getElementById("table-create-query").textContent = details.create_table_query;
+	document.getElementById("table-copy-ddl").hidden = !details.exists;
+	document.getElementById("table-panel").hidden = false;
+}
+
+// selectTable is the click callback of the nodes: it shows the table details and highlights the upstream and downstream tables.
+function selectTable(nodeId) {
+	showTable(nodeId);
+	focusTable(nodeId);
+}
+
+function clearSelection() {
+	document.getElementById("table-panel").hidden = true;
+	focusTable(null);
+}
+
+// searchTable selects and centers the table with the specified name, or the first table which name contains the text.
+function searchTable(text) {
+	var tables = loadGraphData().tables;
+	var query = text.trim().toLowerCase();
+	if (query === "") {
+		return;
+	}
+	var ids = Object.keys(tables).sort(function (a, b) {
+		return tables[a].name < tables[b].name ? -1 : 1;
+	});
+	var found = ids.filter(function (id) {
+		return tables[id].name.toLowerCase() === query;
+	}).concat(ids.filter(function (id) {
+		return tables[id].name.toLowerCase().indexOf(query) >= 0;
+	}));
+	if (found.length === 0) {
+		return;
+	}
+	selectTable(found[0]);
+	var elements = nodeElements(found[0]);
+	if (elements.length > 0 && diagram !== null) {
+		diagram.zoom.center(elements[0]);
+	}
+}
+
+// hideEngine hides or shows the tables of the engine and their links.
+function hideEngine(engine, hidden) {
+	var tables = loadGraphData().tables;
+	hiddenEngines[engine] = hidden;
+	Object.keys(tables).forEach(function (id) {
+		if (engineOf(tables[id]) === engine) {
+			setClass(nodeElements(id), "hidden-engine", hidden);
+		}
+	});
+	loadGraphData().links.forEach(function (link) {
+		var linkHidden = hiddenEngines[engineOf(tables[link.from])] || hiddenEngines[engineOf(tables[link.to])];
+		setClass(linkElements(link), "hidden-engine", !!linkHidden);
+	});
+}
+
+function copyText(text) {
+	if (navigator.clipboard && window.isSecureContext) {
+		return navigator.clipboard.writeText(text);
+	}
+	var textArea = document.createElement("textarea");
+	textArea.value = text;
+	document.body.appendChild(textArea);
+	textArea.select();
+	document.execCommand("copy");
+	document.body.removeChild(textArea);
+	return Promise.resolve();
+}
+
+// initExplorer is called with the rendered diagram and its zoom controller when mermaid renders the diagram.
+function initExplorer(svg, zoom) {
+	diagram = {svg: svg, zoom: zoom};
+}
+
+window.addEventListener("load", function () {
+	var tables = loadGraphData().tables;
+	var names = document.getElementById("table-names");
+	var engines = {};
+	Object.keys(tables).forEach(function (id) {
+		var option = document.createElement("option");
+		option.value = tables[id].name;
+		names.appendChild(option);
+		engines[engineOf(tables[id])] = true;
+	});
+	var toggles = document.getElementById("engine-toggles");
+	Object.keys(engines).sort().forEach(function (engine) {
+		var label = document.createElement("label");
+		var checkbox = document.createElement("input");
+		checkbox.type = "checkbox";
+		checkbox.checked = true;
+		checkbox.addEventListener("change", function () {
+			hideEngine(engine, !checkbox.checked);
+		});
+		label.appendChild(checkbox);
+		label.appendChild(document.createTextNode(" " + engine + " "));
+		toggles.appendChild(label);
+	});
+
+	var search = document.getElementById("table-search");
+	search.addEventListener("keydown", function (event) {
+		if (event.key === "Enter") {
+			searchTable(search.value);
+		}
+	});
+	search.addEventListener("change", function () {
+		searchTable(search.value);
+	});
+	document.getElementById("clear-selection").addEventListener("click", clearSelection);
+	document.getElementById("table-panel-close").addEventListener("click", clearSelection);
+	document.addEventListener("keydown", function (event) {
+		if (event.key === "Escape") {
+			clearSelection();
+		}
+	});
+	document.getElementById("table-copy-ddl").addEventListener("click", function (event) {
+		var button = event.target;
+		copyText(document.getElementById("table-create-query").textContent).then(function () {
+			button.textContent = "Copied";
+			setTimeout(function () {
+				button.textContent = "Copy DDL";
+			}, 1500);
+		});
+	});
+});
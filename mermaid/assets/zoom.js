@@ -0,0 +1,68 @@
+// Zoom and pan of the rendered mermaid diagrams: mouse wheel zooms around the cursor, dragging moves the diagram.
+// Returns the controller which centers the element of the diagram in the visible part of the diagram.
+function enableZoom(svg) {
+	var inner = document.createElementNS("http://www.w3.org/2000/svg", "g");
+	while (svg.firstChild) {
+		inner.appendChild(svg.firstChild);
+	}
+	svg.appendChild(inner);
+	var scale = 1, x = 0, y = 0, dragging = null;
+	function apply() {
+		inner.setAttribute("transform", "translate(" + x + "," + y + ") scale(" + scale + ")");
+	}
+	function point(event) {
+		var matrix = svg.getScreenCTM().inverse();
+		return {x: event.clientX * matrix.a + event.clientY * matrix.c + matrix.e, y: event.clientX * matrix.b + event.clientY * matrix.d + matrix.f};
+	}
+	svg.addEventListener("wheel", function (event) {
+		event.preventDefault();
+		var p = point(event);
+		var factor = Math.exp(-event.deltaY * 0.002);
+		var next = Math.min(Math.max(scale * factor, 0.1), 20);
+		x = p.x - (p.x - x) * next / scale;
+		y = p.y - (p.y - y) * next / scale;
+		scale = next;
+		apply();
+	}, {passive: false});
+	svg.addEventListener("mousedown", function (event) {
+		var p = point(event);
+		dragging = {x: p.x - x, y: p.y - y};
+	});
+	window.addEventListener("mousemove", function (event) {
+		if (dragging) {
+			var p = point(event);
+			x = p.x - dragging.x;
+			y = p.y - dragging.y;
+			apply();
+		}
+	});
+	window.addEventListener("mouseup", function () {
+		dragging = null;
+	});
+	return {
+		center: function (element) {
+			var box = element.getBoundingClientRect();
+			var svgBox = svg.getBoundingClientRect();
+			var left = Math.max(svgBox.left, 0), right = Math.min(svgBox.right, window.innerWidth);
+			var top = Math.max(svgBox.top, 0), bottom = Math.min(svgBox.bottom, window.innerHeight);
+			var target = point({clientX: box.left + box.width / 2, clientY: box.top + box.height / 2});
+			var middle = point({clientX: (left + right) / 2, clientY: (top + bottom) / 2});
+			x += middle.x - target.x;
+			y += middle.y - target.y;
+			apply();
+		}
+	};
+}
+
+window.addEventListener("load", function () {
+	// Click callbacks of the nodes are called only with the loose security level, labels of the nodes are escaped by the flowchart.
+	mermaid.initialize({startOnLoad: false, securityLevel: typeof selectTable === "function" ? "loose" : "strict"});
+	mermaid.run({querySelector: ".mermaid"}).then(function () {
+		document.querySelectorAll(".mermaid svg").forEach(function (svg) {
+			var zoom = enableZoom(svg);
+			if (typeof initExplorer === "function") {
+				initExplorer(svg, zoom);
+			}
+		});
+	});
+});
@@ -6,12 +6,16 @@
 //
 // Use [Html] function to generate a Mermaid HTML from the specified Mermaid string.
 //
-//	html := mermaid.Html(md, mermaid.HtmlOptions{})
+//	html, err := mermaid.Html(md, mermaid.HtmlOptions{})
 package mermaid
 
 import (
 	"github.com/mbaksheev/clickhouse-table-graph/graph"
+	"github.com/mbaksheev/clickhouse-table-graph/internal/deps"
+	"github.com/mbaksheev/clickhouse-table-graph/internal/nodeid"
 	"github.com/mbaksheev/clickhouse-table-graph/table"
+	"slices"
+	"strconv"
 	"strings"
 )
 
@@ -31,28 +35,42 @@ const (
 )
 
 // name returns the textual name of the [Orientation] in order to use it in the chart.
+// Unknown values fall back to the [TB] orientation.
 func (o Orientation) name() string {
-	return [...]string{"TB", "BT", "LR", "RL"}[o]
+	names := [...]string{"TB", "BT", "LR", "RL"}
+	if o < 0 || int(o) >= len(names) {
+		return names[TB]
+	}
+	return names[o]
 }
 
-// nodeShape represents the shape of the node in the flowchart.
-type nodeShape int
+// themeName returns the theme to use in the init directive of the chart.
+// The theme is written into the quoted string of the directive, so themes with characters other than letters, digits,
+// hyphens and underscores fall back to the "default" theme instead of breaking the directive.
+func themeName(theme string) string {
+	invalid := strings.IndexFunc(theme, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_')
+	})
+	if invalid >= 0 {
+		return "default"
+	}
+	return theme
+}
 
-// Possible values for the [nodeShape] type.
+// Grouping represents the grouping of the nodes of the flowchart into subgraphs.
+type Grouping int
+
+// Possible values for the [Grouping] type.
 const (
-	rectangle nodeShape = iota
-	rounded
-	stackedRectangle
-	hexagon
-	notchRectangle
-	winPane
+	// NoGrouping renders all nodes without subgraphs.
+	NoGrouping Grouping = iota
+	// DatabaseGrouping groups the nodes into subgraphs by database.
+	DatabaseGrouping
+	// ClusterGrouping groups the nodes into subgraphs by database,
+	// and the Distributed tables of every database into nested subgraphs by the cluster of the Distributed engine.
+	ClusterGrouping
 )
 
-// name returns the textual name of the [nodeShape] in order to use it in the chart.
-func (ns nodeShape) name() string {
-	return [...]string{"rect", "rounded", "st-rect", "hex", "notch-rect", "win-pane"}[ns]
-}
-
 // FlowchartOptions represents the options for the flowchart diagram.
 type FlowchartOptions struct {
 	// Orientation is the orientation of the flowchart graph.
@@ -61,96 +79,322 @@ type FlowchartOptions struct {
 	IncludeEngine bool
 	// Theme is the theme of the flowchart diagram.
 	// E.g. "neutral", "dark". The default value is "default". See https://mermaid.js.org/config/theming.html
+	// Themes with characters other than letters, digits, hyphens and underscores fall back to "default".
 	Theme string
-	// InitialTableHighlightColor is the color of the node border for the initial table in the flowchart diagram.
+	// InitialTableHighlightColor is the color of the node border for the initial tables in the flowchart diagram.
 	// E.g. "#ff8585", "red". If not specified, the node is not highlighted.
 	InitialTableHighlightColor string
+	// CycleHighlightColor is the color of the links and the node borders of the tables which form cycles, e.g. loops of materialized views.
+	// E.g. "#ff5757", "red". If not specified, the cycles are not highlighted.
+	CycleHighlightColor string
+	// LinkHighlights is a list of groups of links to highlight with the specified colors, e.g. links of the path between two tables.
+	LinkHighlights []graph.LinkHighlight
+	// TableHighlights is a list of groups of tables to highlight with the specified colors, e.g. tables affected by dropping a table.
+	TableHighlights []graph.TableHighlight
+	// Grouping is the grouping of the nodes into subgraphs. By default, the nodes are not grouped.
+	Grouping Grouping
+	// EngineStyles is a list of the user-defined styles of the tables with the matching engines, which override the shape and the colors
+	// of the [DefaultEngineStyles]. The first matching style is applied to the table.
+	EngineStyles []EngineStyle
+	// Legend is a flag to include the legend subgraph with one node for every engine style used in the flowchart.
+	Legend bool
+	// Clickable is a flag to add the click callbacks and the tooltips with the engine definition to the nodes of the existing tables.
+	// The callback shows the table details and highlights the upstream and downstream tables in the HTML document generated by [Html] with the Graph option.
+	Clickable bool
+	// CrossDatabaseLinkColor is the color of the links between tables of different databases if the nodes are grouped.
+	// E.g. "#7f7f7f", "gray". Such links are always rendered with the dashed stroke, if the color is not specified, the default color is used.
+	CrossDatabaseLinkColor string
 }
 
 // Flowchart generates a Mermaid flowchart diagram from the specified [graph.Links].
+// Every node is declared once with its shape and label, and then the links are written between the node identifiers.
+// Tables of every engine family get the mermaid class with the fill and border colors of the engine style.
+// Nodes and links are sorted by database and table name, so the diagram of the same graph is always the same.
+// Nodes without links, e.g. isolated tables of the full graph, are rendered as standalone nodes.
 func Flowchart(graphLinks graph.Links, options FlowchartOptions) string {
 	orientation := options.Orientation.name()
+	links := sortedLinks(graphLinks)
 
 	var mermaid strings.Builder
 	mermaid.WriteString("flowchart " + orientation + "\n")
-	mermaid.WriteString("%%{init: {'theme':'" + options.Theme + "'}}%%\n")
-	for _, link := range graphLinks.Links {
-
-		fromTableInfo, fromExists := graphLinks.TableInfo(link.FromTableKey)
-		if !fromExists {
-			writeInvalidNode(&mermaid, link.FromTableKey)
-		} else {
-			writeValidNode(&mermaid, fromTableInfo, options)
+	mermaid.WriteString("%%{init: {'theme':'" + themeName(options.Theme) + "'}}%%\n")
+	if options.Grouping == NoGrouping {
+		for _, tableKey := range graphLinks.SortedNodes() {
+			writeNode(&mermaid, graphLinks, tableKey, options)
+			mermaid.WriteString("\n")
 		}
+	} else {
+		writeSubgraphs(&mermaid, graphLinks, options)
+	}
+	if options.Legend {
+		writeLegend(&mermaid, graphLinks, options)
+	}
+	for _, link := range links {
+		mermaid.WriteString(NodeId(link.FromTableKey))
+		writeLink(&mermaid, link.Kind)
+		mermaid.WriteString(NodeId(link.ToTableKey))
+		mermaid.WriteString("\n")
+	}
+	writeClasses(&mermaid, graphLinks, options)
+	if options.Clickable {
+		writeClicks(&mermaid, graphLinks)
+	}
+	if options.Grouping != NoGrouping {
+		writeStyleForCrossDatabaseLinks(&mermaid, links, options.CrossDatabaseLinkColor)
+	}
+	highlights := graphLinks.Highlights(graph.HighlightOptions{
+		InitialTableHighlightColor: options.InitialTableHighlightColor,
+		CycleHighlightColor:        options.CycleHighlightColor,
+		LinkHighlights:             options.LinkHighlights,
+		TableHighlights:            options.TableHighlights,
+	})
+	writeStyleForHighlightedNodes(&mermaid, graphLinks, highlights)
+	writeStyleForHighlightedLinks(&mermaid, links, highlights)
+	return mermaid.String()
+}
 
-		writeLink(&mermaid)
+// sortedLinks returns the links of the graph sorted by the tables they connect and by the link kind.
+// Links are referenced by their index in this order in the link styles.
+func sortedLinks(graphLinks graph.Links) []graph.Link {
+	links := slices.Clone(graphLinks.Links)
+	slices.SortStableFunc(links, func(a, b graph.Link) int {
+		if result := compareKeys(a.FromTableKey, b.FromTableKey); result != 0 {
+			return result
+		}
+		if result := compareKeys(a.ToTableKey, b.ToTableKey); result != 0 {
+			return result
+		}
+		return int(a.Kind) - int(b.Kind)
+	})
+	return links
+}
 
-		toTableInfo, toExists := graphLinks.TableInfo(link.ToTableKey)
-		if !toExists {
-			writeInvalidNode(&mermaid, link.ToTableKey)
-		} else {
-			writeValidNode(&mermaid, toTableInfo, options)
+// compareKeys compares the table keys by database and table name.
+func compareKeys(a, b table.Key) int {
+	if result := strings.Compare(a.Database, b.Database); result != 0 {
+		return result
+	}
+	return strings.Compare(a.Name, b.Name)
+}
+
+// writeSubgraphs writes the nodes of every database in the subgraph of the database.
+// With the [ClusterGrouping], Distributed tables are written in the nested subgraphs of their clusters.
+func writeSubgraphs(stringBuildr *strings.Builder, graphLinks graph.Links, options FlowchartOptions) {
+	nodes := graphLinks.SortedNodes()
+	for start := 0; start < len(nodes); {
+		database := nodes[start].Database
+		end := start
+		for end < len(nodes) && nodes[end].Database == database {
+			end++
+		}
+		clusters := make(map[string][]table.Key)
+		stringBuildr.WriteString("subgraph " + nodeid.Stable("d", database) + " [\"" + escapeLabel(database) + "\"]\n")
+		for _, tableKey := range nodes[start:end] {
+			if cluster := clusterOf(graphLinks, tableKey); options.Grouping == ClusterGrouping && cluster != "" {
+				clusters[cluster] = append(clusters[cluster], tableKey)
+				continue
+			}
+			stringBuildr.WriteString("  ")
+			writeNode(stringBuildr, graphLinks, tableKey, options)
+			stringBuildr.WriteString("\n")
+		}
+		clusterNames := make([]string, 0, len(clusters))
+		for cluster := range clusters {
+			clusterNames = append(clusterNames, cluster)
+		}
+		slices.Sort(clusterNames)
+		for _, cluster := range clusterNames {
+			stringBuildr.WriteString("  subgraph " + nodeid.Stable("c", database, cluster) + " [\"cluster " + escapeLabel(cluster) + "\"]\n")
+			for _, tableKey := range clusters[cluster] {
+				stringBuildr.WriteString("    ")
+				writeNode(stringBuildr, graphLinks, tableKey, options)
+				stringBuildr.WriteString("\n")
+			}
+			stringBuildr.WriteString("  end\n")
+		}
+		stringBuildr.WriteString("end\n")
+		start = end
+	}
+}
+
+// clusterOf returns the cluster of the Distributed table, or an empty string for other tables.
+func clusterOf(graphLinks graph.Links, tableKey table.Key) string {
+	tableInfo, exists := graphLinks.TableInfo(tableKey)
+	if !exists || tableInfo.Engine != "Distributed" {
+		return ""
+	}
+	return deps.ClusterFromDistributedEngine(tableInfo.EngineFull)
+}
+
+// writeStyleForCrossDatabaseLinks writes the dashed style of the links between tables of different databases.
+func writeStyleForCrossDatabaseLinks(stringBuildr *strings.Builder, links []graph.Link, color string) {
+	indexes := make([]string, 0)
+	for i, link := range links {
+		if link.FromTableKey.Database != link.ToTableKey.Database {
+			indexes = append(indexes, strconv.Itoa(i))
 		}
-		mermaid.WriteString("\n")
 	}
-	if options.InitialTableHighlightColor != "" {
-		writeStyleForHighlightedNode(&mermaid, graphLinks.InitialTable, options.InitialTableHighlightColor)
+	if len(indexes) == 0 {
+		return
+	}
+	stringBuildr.WriteString("linkStyle ")
+	stringBuildr.WriteString(strings.Join(indexes, ","))
+	if color != "" {
+		stringBuildr.WriteString(" stroke:")
+		stringBuildr.WriteString(color)
+		stringBuildr.WriteString(",stroke-dasharray:6 4\n")
+	} else {
+		stringBuildr.WriteString(" stroke-dasharray:6 4\n")
+	}
+}
+
+// writeNode writes the node of the table, or the invalid node if the table does not exist.
+func writeNode(stringBuildr *strings.Builder, graphLinks graph.Links, tableKey table.Key, options FlowchartOptions) {
+	tableInfo, exists := graphLinks.TableInfo(tableKey)
+	if !exists {
+		writeInvalidNode(stringBuildr, tableKey)
+	} else {
+		writeValidNode(stringBuildr, tableInfo, options)
 	}
-	return mermaid.String()
 }
 
 func writeValidNode(stringBuildr *strings.Builder, tableInfo table.Info, options FlowchartOptions) {
-	stringBuildr.WriteString(tableInfo.Key.String())
+	stringBuildr.WriteString(NodeId(tableInfo.Key))
 	stringBuildr.WriteString("@{ shape: ")
-	stringBuildr.WriteString(shapeOf(tableInfo))
+	stringBuildr.WriteString(engineStyleOf(tableInfo.Engine, options.EngineStyles).Shape)
 	stringBuildr.WriteString(", label: \"")
 	writeNodeLabel(stringBuildr, tableInfo, options)
 	stringBuildr.WriteString("\" }")
 }
 
 func writeInvalidNode(stringBuildr *strings.Builder, tableKey table.Key) {
-	stringBuildr.WriteString(tableKey.String())
+	stringBuildr.WriteString(NodeId(tableKey))
 	stringBuildr.WriteString("@{ shape: ")
-	stringBuildr.WriteString(notchRectangle.name())
+	stringBuildr.WriteString(missingTableShape)
 	stringBuildr.WriteString(", label: \"")
-	stringBuildr.WriteString(tableKey.String())
+	stringBuildr.WriteString(escapeLabel(tableKey.String()))
 	stringBuildr.WriteString(" (table does not exist)")
 	stringBuildr.WriteString("\" }")
 }
 
-func shapeOf(tableInfo table.Info) string {
-	var shape nodeShape
-	switch tableInfo.Engine {
-	case "MaterializedView":
-		shape = hexagon
-	case "Distributed":
-		shape = stackedRectangle
-	case "Null":
-		shape = rounded
-	case "Dictionary":
-		shape = winPane
-	default:
-		shape = rectangle
-	}
-	return shape.name()
-}
-
 func writeNodeLabel(stringBuildr *strings.Builder, tableInfo table.Info, options FlowchartOptions) {
-	stringBuildr.WriteString(tableInfo.Key.String())
+	stringBuildr.WriteString(escapeLabel(tableInfo.Key.String()))
 	if options.IncludeEngine {
 		stringBuildr.WriteString(" (")
-		stringBuildr.WriteString(tableInfo.Engine)
+		stringBuildr.WriteString(escapeLabel(tableInfo.Engine))
 		stringBuildr.WriteString(")")
 	}
 }
 
-func writeLink(stringBuildr *strings.Builder) {
-	stringBuildr.WriteString(" --> ")
+// writeLink writes the arrow depending on the link kind:
+// dashed arrows for JOIN reads, dictionary lookups and dictionary sources, labelled arrows for materialized view targets and
+// regular arrows for the rest of the links.
+func writeLink(stringBuildr *strings.Builder, kind graph.LinkKind) {
+	switch kind {
+	case graph.MVTarget:
+		stringBuildr.WriteString(" -->|target| ")
+	case graph.JoinRead, graph.DictionaryLookup, graph.DictionarySource:
+		stringBuildr.WriteString(" -.-> ")
+	default:
+		stringBuildr.WriteString(" --> ")
+	}
 }
 
-func writeStyleForHighlightedNode(stringBuildr *strings.Builder, tableKey table.Key, color string) {
-	stringBuildr.WriteString("style ")
-	stringBuildr.WriteString(tableKey.String())
+// writeStyleForHighlightedNodes writes the border style of the highlighted nodes in the order of the nodes.
+// Nodes of the cycles get the dashed border.
+func writeStyleForHighlightedNodes(stringBuildr *strings.Builder, graphLinks graph.Links, highlights *graph.Highlights) {
+	for _, tableKey := range graphLinks.SortedNodes() {
+		style := highlights.Table(tableKey)
+		if style.Color == "" {
+			continue
+		}
+		stringBuildr.WriteString("style ")
+		stringBuildr.WriteString(NodeId(tableKey))
+		stringBuildr.WriteString(" stroke:")
+		stringBuildr.WriteString(style.Color)
+		if style.Dashed {
+			stringBuildr.WriteString(",stroke-dasharray:5 5")
+		}
+		stringBuildr.WriteString("\n")
+	}
+}
+
+// writeStyleForHighlightedLinks writes the style of the highlighted links, one linkStyle statement for every color.
+// Links are referenced by their index, which is the order of the links in the flowchart.
+func writeStyleForHighlightedLinks(stringBuildr *strings.Builder, links []graph.Link, highlights *graph.Highlights) {
+	colors := make([]string, 0)
+	indexes := make(map[string][]string)
+	for i, link := range links {
+		color := highlights.Link(link)
+		if color == "" {
+			continue
+		}
+		if _, found := indexes[color]; !found {
+			colors = append(colors, color)
+		}
+		indexes[color] = append(indexes[color], strconv.Itoa(i))
+	}
+	for _, color := range colors {
+		writeLinkStyle(stringBuildr, indexes[color], color)
+	}
+}
+
+// writeLinkStyle writes the style of the links with the specified indexes.
+func writeLinkStyle(stringBuildr *strings.Builder, indexes []string, color string) {
+	stringBuildr.WriteString("linkStyle ")
+	stringBuildr.WriteString(strings.Join(indexes, ","))
 	stringBuildr.WriteString(" stroke:")
 	stringBuildr.WriteString(color)
+	stringBuildr.WriteString(",stroke-width:2px\n")
+}
+
+// NodeId returns the stable identifier of the node of the table, which is valid for any database and table name.
+// Use it to reference the node in additional mermaid statements, e.g. "style" or "click".
+// It contains the database and table name with all characters except ASCII letters, digits and underscores replaced with underscores,
+// and the hash of the table key, so different tables never get the same identifier and the names like "end" do not clash with mermaid keywords.
+func NodeId(tableKey table.Key) string {
+	return nodeid.Table(tableKey)
+}
+
+// labelReplacer replaces the characters which break the quoted mermaid label or are rendered as markup with the mermaid entity codes.
+var labelReplacer = strings.NewReplacer(
+	"#", "#35;",
+	"\"", "#quot;",
+	"&", "#amp;",
+	"<", "#lt;",
+	">", "#gt;",
+	"`", "#96;",
+	"\n", " ",
+	"\r", " ",
+)
+
+// tooltipReplacer replaces the characters which break the quoted mermaid tooltip or are rendered as markup.
+var tooltipReplacer = strings.NewReplacer(
+	"\"", "'",
+	"<", "‹",
+	">", "›",
+	"\n", " ",
+	"\r", " ",
+)
+
+// writeClicks writes the click callback with the tooltip for every existing table of the graph.
+// The tooltip is the engine definition of the table, the callback is called with the node identifier.
+func writeClicks(stringBuildr *strings.Builder, graphLinks graph.Links) {
+	for _, tableKey := range graphLinks.SortedNodes() {
+		tableInfo, exists := graphLinks.TableInfo(tableKey)
+		if !exists {
+			continue
+		}
+		tooltip := tableInfo.EngineFull
+		if tooltip == "" {
+			tooltip = tableInfo.Engine
+		}
+		stringBuildr.WriteString("click " + NodeId(tableKey) + " " + clickCallback + " \"" + tooltipReplacer.Replace(tooltip) + "\"\n")
+	}
+}
+
+// escapeLabel returns the text of the label with the special characters replaced with the mermaid entity codes,
+// so the text is rendered as is. See https://mermaid.js.org/syntax/flowchart.html#entity-codes-to-escape-characters
+func escapeLabel(text string) string {
+	return labelReplacer.Replace(text)
 }
@@ -0,0 +1,353 @@
+package mermaid
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mbaksheev/clickhouse-table-graph/graph"
+	"github.com/mbaksheev/clickhouse-table-graph/internal/nodeid"
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+// nastyKeys is a corpus of table keys which are valid ClickHouse identifiers, but break the mermaid syntax if used as is.
+var nastyKeys = []table.Key{
+	{Database: "db", Name: "with-dash"},
+	{Database: "db", Name: "with space"},
+	{Database: "my db", Name: "events"},
+	{Database: "db", Name: "ünïcødé"},
+	{Database: "db", Name: "名前"},
+	{Database: "db", Name: `quo"te`},
+	{Database: "db", Name: "single'quote"},
+	{Database: "db", Name: "back`tick"},
+	{Database: "db", Name: "end"},
+	{Database: "end", Name: "graph"},
+	{Database: "db", Name: "subgraph"},
+	{Database: "db", Name: "style"},
+	{Database: "db", Name: "click"},
+	{Database: "db", Name: "flowchart"},
+	{Database: "db", Name: "a#quot;b"},
+	{Database: "db", Name: "<b>bold</b>"},
+	{Database: "db", Name: "amp&amp;"},
+	{Database: "db", Name: "semi;colon"},
+	{Database: "db", Name: "pipe|name"},
+	{Database: "db", Name: "brackets[]{}()"},
+	{Database: "db", Name: "dotted.name"},
+	{Database: "db.dotted", Name: "name"},
+	{Database: "db", Name: "with_dash"},
+	{Database: "db", Name: "%%comment"},
+	{Database: "db", Name: "-->"},
+	{Database: "db", Name: "line\nbreak"},
+	{Database: "db", Name: ""},
+}
+
+var (
+	validNodeId          = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	validNodeIds         = regexp.MustCompile(`^[A-Za-z0-9_]+(,[A-Za-z0-9_]+)*$`)
+	nodeDeclaration      = regexp.MustCompile(`^([A-Za-z0-9_]+)@\{ shape: [a-z-]+, label: "([^"]*)" \}$`)
+	mermaidEntityPattern = regexp.MustCompile(`#(\d+|quot|amp|lt|gt);`)
+)
+
+func TestNodeId(t *testing.T) {
+	ids := make(map[string]table.Key)
+	for _, tableKey := range nastyKeys {
+		id := NodeId(tableKey)
+		if !validNodeId.MatchString(id) {
+			t.Errorf("NodeId(%q) = %q, want only ASCII letters, digits and underscores", tableKey, id)
+		}
+		if other, exists := ids[id]; exists {
+			t.Errorf("NodeId(%q) = NodeId(%q) = %q", tableKey, other, id)
+		}
+		ids[id] = tableKey
+		if again := NodeId(tableKey); again != id {
+			t.Errorf("NodeId(%q) is not stable: %q and %q", tableKey, id, again)
+		}
+	}
+}
+
+func TestOrientationName(t *testing.T) {
+	tests := []struct {
+		orientation Orientation
+		want        string
+	}{
+		{orientation: TB, want: "TB"},
+		{orientation: RL, want: "RL"},
+		{orientation: Orientation(4), want: "TB"},
+		{orientation: Orientation(-1), want: "TB"},
+	}
+	for _, tt := range tests {
+		if got := tt.orientation.name(); got != tt.want {
+			t.Errorf("Orientation(%d).name() = %s, want %s", tt.orientation, got, tt.want)
+		}
+	}
+	if got := Flowchart(graph.Links{}, FlowchartOptions{Orientation: Orientation(42)}); !strings.Contains(got, "flowchart TB\n") {
+		t.Errorf("Flowchart() with unknown Orientation does not fall back to TB:\n%s", got)
+	}
+}
+
+func TestThemeName(t *testing.T) {
+	tests := []struct {
+		theme string
+		want  string
+	}{
+		{theme: "", want: ""},
+		{theme: "dark", want: "dark"},
+		{theme: "my-theme_2", want: "my-theme_2"},
+		{theme: "dark'}}%%\nflowchart LR", want: "default"},
+		{theme: "x\"><script>", want: "default"},
+	}
+	for _, tt := range tests {
+		if got := themeName(tt.theme); got != tt.want {
+			t.Errorf("themeName(%q) = %q, want %q", tt.theme, got, tt.want)
+		}
+	}
+	got := Flowchart(graph.Links{}, FlowchartOptions{Theme: "dark'}}%%\nclick x call alert()"})
+	if !strings.HasPrefix(got, "flowchart TB\n%%{init: {'theme':'default'}}%%\n") || strings.Contains(got, "alert") {
+		t.Errorf("Flowchart() with invalid Theme does not fall back to default:\n%s", got)
+	}
+}
+
+func TestFlowchartNastyIdentifiers(t *testing.T) {
+	b := graph.New()
+	for _, tableKey := range nastyKeys {
+		b.AddTable(table.Info{Key: tableKey, Engine: "MergeTree"})
+	}
+	flowchart := Flowchart(*b.FullGraph(), FlowchartOptions{TableHighlights: []graph.TableHighlight{{Tables: nastyKeys, Color: "red"}}})
+
+	labels := make(map[string]string)
+	for _, line := range strings.Split(strings.TrimSpace(flowchart), "\n")[2:] {
+		if strings.HasPrefix(line, "style ") || strings.HasPrefix(line, "class ") || strings.HasPrefix(line, "classDef ") {
+			if fields := strings.Fields(line); len(fields) != 3 || !validNodeIds.MatchString(fields[1]) {
+				t.Errorf("invalid %s statement: %q", fields[0], line)
+			}
+			continue
+		}
+		match := nodeDeclaration.FindStringSubmatch(line)
+		if match == nil {
+			t.Errorf("invalid node declaration: %q", line)
+			continue
+		}
+		labels[match[1]] = match[2]
+	}
+	for _, tableKey := range nastyKeys {
+		label, exists := labels[NodeId(tableKey)]
+		if !exists {
+			t.Errorf("node of %q is not declared", tableKey)
+			continue
+		}
+		want := strings.ReplaceAll(tableKey.String(), "\n", " ")
+		if got := unescapeLabel(label); got != want {
+			t.Errorf("label of %q = %q, unescaped = %q, want %q", tableKey, label, got, want)
+		}
+		if strings.ContainsAny(label, "\"<>`&\n") {
+			t.Errorf("label of %q contains unescaped characters: %q", tableKey, label)
+		}
+	}
+}
+
+func TestFlowchartDeclaresNodesOnce(t *testing.T) {
+	source := table.Key{Database: "db", Name: "source"}
+	b := graph.New()
+	b.AddTable(table.Info{Key: source, Engine: "Null"})
+	for _, name := range []string{"mv_c", "mv_a", "mv_b"} {
+		b.AddTable(table.Info{
+			Key:              table.Key{Database: "db", Name: name},
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db." + name + " TO db.target AS SELECT * FROM db.source",
+			AsSelect:         "SELECT * FROM db.source",
+		})
+	}
+	b.AddTable(table.Info{Key: table.Key{Database: "db", Name: "target"}, Engine: "MergeTree"})
+	fullGraph := b.FullGraph()
+	options := FlowchartOptions{
+		IncludeEngine:  true,
+		LinkHighlights: []graph.LinkHighlight{{Links: []graph.Link{{FromTableKey: table.Key{Database: "db", Name: "mv_b"}, ToTableKey: table.Key{Database: "db", Name: "target"}}}, Color: "red"}},
+	}
+	flowchart := Flowchart(*fullGraph, options)
+
+	id := func(name string) string {
+		return NodeId(table.Key{Database: "db", Name: name})
+	}
+	want := "flowchart TB\n" +
+		"%%{init: {'theme':''}}%%\n" +
+		id("mv_a") + "@{ shape: hex, label: \"db.mv_a (MaterializedView)\" }\n" +
+		id("mv_b") + "@{ shape: hex, label: \"db.mv_b (MaterializedView)\" }\n" +
+		id("mv_c") + "@{ shape: hex, label: \"db.mv_c (MaterializedView)\" }\n" +
+		id("source") + "@{ shape: rounded, label: \"db.source (Null)\" }\n" +
+		id("target") + "@{ shape: rect, label: \"db.target (MergeTree)\" }\n" +
+		id("mv_a") + " -->|target| " + id("target") + "\n" +
+		id("mv_b") + " -->|target| " + id("target") + "\n" +
+		id("mv_c") + " -->|target| " + id("target") + "\n" +
+		id("source") + " --> " + id("mv_a") + "\n" +
+		id("source") + " --> " + id("mv_b") + "\n" +
+		id("source") + " --> " + id("mv_c") + "\n" +
+		"classDef " + classOf(engineStyleOf("MaterializedView", nil)) + " fill:#e8eaf6,stroke:#3f51b5\n" +
+		"class " + id("mv_a") + "," + id("mv_b") + "," + id("mv_c") + " " + classOf(engineStyleOf("MaterializedView", nil)) + "\n" +
+		"classDef " + classOf(engineStyleOf("MergeTree", nil)) + " fill:#f1f8e9,stroke:#7cb342\n" +
+		"class " + id("target") + " " + classOf(engineStyleOf("MergeTree", nil)) + "\n" +
+		"classDef " + classOf(engineStyleOf("Null", nil)) + " fill:#f5f5f5,stroke:#757575\n" +
+		"class " + id("source") + " " + classOf(engineStyleOf("Null", nil)) + "\n" +
+		"linkStyle 1 stroke:red,stroke-width:2px\n"
+	if flowchart != want {
+		t.Errorf("Flowchart() =\n%s\nWant =\n%s", flowchart, want)
+	}
+
+	reversed := *fullGraph
+	reversed.Links = slices.Clone(fullGraph.Links)
+	slices.Reverse(reversed.Links)
+	reversed.Nodes = slices.Clone(fullGraph.Nodes)
+	slices.Reverse(reversed.Nodes)
+	if got := Flowchart(reversed, options); got != flowchart {
+		t.Errorf("Flowchart() depends on the order of links and nodes:\n%s\nWant =\n%s", got, flowchart)
+	}
+}
+
+func TestFlowchartGrouping(t *testing.T) {
+	b := graph.New()
+	b.AddTable(table.Info{Key: table.Key{Database: "raw", Name: "events"}, Engine: "MergeTree"})
+	b.AddTable(table.Info{
+		Key:        table.Key{Database: "raw", Name: "events_dist"},
+		Engine:     "Distributed",
+		EngineFull: "Distributed('main', 'raw', 'events')",
+	})
+	b.AddTable(table.Info{
+		Key:              table.Key{Database: "stats", Name: "events_mv"},
+		Engine:           "MaterializedView",
+		CreateTableQuery: "CREATE MATERIALIZED VIEW stats.events_mv TO stats.daily AS SELECT * FROM raw.events",
+		AsSelect:         "SELECT * FROM raw.events",
+	})
+	b.AddTable(table.Info{Key: table.Key{Database: "stats", Name: "daily"}, Engine: "MergeTree"})
+	fullGraph := b.FullGraph()
+
+	id := func(database, name string) string {
+		return NodeId(table.Key{Database: database, Name: name})
+	}
+	nodes := "  " + id("raw", "events") + "@{ shape: rect, label: \"raw.events\" }\n"
+	distributedNode := id("raw", "events_dist") + "@{ shape: st-rect, label: \"raw.events_dist\" }\n"
+	statsSubgraph := "subgraph " + nodeid.Stable("d", "stats") + " [\"stats\"]\n" +
+		"  " + id("stats", "daily") + "@{ shape: rect, label: \"stats.daily\" }\n" +
+		"  " + id("stats", "events_mv") + "@{ shape: hex, label: \"stats.events_mv\" }\n" +
+		"end\n"
+	links := id("raw", "events") + " --> " + id("raw", "events_dist") + "\n" +
+		id("raw", "events") + " --> " + id("stats", "events_mv") + "\n" +
+		id("stats", "events_mv") + " -->|target| " + id("stats", "daily") + "\n" +
+		"classDef " + classOf(engineStyleOf("Distributed", nil)) + " fill:#e0f7fa,stroke:#00838f\n" +
+		"class " + id("raw", "events_dist") + " " + classOf(engineStyleOf("Distributed", nil)) + "\n" +
+		"classDef " + classOf(engineStyleOf("MaterializedView", nil)) + " fill:#e8eaf6,stroke:#3f51b5\n" +
+		"class " + id("stats", "events_mv") + " " + classOf(engineStyleOf("MaterializedView", nil)) + "\n" +
+		"classDef " + classOf(engineStyleOf("MergeTree", nil)) + " fill:#f1f8e9,stroke:#7cb342\n" +
+		"class " + id("raw", "events") + "," + id("stats", "daily") + " " + classOf(engineStyleOf("MergeTree", nil)) + "\n"
+
+	tests := []struct {
+		name    string
+		options FlowchartOptions
+		want    string
+	}{
+		{
+			name:    "database grouping",
+			options: FlowchartOptions{Grouping: DatabaseGrouping},
+			want: "subgraph " + nodeid.Stable("d", "raw") + " [\"raw\"]\n" + nodes + "  " + distributedNode + "end\n" + statsSubgraph +
+				links + "linkStyle 1 stroke-dasharray:6 4\n",
+		},
+		{
+			name:    "cluster grouping",
+			options: FlowchartOptions{Grouping: ClusterGrouping, CrossDatabaseLinkColor: "gray"},
+			want: "subgraph " + nodeid.Stable("d", "raw") + " [\"raw\"]\n" + nodes +
+				"  subgraph " + nodeid.Stable("c", "raw", "main") + " [\"cluster main\"]\n" + "    " + distributedNode + "  end\n" +
+				"end\n" + statsSubgraph + links + "linkStyle 1 stroke:gray,stroke-dasharray:6 4\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Flowchart(*fullGraph, tt.options)
+			want := "flowchart TB\n%%{init: {'theme':''}}%%\n" + tt.want
+			if got != want {
+				t.Errorf("Flowchart() =\n%s\nWant =\n%s", got, want)
+			}
+		})
+	}
+}
+
+func TestEngineStyleOf(t *testing.T) {
+	userStyles := []EngineStyle{
+		{Name: "Kafka topics", Engines: []string{"Kafka"}, Fill: "#ffffff"},
+		{EngineRegex: regexp.MustCompile(`^Replicated`), Shape: "cyl"},
+	}
+	tests := []struct {
+		engine string
+		styles []EngineStyle
+		want   EngineStyle
+	}{
+		{engine: "MergeTree", want: EngineStyle{Name: "MergeTree", Shape: "rect", Fill: "#f1f8e9", Stroke: "#7cb342"}},
+		{engine: "ReplacingMergeTree", want: EngineStyle{Name: "MergeTree", Shape: "rect", Fill: "#f1f8e9", Stroke: "#7cb342"}},
+		{engine: "ReplicatedAggregatingMergeTree", want: EngineStyle{Name: "Aggregating MergeTree", Shape: "rect", Fill: "#f9fbe7", Stroke: "#9e9d24"}},
+		{engine: "ReplicatedReplacingMergeTree", want: EngineStyle{Name: "Replicated MergeTree", Shape: "rect", Fill: "#e8f5e9", Stroke: "#1b5e20"}},
+		{engine: "Kafka", want: EngineStyle{Name: "Queue", Shape: "h-cyl", Fill: "#fbe9e7", Stroke: "#d84315"}},
+		{engine: "Buffer", want: EngineStyle{Name: "Buffer", Shape: "bow-rect", Fill: "#fce4ec", Stroke: "#ad1457"}},
+		{engine: "Join", want: EngineStyle{Name: "Join, Set", Shape: "div-rect", Fill: "#f3e5f5", Stroke: "#8e24aa"}},
+		{engine: "GenerateRandom", want: EngineStyle{Name: "Other", Shape: "rect"}},
+		{engine: "Kafka", styles: userStyles, want: EngineStyle{Name: "Kafka topics", Shape: "h-cyl", Fill: "#ffffff", Stroke: "#d84315"}},
+		{engine: "ReplicatedMergeTree", styles: userStyles, want: EngineStyle{Name: "^Replicated", Shape: "cyl", Fill: "#e8f5e9", Stroke: "#1b5e20"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.engine, func(t *testing.T) {
+			got := engineStyleOf(tt.engine, tt.styles)
+			if got.Name != tt.want.Name || got.Shape != tt.want.Shape || got.Fill != tt.want.Fill || got.Stroke != tt.want.Stroke {
+				t.Errorf("engineStyleOf() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFlowchartLegend(t *testing.T) {
+	b := graph.New()
+	b.AddTable(table.Info{Key: table.Key{Database: "db", Name: "queue"}, Engine: "Kafka"})
+	b.AddTable(table.Info{
+		Key:              table.Key{Database: "db", Name: "queue_mv"},
+		Engine:           "MaterializedView",
+		CreateTableQuery: "CREATE MATERIALIZED VIEW db.queue_mv TO db.missing AS SELECT * FROM db.queue",
+		AsSelect:         "SELECT * FROM db.queue",
+	})
+	flowchart := Flowchart(*b.FullGraph(), FlowchartOptions{
+		Legend:       true,
+		EngineStyles: []EngineStyle{{Name: "Kafka", Engines: []string{"Kafka"}, Fill: "#000000"}},
+	})
+	kafka := engineStyleOf("Kafka", []EngineStyle{{Name: "Kafka", Engines: []string{"Kafka"}}})
+	mv := engineStyleOf("MaterializedView", nil)
+	for _, part := range []string{
+		"subgraph legend [\"Legend\"]\n" +
+			"  " + legendNodeId(kafka) + "@{ shape: h-cyl, label: \"Kafka\" }\n" +
+			"  " + legendNodeId(mv) + "@{ shape: hex, label: \"MaterializedView\" }\n" +
+			"  legend_missing@{ shape: notch-rect, label: \"table does not exist\" }\n" +
+			"end\n",
+		"classDef " + classOf(kafka) + " fill:#000000,stroke:#d84315\n",
+		"class " + NodeId(table.Key{Database: "db", Name: "queue"}) + "," + legendNodeId(kafka) + " " + classOf(kafka) + "\n",
+	} {
+		if !strings.Contains(flowchart, part) {
+			t.Errorf("Flowchart() does not contain\n%s\ngot:\n%s", part, flowchart)
+		}
+	}
+}
+
+// unescapeLabel returns the label text with the mermaid entity codes replaced with the characters.
+func unescapeLabel(label string) string {
+	return mermaidEntityPattern.ReplaceAllStringFunc(label, func(entity string) string {
+		switch code := entity[1 : len(entity)-1]; code {
+		case "quot":
+			return `"`
+		case "amp":
+			return "&"
+		case "lt":
+			return "<"
+		case "gt":
+			return ">"
+		default:
+			var r rune
+			for _, digit := range code {
+				r = r*10 + digit - '0'
+			}
+			return string(r)
+		}
+	})
+}
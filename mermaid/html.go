@@ -1,70 +1,155 @@
 package mermaid
 
-import "fmt"
+import (
+	"embed"
+	"fmt"
+	"html/template"
+	"strings"
 
-const defaultMermaidJsUrl = "https://cdn.jsdelivr.net/npm/mermaid@11/dist/mermaid.min.js"
+	"github.com/mbaksheev/clickhouse-table-graph/graph"
+	"github.com/mbaksheev/clickhouse-table-graph/internal/deps"
+	"github.com/mbaksheev/clickhouse-table-graph/internal/htmlscript"
+)
+
+//go:generate curl -sSfL -o assets/mermaid.min.js https://cdn.jsdelivr.net/npm/mermaid@11.12.2/dist/mermaid.min.js
+
+// assets contains the template of the HTML document and the JavaScript embedded into it: the zoom and pan script,
+// the explorer script and the Mermaid JS library 11.12.2 for the offline documents (MIT License, see assets/mermaid.LICENSE).
+// The library is updated with go generate, the version must match the defaultMermaidJsUrl.
+//
+//go:embed assets
+var assets embed.FS
+
+// htmlTemplate is the template of the HTML document. The title and the diagram are escaped by the template.
+var htmlTemplate = template.Must(template.ParseFS(assets, "assets/flowchart.html"))
+
+const defaultMermaidJsUrl = "https://cdn.jsdelivr.net/npm/mermaid@11.12.2/dist/mermaid.min.js"
 const defaultTitle = "ClickHouse table dependencies graph"
 
+// clickCallback is the name of the JavaScript function of the HTML document which shows the table details in the side panel
+// and highlights the upstream and downstream tables. It is called by mermaid with the node identifier when the node is clicked.
+const clickCallback = "selectTable"
+
 // HtmlOptions represents options for the Html function.
 type HtmlOptions struct {
 	// Title is the title of the HTML document.
 	Title string
 
-	// MermaidJsUrl is the URL of the Mermaid JS library. Optional.
+	// MermaidJsUrl is the URL of the Mermaid JS library. Optional. It is ignored if Offline is true.
+	// The URL must be an absolute http or https URL, or a relative URL without the scheme.
 	MermaidJsUrl string
+
+	// Offline is a flag to embed the Mermaid JS library into the HTML document instead of loading it from MermaidJsUrl,
+	// so the document is rendered without network access. The document is about 3 MB larger.
+	Offline bool
+
+	// Graph is the graph rendered in the diagram. Optional. If specified, the tables and the links of the graph are embedded
+	// into the document as JSON, and the document becomes the interactive explorer of the graph: the search box finds and centers the table,
+	// the click on the node highlights its upstream and downstream tables and shows the side panel with the engine, the sorting key
+	// and the create query of the table, and the tables of the selected engines can be hidden.
+	// The diagram must be generated from the same graph with the Clickable flowchart option.
+	Graph *graph.Links
+}
+
+// htmlData is the data of the HTML document template.
+type htmlData struct {
+	Title        string
+	Diagram      string
+	MermaidJsUrl string
+	MermaidJs    template.JS
+	ZoomJs       template.JS
+	ExplorerJs   template.JS
+	Graph        *graphData
+}
+
+// graphData represents the graph embedded into the HTML document for the interactive explorer.
+type graphData struct {
+	// Tables are the details of all tables of the graph by their node identifiers.
+	Tables map[string]tableDetails `json:"tables"`
+	// Links are the links of the graph in the order of the flowchart links.
+	Links []linkDetails `json:"links"`
+}
+
+// tableDetails represents the details of the table shown in the side panel of the HTML document.
+type tableDetails struct {
+	Name             string `json:"name"`
+	Exists           bool   `json:"exists"`
+	Engine           string `json:"engine"`
+	EngineFull       string `json:"engine_full"`
+	SortingKey       string `json:"sorting_key"`
+	CreateTableQuery string `json:"create_table_query"`
+}
+
+// linkDetails represents the link between two tables by their node identifiers.
+type linkDetails struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+	Kind string `json:"kind"`
 }
 
 // Html generates a full HTML document with the Mermaid flowchart diagram.
-func Html(mermaidString string, options HtmlOptions) string {
-	var mermaidJsUrl string
-	if options.MermaidJsUrl == "" {
-		mermaidJsUrl = defaultMermaidJsUrl
-	} else {
-		mermaidJsUrl = options.MermaidJsUrl
+// The zoom and pan script is always embedded into the document, the Mermaid JS library is embedded if the Offline option is set.
+// If the Graph option is set, the graph and the explorer script are embedded as well, so the explorer works without network access.
+// The title and the diagram are escaped, so table names with HTML markup are rendered as text.
+func Html(mermaidString string, options HtmlOptions) (string, error) {
+	data := htmlData{
+		Title:        options.Title,
+		Diagram:      mermaidString,
+		MermaidJsUrl: options.MermaidJsUrl,
+	}
+	if data.Title == "" {
+		data.Title = defaultTitle
+	}
+	if data.MermaidJsUrl == "" {
+		data.MermaidJsUrl = defaultMermaidJsUrl
+	} else if err := htmlscript.ValidateUrl(data.MermaidJsUrl); err != nil {
+		return "", fmt.Errorf("Html: %w", err)
+	}
+	zoomJs, err := assets.ReadFile("assets/zoom.js")
+	if err != nil {
+		return "", fmt.Errorf("Html: failed to read zoom script: %w", err)
 	}
-	var title string
-	if options.Title == "" {
-		title = defaultTitle
-	} else {
-		title = options.Title
+	data.ZoomJs = htmlscript.Content(zoomJs)
+	if options.Offline {
+		mermaidJs, err := assets.ReadFile("assets/mermaid.min.js")
+		if err != nil {
+			return "", fmt.Errorf("Html: failed to read Mermaid JS library: %w", err)
+		}
+		data.MermaidJs = htmlscript.Content(mermaidJs)
 	}
+	if options.Graph != nil {
+		explorerJs, err := assets.ReadFile("assets/explorer.js")
+		if err != nil {
+			return "", fmt.Errorf("Html: failed to read explorer script: %w", err)
+		}
+		data.ExplorerJs = htmlscript.Content(explorerJs)
+		data.Graph = explorerData(*options.Graph)
+	}
+
+	var html strings.Builder
+	if err := htmlTemplate.Execute(&html, data); err != nil {
+		return "", fmt.Errorf("Html: failed to render HTML document: %w", err)
+	}
+	return html.String(), nil
+}
 
-	return fmt.Sprintf(`
-<html lang="en">
-	<head>
-    	<meta charset="UTF-8">
-    	<title>ClickHouse table graph - %s</title>
-    	<script src="%s"></script>
-		<script src="https://d3js.org/d3.v6.min.js"></script> <!-- For zoom and Pan	-->
-    	<script>mermaid.initialize({startOnLoad:true});</script>
-		<script>  <!-- For zoom and Pan	-->
-			window.addEventListener('load', function () {
-				var svgs = d3.selectAll(".mermaid svg");
-				svgs.each(function() {
-					var svg = d3.select(this);
-					svg.html("<g>" + svg.html() + "</g>");
-					var inner = svg.select("g");
-					var zoom = d3.zoom().on("zoom", function(event) {
-						inner.attr("transform", event.transform);
-					});
-					svg.call(zoom);
-				});
-			});
-		</script>
-		<style>
-			.mermaid svg {
-				max-width: 100%%;
-				height: 100%%;
-			}
-		</style>
-	</head>
-	<body>
-<h3>%s</h3>
-		<pre class="mermaid">
-			%s
-		</pre>
-	</body>
-</html>
-
-`, title, mermaidJsUrl, title, mermaidString)
+// explorerData returns the tables of the graph by their node identifiers and the links between them.
+// Tables which do not exist have only the name.
+func explorerData(graphLinks graph.Links) *graphData {
+	data := &graphData{Tables: make(map[string]tableDetails), Links: make([]linkDetails, 0, len(graphLinks.Links))}
+	for _, tableKey := range graphLinks.SortedNodes() {
+		details := tableDetails{Name: tableKey.String()}
+		if tableInfo, exists := graphLinks.TableInfo(tableKey); exists {
+			details.Exists = true
+			details.Engine = tableInfo.Engine
+			details.EngineFull = tableInfo.EngineFull
+			details.SortingKey = deps.SortingKeyFromEngine(tableInfo.EngineFull)
+			details.CreateTableQuery = tableInfo.CreateTableQuery
+		}
+		data.Tables[NodeId(tableKey)] = details
+	}
+	for _, link := range sortedLinks(graphLinks) {
+		data.Links = append(data.Links, linkDetails{From: NodeId(link.FromTableKey), To: NodeId(link.ToTableKey), Kind: link.Kind.String()})
+	}
+	return data
 }
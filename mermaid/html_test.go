@@ -0,0 +1,205 @@
+package mermaid
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/mbaksheev/clickhouse-table-graph/graph"
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+func TestHtml(t *testing.T) {
+	html, err := Html("flowchart TB\na --> b", HtmlOptions{Title: "test graph"})
+	if err != nil {
+		t.Fatalf("Html() error = %v", err)
+	}
+	for _, part := range []string{
+		`<script src="` + defaultMermaidJsUrl + `"></script>`,
+		"function enableZoom(svg)",
+		"<title>ClickHouse table graph - test graph</title>",
+		"flowchart TB\na --&gt; b",
+	} {
+		if !strings.Contains(html, part) {
+			t.Errorf("Html() does not contain %q:\n%s", part, html)
+		}
+	}
+	if strings.Contains(html, "d3js.org") {
+		t.Errorf("Html() loads d3 from the network")
+	}
+}
+
+func TestHtmlOffline(t *testing.T) {
+	b := graph.New()
+	b.AddTable(table.Info{Key: table.Key{Database: "db", Name: "a"}, Engine: "MergeTree"})
+	graphLinks := b.FullGraph()
+	flowchart := Flowchart(*graphLinks, FlowchartOptions{Clickable: true})
+	html, err := Html(flowchart, HtmlOptions{Offline: true, MermaidJsUrl: "https://example.com/mermaid.js", Graph: graphLinks})
+	if err != nil {
+		t.Fatalf("Html() error = %v", err)
+	}
+	if strings.Contains(html, "<script src=") || strings.Contains(html, "example.com") {
+		t.Errorf("offline Html() loads scripts from the network")
+	}
+	if !strings.Contains(html, `globalThis["mermaid"]`) {
+		t.Errorf("offline Html() does not embed the Mermaid JS library")
+	}
+	if strings.Count(html, "</script") != strings.Count(html, "<script") {
+		t.Errorf("offline Html() has unbalanced script elements")
+	}
+}
+
+func TestHtmlHostileNames(t *testing.T) {
+	hostileKey := table.Key{Database: `db"><script>alert(1)</script>`, Name: `t</pre><img src=x onerror=alert(2)>`}
+	b := graph.New()
+	b.AddTable(table.Info{Key: hostileKey, Engine: "MergeTree"})
+	flowchart := Flowchart(*b.FullGraph(), FlowchartOptions{IncludeEngine: true})
+
+	tests := []struct {
+		name    string
+		title   string
+		diagram string
+	}{
+		{name: "hostile title", title: `</title><script>alert(3)</script>`, diagram: "flowchart TB\na --> b"},
+		{name: "hostile quotes in title", title: `" onload="alert(4)`, diagram: "flowchart TB\na --> b"},
+		{name: "hostile table name", title: "graph", diagram: flowchart},
+		{name: "closing pre tag", title: "graph", diagram: "flowchart TB\n</pre><pre>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			html, err := Html(tt.diagram, HtmlOptions{Title: tt.title})
+			if err != nil {
+				t.Fatalf("Html() error = %v", err)
+			}
+			for _, injected := range []string{"<script>alert", "<img", `onload="alert`} {
+				if strings.Contains(html, injected) {
+					t.Errorf("Html() contains injected markup %q:\n%s", injected, html)
+				}
+			}
+			if count := strings.Count(html, "</pre>"); count != 1 {
+				t.Errorf("Html() contains %d closing pre tags, want 1", count)
+			}
+			if count := strings.Count(html, "</title>"); count != 1 {
+				t.Errorf("Html() contains %d closing title tags, want 1", count)
+			}
+		})
+	}
+}
+
+func TestHtmlExplorer(t *testing.T) {
+	hostileKey := table.Key{Database: "db", Name: `events</script><script>alert(1)</script>`}
+	b := graph.New()
+	b.AddTable(table.Info{
+		Key:              hostileKey,
+		Engine:           "ReplicatedMergeTree",
+		EngineFull:       "ReplicatedMergeTree('/tables/events', '{replica}') PARTITION BY date ORDER BY (id, date) SETTINGS index_granularity = 8192",
+		CreateTableQuery: "CREATE TABLE db.`events</script><script>alert(1)</script>` (id UInt64, date Date) ENGINE = ReplicatedMergeTree",
+	})
+	b.AddTable(table.Info{
+		Key:              table.Key{Database: "db", Name: "events_mv"},
+		Engine:           "MaterializedView",
+		CreateTableQuery: "CREATE MATERIALIZED VIEW db.events_mv TO db.missing AS SELECT * FROM db.`events</script><script>alert(1)</script>`",
+		AsSelect:         "SELECT * FROM db.`events</script><script>alert(1)</script>`",
+	})
+	fullGraph := b.FullGraph()
+	flowchart := Flowchart(*fullGraph, FlowchartOptions{Clickable: true})
+	for _, part := range []string{
+		"click " + NodeId(hostileKey) + " selectTable \"ReplicatedMergeTree('/tables/events', '{replica}') PARTITION BY date ORDER BY (id, date) SETTINGS index_granularity = 8192\"\n",
+		"click " + NodeId(table.Key{Database: "db", Name: "events_mv"}) + " selectTable \"MaterializedView\"\n",
+	} {
+		if !strings.Contains(flowchart, part) {
+			t.Errorf("Flowchart() does not contain\n%s\ngot:\n%s", part, flowchart)
+		}
+	}
+	if strings.Contains(flowchart, "click "+NodeId(table.Key{Database: "db", Name: "missing"})) {
+		t.Errorf("Flowchart() contains click callback of the table which does not exist:\n%s", flowchart)
+	}
+
+	html, err := Html(flowchart, HtmlOptions{Graph: fullGraph})
+	if err != nil {
+		t.Fatalf("Html() error = %v", err)
+	}
+	if strings.Contains(html, "<script>alert") {
+		t.Errorf("Html() contains injected markup:\n%s", html)
+	}
+	start := strings.Index(html, `<script type="application/json" id="graph-data">`)
+	if start < 0 {
+		t.Fatalf("Html() does not contain graph data:\n%s", html)
+	}
+	content := html[start+len(`<script type="application/json" id="graph-data">`):]
+	content = content[:strings.Index(content, "</script>")]
+	var got graphData
+	if err := json.Unmarshal([]byte(content), &got); err != nil {
+		t.Fatalf("failed to parse graph data: %v\n%s", err, content)
+	}
+	mv := NodeId(table.Key{Database: "db", Name: "events_mv"})
+	missing := NodeId(table.Key{Database: "db", Name: "missing"})
+	wantTables := map[string]tableDetails{
+		NodeId(hostileKey): {
+			Name:             hostileKey.String(),
+			Exists:           true,
+			Engine:           "ReplicatedMergeTree",
+			EngineFull:       "ReplicatedMergeTree('/tables/events', '{replica}') PARTITION BY date ORDER BY (id, date) SETTINGS index_granularity = 8192",
+			SortingKey:       "(id, date)",
+			CreateTableQuery: "CREATE TABLE db.`events</script><script>alert(1)</script>` (id UInt64, date Date) ENGINE = ReplicatedMergeTree",
+		},
+		mv: {
+			Name:             "db.events_mv",
+			Exists:           true,
+			Engine:           "MaterializedView",
+			CreateTableQuery: "CREATE MATERIALIZED VIEW db.events_mv TO db.missing AS SELECT * FROM db.`events</script><script>alert(1)</script>`",
+		},
+		missing: {Name: "db.missing"},
+	}
+	if !reflect.DeepEqual(got.Tables, wantTables) {
+		t.Errorf("tables = %+v, want %+v", got.Tables, wantTables)
+	}
+	wantLinks := []linkDetails{
+		{From: NodeId(hostileKey), To: mv, Kind: "MVTrigger"},
+		{From: mv, To: missing, Kind: "MVTarget"},
+	}
+	if !reflect.DeepEqual(got.Links, wantLinks) {
+		t.Errorf("links = %+v, want %+v", got.Links, wantLinks)
+	}
+	for _, part := range []string{"function selectTable(nodeId)", `id="table-panel"`, `id="table-search"`, `id="engine-toggles"`} {
+		if !strings.Contains(html, part) {
+			t.Errorf("Html() does not contain the explorer %q:\n%s", part, html)
+		}
+	}
+
+	html, err = Html(flowchart, HtmlOptions{})
+	if err != nil {
+		t.Fatalf("Html() error = %v", err)
+	}
+	if strings.Contains(html, "graph-data") || strings.Contains(html, "table-panel") || strings.Contains(html, "table-search") {
+		t.Errorf("Html() contains the explorer without the graph:\n%s", html)
+	}
+}
+
+func TestHtmlMermaidJsUrl(t *testing.T) {
+	tests := []struct {
+		url     string
+		wantErr bool
+	}{
+		{url: "https://cdn.example.com/mermaid.min.js"},
+		{url: "http://localhost:8080/mermaid.min.js"},
+		{url: "js/mermaid.min.js"},
+		{url: "javascript:alert(1)", wantErr: true},
+		{url: "data:text/javascript,alert(1)", wantErr: true},
+		{url: "https:///mermaid.min.js", wantErr: true},
+		{url: "file:///tmp/mermaid.min.js", wantErr: true},
+		{url: "https://example.com/%zz", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.url, func(t *testing.T) {
+			html, err := Html("flowchart TB", HtmlOptions{MermaidJsUrl: tt.url})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Html() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && !strings.Contains(html, `src="`+tt.url+`"`) {
+				t.Errorf("Html() does not load the script from %s:\n%s", tt.url, html)
+			}
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package mermaid
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/mbaksheev/clickhouse-table-graph/graph"
+	"github.com/mbaksheev/clickhouse-table-graph/internal/engine"
+	"github.com/mbaksheev/clickhouse-table-graph/internal/nodeid"
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+// missingTableShape is the shape of the node of the table which does not exist.
+const missingTableShape = "notch-rect"
+
+// familyStyles are the shapes and colors of the built-in engine styles of the engine families, see [DefaultEngineStyles].
+var familyStyles = map[engine.Family]EngineStyle{
+	engine.Other:                {Shape: "rect"},
+	engine.MaterializedView:     {Shape: "hex", Fill: "#e8eaf6", Stroke: "#3f51b5"},
+	engine.View:                 {Shape: "stadium", Fill: "#ede7f6", Stroke: "#673ab7"},
+	engine.Distributed:          {Shape: "st-rect", Fill: "#e0f7fa", Stroke: "#00838f"},
+	engine.Null:                 {Shape: "rounded", Fill: "#f5f5f5", Stroke: "#757575"},
+	engine.Dictionary:           {Shape: "win-pane", Fill: "#fff8e1", Stroke: "#ff8f00"},
+	engine.AggregatingMergeTree: {Shape: "rect", Fill: "#f9fbe7", Stroke: "#9e9d24"},
+	engine.ReplicatedMergeTree:  {Shape: "rect", Fill: "#e8f5e9", Stroke: "#1b5e20"},
+	engine.MergeTree:            {Shape: "rect", Fill: "#f1f8e9", Stroke: "#7cb342"},
+	engine.Queue:                {Shape: "h-cyl", Fill: "#fbe9e7", Stroke: "#d84315"},
+	engine.Buffer:               {Shape: "bow-rect", Fill: "#fce4ec", Stroke: "#ad1457"},
+	engine.JoinSet:              {Shape: "div-rect", Fill: "#f3e5f5", Stroke: "#8e24aa"},
+	engine.MemoryLog:            {Shape: "lin-rect", Fill: "#eceff1", Stroke: "#546e7a"},
+	engine.External:             {Shape: "cyl", Fill: "#e3f2fd", Stroke: "#1565c0"},
+}
+
+// EngineStyle represents the style of the nodes of the tables with the matching engines, e.g. the engine family like MergeTree tables.
+// Tables with the same style are rendered with the same mermaid class.
+type EngineStyle struct {
+	// Name is the name of the style shown in the legend, e.g. "Replicated MergeTree".
+	// If not specified, the list of the engines or the engine regular expression is used.
+	Name string
+	// Engines is a list of the engine names matched by the style, e.g. "Kafka".
+	Engines []string
+	// EngineRegex is the regular expression matched against the engine name, e.g. ^Replicated.*MergeTree$. Optional.
+	EngineRegex *regexp.Regexp
+	// Shape is the mermaid shape of the node, e.g. "rect", "hex" or "cyl". See https://mermaid.js.org/syntax/flowchart.html#complete-list-of-new-shapes
+	// If not specified, the shape of the default engine style is used.
+	Shape string
+	// Fill is the fill color of the node, e.g. "#e8f5e9". If not specified, the fill color of the default engine style is used.
+	Fill string
+	// Stroke is the border color of the node, e.g. "#1b5e20". If not specified, the border color of the default engine style is used.
+	Stroke string
+}
+
+// name returns the name of the style, or the list of the engines and the engine regular expression if the name is not specified.
+func (style EngineStyle) name() string {
+	if style.Name != "" {
+		return style.Name
+	}
+	names := slices.Clone(style.Engines)
+	if style.EngineRegex != nil {
+		names = append(names, style.EngineRegex.String())
+	}
+	return strings.Join(names, ", ")
+}
+
+// matches reports whether the style is applied to the tables with the specified engine.
+func (style EngineStyle) matches(engine string) bool {
+	return slices.Contains(style.Engines, engine) || style.EngineRegex != nil && style.EngineRegex.MatchString(engine)
+}
+
+// DefaultEngineStyles returns the built-in styles of the engine families: materialized views, views, Distributed tables,
+// Null tables, dictionaries, MergeTree tables, queues like Kafka, Buffer tables, Join and Set tables, in-memory and log tables,
+// and tables of the external storages. The first matching style is applied to the table.
+func DefaultEngineStyles() []EngineStyle {
+	styles := make([]EngineStyle, 0, len(familyStyles))
+	for _, family := range engine.Families() {
+		styles = append(styles, familyStyleOf(family))
+	}
+	return styles
+}
+
+// familyStyleOf returns the built-in style of the engine family with its name and engines.
+func familyStyleOf(family engine.Family) EngineStyle {
+	style := familyStyles[family]
+	style.Name = family.String()
+	style.Engines = family.Engines()
+	style.EngineRegex = family.EngineRegex()
+	return style
+}
+
+// engineStyleOf returns the style of the tables with the specified engine: the default style of the engine family
+// with the shape and colors overridden by the first matching user-defined style.
+func engineStyleOf(engineName string, styles []EngineStyle) EngineStyle {
+	result := familyStyleOf(engine.FamilyOf(engineName))
+	for _, style := range styles {
+		if !style.matches(engineName) {
+			continue
+		}
+		result.Name = style.name()
+		if style.Shape != "" {
+			result.Shape = style.Shape
+		}
+		if style.Fill != "" {
+			result.Fill = style.Fill
+		}
+		if style.Stroke != "" {
+			result.Stroke = style.Stroke
+		}
+		break
+	}
+	return result
+}
+
+// usedEngineStyles returns the styles of the existing tables of the graph sorted by name, and the keys of the tables of every style.
+func usedEngineStyles(graphLinks graph.Links, options FlowchartOptions) ([]EngineStyle, map[string][]table.Key) {
+	styles := make([]EngineStyle, 0)
+	tables := make(map[string][]table.Key)
+	for _, tableKey := range graphLinks.SortedNodes() {
+		tableInfo, exists := graphLinks.TableInfo(tableKey)
+		if !exists {
+			continue
+		}
+		style := engineStyleOf(tableInfo.Engine, options.EngineStyles)
+		if _, used := tables[style.Name]; !used {
+			styles = append(styles, style)
+		}
+		tables[style.Name] = append(tables[style.Name], tableKey)
+	}
+	slices.SortFunc(styles, func(a, b EngineStyle) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return styles, tables
+}
+
+// classOf returns the mermaid class name of the engine style.
+func classOf(style EngineStyle) string {
+	return nodeid.Stable("e", style.Name)
+}
+
+// legendNodeId returns the identifier of the legend node of the engine style.
+func legendNodeId(style EngineStyle) string {
+	return nodeid.Stable("l", style.Name)
+}
+
+// writeLegend writes the subgraph with one node for every engine style used in the graph,
+// and the node of the table which does not exist if there are such tables.
+func writeLegend(stringBuildr *strings.Builder, graphLinks graph.Links, options FlowchartOptions) {
+	styles, _ := usedEngineStyles(graphLinks, options)
+	stringBuildr.WriteString("subgraph legend [\"Legend\"]\n")
+	for _, style := range styles {
+		stringBuildr.WriteString("  " + legendNodeId(style) + "@{ shape: " + style.Shape + ", label: \"" + escapeLabel(style.Name) + "\" }\n")
+	}
+	if slices.ContainsFunc(graphLinks.SortedNodes(), func(tableKey table.Key) bool {
+		_, exists := graphLinks.TableInfo(tableKey)
+		return !exists
+	}) {
+		stringBuildr.WriteString("  legend_missing@{ shape: " + missingTableShape + ", label: \"table does not exist\" }\n")
+	}
+	stringBuildr.WriteString("end\n")
+}
+
+// writeClasses writes the class definition of every engine style used in the graph with the fill or border color,
+// and assigns the classes to the nodes of the tables and to the legend nodes.
+func writeClasses(stringBuildr *strings.Builder, graphLinks graph.Links, options FlowchartOptions) {
+	styles, tables := usedEngineStyles(graphLinks, options)
+	for _, style := range styles {
+		properties := make([]string, 0, 2)
+		if style.Fill != "" {
+			properties = append(properties, "fill:"+style.Fill)
+		}
+		if style.Stroke != "" {
+			properties = append(properties, "stroke:"+style.Stroke)
+		}
+		if len(properties) == 0 {
+			continue
+		}
+		ids := make([]string, 0, len(tables[style.Name])+1)
+		for _, tableKey := range tables[style.Name] {
+			ids = append(ids, NodeId(tableKey))
+		}
+		if options.Legend {
+			ids = append(ids, legendNodeId(style))
+		}
+		stringBuildr.WriteString("classDef " + classOf(style) + " " + strings.Join(properties, ",") + "\n")
+		stringBuildr.WriteString("class " + strings.Join(ids, ",") + " " + classOf(style) + "\n")
+	}
+}
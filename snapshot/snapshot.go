@@ -0,0 +1,180 @@
+// Package snapshot provides a versioned file format to save tables information and read it later without ClickHouse server.
+//
+// Use [Write] function to save tables information, e.g. fetched from ClickHouse server, to a snapshot:
+//
+//	err := snapshot.Write(file, tables, snapshot.JSON)
+//
+// Use [File] tables information provider to read tables information from the snapshot file:
+//
+//	snapshotFile := snapshot.File{Path: "schema.json"}
+//	tables, err := snapshotFile.TableInfos()
+//
+// Two formats are supported:
+//   - [JSON] - a single JSON object with the header fields and the list of tables;
+//   - [NDJSON] - newline delimited JSON, where the first line is the header and each next line is a table.
+package snapshot
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+// Version is the version of the snapshot format written by the [Write] function.
+// Snapshots with a greater version can not be read.
+const Version = 1
+
+// Format represents the snapshot file format.
+type Format int
+
+// Possible values for the [Format] type.
+const (
+	// JSON is a single JSON object with the header fields and the list of tables.
+	JSON Format = iota
+	// NDJSON is a newline delimited JSON, where the first line is the header and each next line is a table.
+	NDJSON
+)
+
+// header represents the snapshot header. In the JSON format it contains tables as well.
+type header struct {
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Tables    []tableV1 `json:"tables,omitempty"`
+}
+
+// tableV1 represents a table in the snapshot. Field names are the same as column names of the system.tables table.
+type tableV1 struct {
+	Database             string   `json:"database"`
+	Name                 string   `json:"name"`
+	Engine               string   `json:"engine"`
+	EngineFull           string   `json:"engine_full"`
+	CreateTableQuery     string   `json:"create_table_query"`
+	AsSelect             string   `json:"as_select"`
+	DependenciesDatabase []string `json:"dependencies_database"`
+	DependenciesTable    []string `json:"dependencies_table"`
+}
+
+// File represents a snapshot file. It implements the [table.InfoProvider] interface.
+type File struct {
+	// Path is the path of the snapshot file. The format is detected automatically.
+	Path string
+}
+
+// String returns a string representation of the snapshot file.
+func (f *File) String() string {
+	return fmt.Sprintf("Snapshot file [Path=%s]", f.Path)
+}
+
+// TableInfos returns the list of tables from the snapshot file.
+func (f *File) TableInfos() ([]table.Info, error) {
+	file, err := os.Open(f.Path)
+	if err != nil {
+		return nil, fmt.Errorf("TableInfos: failed to open snapshot file: %s, %w", f.Path, err)
+	}
+	defer file.Close()
+	tables, err := Read(file)
+	if err != nil {
+		return nil, fmt.Errorf("TableInfos: failed to read snapshot file: %s, %w", f.Path, err)
+	}
+	return tables, nil
+}
+
+// Write writes the specified tables to the snapshot in the specified format.
+func Write(w io.Writer, tables []table.Info, format Format) error {
+	snapshotHeader := header{
+		Version:   Version,
+		CreatedAt: time.Now().UTC().Truncate(time.Second),
+	}
+	bufferedWriter := bufio.NewWriter(w)
+	encoder := json.NewEncoder(bufferedWriter)
+	switch format {
+	case JSON:
+		snapshotHeader.Tables = make([]tableV1, 0, len(tables))
+		for _, t := range tables {
+			snapshotHeader.Tables = append(snapshotHeader.Tables, toTableV1(t))
+		}
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(snapshotHeader); err != nil {
+			return fmt.Errorf("Write: failed to write snapshot: %w", err)
+		}
+	case NDJSON:
+		if err := encoder.Encode(snapshotHeader); err != nil {
+			return fmt.Errorf("Write: failed to write snapshot header: %w", err)
+		}
+		for _, t := range tables {
+			if err := encoder.Encode(toTableV1(t)); err != nil {
+				return fmt.Errorf("Write: failed to write table %s: %w", t.Key, err)
+			}
+		}
+	default:
+		return fmt.Errorf("Write: unknown snapshot format: %d", format)
+	}
+	return bufferedWriter.Flush()
+}
+
+// Read reads tables from the snapshot. Both [JSON] and [NDJSON] formats are supported.
+func Read(r io.Reader) ([]table.Info, error) {
+	decoder := json.NewDecoder(r)
+	var snapshotHeader header
+	if err := decoder.Decode(&snapshotHeader); err != nil {
+		return nil, fmt.Errorf("Read: failed to read snapshot header: %w", err)
+	}
+	if snapshotHeader.Version < 1 || snapshotHeader.Version > Version {
+		return nil, fmt.Errorf("Read: unsupported snapshot version: %d, supported versions: 1-%d", snapshotHeader.Version, Version)
+	}
+	tables := make([]table.Info, 0, len(snapshotHeader.Tables))
+	for _, t := range snapshotHeader.Tables {
+		tables = append(tables, t.toTableInfo())
+	}
+	for {
+		var t tableV1
+		err := decoder.Decode(&t)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Read: failed to read table #%d: %w", len(tables)+1, err)
+		}
+		tables = append(tables, t.toTableInfo())
+	}
+	return tables, nil
+}
+
+func toTableV1(info table.Info) tableV1 {
+	return tableV1{
+		Database:             info.Database,
+		Name:                 info.Name,
+		Engine:               info.Engine,
+		EngineFull:           info.EngineFull,
+		CreateTableQuery:     info.CreateTableQuery,
+		AsSelect:             info.AsSelect,
+		DependenciesDatabase: nonNil(info.DependenciesDatabase),
+		DependenciesTable:    nonNil(info.DependenciesTable),
+	}
+}
+
+func (t tableV1) toTableInfo() table.Info {
+	return table.Info{
+		Key:                  table.Key{Database: t.Database, Name: t.Name},
+		Engine:               t.Engine,
+		EngineFull:           t.EngineFull,
+		CreateTableQuery:     t.CreateTableQuery,
+		AsSelect:             t.AsSelect,
+		DependenciesDatabase: t.DependenciesDatabase,
+		DependenciesTable:    t.DependenciesTable,
+	}
+}
+
+// nonNil returns an empty slice for nil, so it is written as an empty JSON array instead of null.
+func nonNil(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
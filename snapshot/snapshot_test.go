@@ -0,0 +1,134 @@
+package snapshot
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mbaksheev/clickhouse-table-graph/table"
+)
+
+var testTables = []table.Info{
+	{
+		Key:                  table.Key{Database: "db", Name: "input"},
+		Engine:               "Null",
+		EngineFull:           "Null",
+		CreateTableQuery:     "CREATE TABLE db.input (id Int64) ENGINE = Null",
+		DependenciesDatabase: []string{"db"},
+		DependenciesTable:    []string{"target_mv"},
+	},
+	{
+		Key:              table.Key{Database: "db", Name: "target_mv"},
+		Engine:           "MaterializedView",
+		CreateTableQuery: "CREATE MATERIALIZED VIEW db.target_mv TO db.target AS SELECT id FROM db.input",
+		AsSelect:         "SELECT id FROM db.input",
+	},
+}
+
+func TestWriteRead(t *testing.T) {
+	tests := []struct {
+		name   string
+		format Format
+	}{
+		{name: "json", format: JSON},
+		{name: "ndjson", format: NDJSON},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buffer bytes.Buffer
+			if err := Write(&buffer, testTables, tt.format); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			got, err := Read(&buffer)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if len(got) != len(testTables) {
+				t.Fatalf("Read() returned %d tables, want %d", len(got), len(testTables))
+			}
+			for i := range got {
+				if got[i].Key != testTables[i].Key ||
+					got[i].CreateTableQuery != testTables[i].CreateTableQuery ||
+					got[i].AsSelect != testTables[i].AsSelect ||
+					!slices.Equal(got[i].DependenciesTable, testTables[i].DependenciesTable) {
+					t.Errorf("Read() =\n %#v, \nWant =\n %#v", got[i], testTables[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	tests := []struct {
+		name      string
+		snapshot  string
+		wantCount int
+		wantErr   bool
+	}{
+		{
+			name:      "ndjson",
+			snapshot:  "{\"version\":1}\n{\"database\":\"db\",\"name\":\"a\"}\n{\"database\":\"db\",\"name\":\"b\"}\n",
+			wantCount: 2,
+		},
+		{
+			name:      "json without tables",
+			snapshot:  `{"version":1,"created_at":"2024-01-01T00:00:00Z"}`,
+			wantCount: 0,
+		},
+		{
+			name:     "unsupported version",
+			snapshot: `{"version":2,"tables":[]}`,
+			wantErr:  true,
+		},
+		{
+			name:     "missing version",
+			snapshot: `{"tables":[]}`,
+			wantErr:  true,
+		},
+		{
+			name:     "invalid table",
+			snapshot: "{\"version\":1}\n{\"database\":1}\n",
+			wantErr:  true,
+		},
+		{
+			name:     "empty",
+			snapshot: "",
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Read(strings.NewReader(tt.snapshot))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Read() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(got) != tt.wantCount {
+				t.Errorf("Read() returned %d tables, want %d", len(got), tt.wantCount)
+			}
+		})
+	}
+}
+
+func TestFileTableInfos(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "snapshot.ndjson")
+	file, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Write(file, testTables, NDJSON); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	file.Close()
+
+	snapshotFile := File{Path: path}
+	got, err := snapshotFile.TableInfos()
+	if err != nil {
+		t.Fatalf("File.TableInfos() error = %v", err)
+	}
+	if len(got) != len(testTables) {
+		t.Errorf("File.TableInfos() returned %d tables, want %d", len(got), len(testTables))
+	}
+}
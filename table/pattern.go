@@ -0,0 +1,99 @@
+package table
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// regexPrefix is the prefix of the pattern with the regular expression.
+const regexPrefix = "re:"
+
+// Pattern represents a pattern to select tables. The following patterns are supported:
+//   - "database.table" - the exact table name;
+//   - "database.table_*" - the glob pattern, where database and table name are matched separately, e.g. "events.*" or "*.raw_?";
+//   - "re:^raw_.*_kafka$" - the regular expression matched against the full table name in format "database.table".
+type Pattern struct {
+	// raw is the pattern as it was specified.
+	raw string
+	// key is the database and table name parts of the exact or glob pattern.
+	key Key
+	// regex is the compiled regular expression of the regex pattern. Nil for other patterns.
+	regex *regexp.Regexp
+}
+
+// ParsePattern parses the specified pattern of tables.
+func ParsePattern(pattern string) (Pattern, error) {
+	if expression, isRegex := strings.CutPrefix(pattern, regexPrefix); isRegex {
+		regex, err := regexp.Compile(expression)
+		if err != nil {
+			return Pattern{}, fmt.Errorf("ParsePattern: incorrect regular expression: '%s', %w", expression, err)
+		}
+		return Pattern{raw: pattern, regex: regex}, nil
+	}
+	database, name, separated := strings.Cut(pattern, ".")
+	if !separated || database == "" || name == "" {
+		return Pattern{}, fmt.Errorf("ParsePattern: incorrect table pattern: '%s'. Table pattern should be in format <database>.<table>, glob pattern like <database>.* or regular expression like re:<regex>", pattern)
+	}
+	for _, part := range []string{database, name} {
+		if _, err := path.Match(part, ""); err != nil {
+			return Pattern{}, fmt.Errorf("ParsePattern: incorrect glob pattern: '%s', %w", pattern, err)
+		}
+	}
+	return Pattern{raw: pattern, key: Key{Database: database, Name: name}}, nil
+}
+
+// String returns the pattern as it was specified.
+func (p Pattern) String() string {
+	return p.raw
+}
+
+// Key returns the table key of the exact pattern, i.e. the pattern without glob wildcards and regular expression.
+func (p Pattern) Key() (Key, bool) {
+	if p.regex != nil || strings.ContainsAny(p.key.Database, `*?[\`) || strings.ContainsAny(p.key.Name, `*?[\`) {
+		return Key{}, false
+	}
+	return p.key, true
+}
+
+// Match reports whether the specified table key matches the pattern.
+func (p Pattern) Match(key Key) bool {
+	if p.regex != nil {
+		return p.regex.MatchString(key.String())
+	}
+	databaseMatched, _ := path.Match(p.key.Database, key.Database)
+	nameMatched, _ := path.Match(p.key.Name, key.Name)
+	return databaseMatched && nameMatched
+}
+
+// Select returns keys of the specified tables, which match any of the patterns.
+// Keys of the exact patterns are returned even if there are no such tables.
+// Keys are returned in order of the patterns, tables matching the same pattern are sorted by database and name.
+// Each key is returned only once.
+func Select(patterns []Pattern, tables []Info) []Key {
+	selected := make([]Key, 0)
+	for _, pattern := range patterns {
+		if key, exact := pattern.Key(); exact {
+			if !slices.Contains(selected, key) {
+				selected = append(selected, key)
+			}
+			continue
+		}
+		matched := make([]Key, 0)
+		for _, t := range tables {
+			if pattern.Match(t.Key) && !slices.Contains(selected, t.Key) && !slices.Contains(matched, t.Key) {
+				matched = append(matched, t.Key)
+			}
+		}
+		slices.SortFunc(matched, func(a, b Key) int {
+			if result := strings.Compare(a.Database, b.Database); result != 0 {
+				return result
+			}
+			return strings.Compare(a.Name, b.Name)
+		})
+		selected = append(selected, matched...)
+	}
+	return selected
+}
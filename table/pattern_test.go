@@ -0,0 +1,75 @@
+package table
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestSelect(t *testing.T) {
+	tables := []Info{
+		{Key: Key{Database: "events", Name: "clicks"}},
+		{Key: Key{Database: "raw", Name: "raw_clicks_kafka"}},
+		{Key: Key{Database: "events", Name: "views"}},
+		{Key: Key{Database: "raw", Name: "raw_views_kafka"}},
+		{Key: Key{Database: "raw", Name: "raw_views"}},
+	}
+	tests := []struct {
+		name     string
+		patterns []string
+		want     []Key
+	}{
+		{
+			name:     "exact table",
+			patterns: []string{"events.views"},
+			want:     []Key{{Database: "events", Name: "views"}},
+		},
+		{
+			name:     "exact table which does not exist",
+			patterns: []string{"events.unknown"},
+			want:     []Key{{Database: "events", Name: "unknown"}},
+		},
+		{
+			name:     "glob pattern",
+			patterns: []string{"events.*"},
+			want:     []Key{{Database: "events", Name: "clicks"}, {Database: "events", Name: "views"}},
+		},
+		{
+			name:     "regular expression",
+			patterns: []string{"re:^raw\\.raw_.*_kafka$"},
+			want:     []Key{{Database: "raw", Name: "raw_clicks_kafka"}, {Database: "raw", Name: "raw_views_kafka"}},
+		},
+		{
+			name:     "several patterns without duplicates",
+			patterns: []string{"raw.raw_views", "*.*views*"},
+			want:     []Key{{Database: "raw", Name: "raw_views"}, {Database: "events", Name: "views"}, {Database: "raw", Name: "raw_views_kafka"}},
+		},
+		{
+			name:     "nothing matched",
+			patterns: []string{"other.*"},
+			want:     []Key{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patterns := make([]Pattern, 0, len(tt.patterns))
+			for _, p := range tt.patterns {
+				pattern, err := ParsePattern(p)
+				if err != nil {
+					t.Fatalf("ParsePattern() error = %v", err)
+				}
+				patterns = append(patterns, pattern)
+			}
+			if got := Select(patterns, tables); !slices.Equal(got, tt.want) {
+				t.Errorf("Select() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePatternError(t *testing.T) {
+	for _, pattern := range []string{"table", ".table", "db.", "db.[", "re:(", ""} {
+		if _, err := ParsePattern(pattern); err == nil {
+			t.Errorf("ParsePattern(%q) expected error", pattern)
+		}
+	}
+}